@@ -1,11 +1,45 @@
 package flag
 
 const (
-	WebServerFlag         = "webserver"
-	JsonOutput            = "json"
-	HostFlag              = "host"
-	CertPathFlag          = "cert"
-	CertKeyPathFlag       = "cert-key"
-	CertChainPathFlag     = "cert-chain"
-	CertFullChainPathFlag = "cert-full-chain"
+	WebServerFlag             = "webserver"
+	JsonOutput                = "json"
+	HostFlag                  = "host"
+	CertPathFlag              = "cert"
+	CertKeyPathFlag           = "cert-key"
+	CertChainPathFlag         = "cert-chain"
+	CertFullChainPathFlag     = "cert-full-chain"
+	ProfileFlag               = "profile"
+	AllFlag                   = "all"
+	SnippetFlag               = "snippet"
+	HeaderFlag                = "header"
+	HstsFlag                  = "hsts"
+	HstsMaxAgeFlag            = "hsts-max-age"
+	HstsIncludeSubDomainsFlag = "include-subdomains"
+	HstsPreloadFlag           = "preload"
+	StaplingFlag              = "stapling"
+	ResolverFlag              = "resolver"
+	CACertFlag                = "ca-cert"
+	VerifyFlag                = "verify"
+	DepthFlag                 = "depth"
+	PathFlag                  = "path"
+	ForwardHeaderFlag         = "forward-header"
+	KeyTypeFlag               = "key-type"
+	DaysFlag                  = "days"
+	OutputDirFlag             = "output-dir"
+	CAFlag                    = "ca"
+	StateDirFlag              = "state-dir"
+	DirFlag                   = "dir"
+	SkipSelfTestFlag          = "skip-self-test"
+	DirectoryFlag             = "directory"
+	DirectoryCACertFlag       = "directory-ca-cert"
+	EmailFlag                 = "email"
+	WebrootFlag               = "webroot"
+	CertbotDirFlag            = "certbot-dir"
+	AcmeShDirFlag             = "acme-sh-dir"
+	RelinkFlag                = "relink"
+	StoreFlag                 = "store"
+	ConfigFlag                = "config"
+	InstanceFlag              = "instance"
+	GracefulFlag              = "graceful"
+	HttpsPortFlag             = "https-port"
 )
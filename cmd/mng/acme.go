@@ -0,0 +1,195 @@
+package mng
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/acme"
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+const acmeWebrootDir = "acme-webroot"
+
+func getAcmeCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "acme",
+		Short: "issue certificates with an ACME server",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getAcmeIssueCmd())
+
+	return &cmd
+}
+
+func getAcmeIssueCmd() *cobra.Command {
+	var config acme.Config
+	var webroot string
+
+	cmd := cobra.Command{
+		Use:   "issue",
+		Short: "issue certificate for all names of host and deploy it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			// the lock is held while the certificate is issued since the challenge webroot is configured for the host
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writelnOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			config.StateDir = stateDir
+			ctx, cancel := context.WithTimeout(context.Background(), acme.DefaultTimeout)
+			defer cancel()
+
+			names, err := issueAcmeCertificate(ctx, code, hostName, webroot, config)
+			if err != nil {
+				return writelnOutput(cmd, err.Error())
+			}
+
+			return writelnOutput(cmd, fmt.Sprintf("certificate for %s is issued and deployed", strings.Join(names, ", ")))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&config.DirectoryURL, flag.DirectoryFlag, acme.DefaultDirectoryURL, "ACME directory URL")
+	cmd.Flags().StringVar(&config.CACertPath, flag.DirectoryCACertFlag, "", "CA bundle to verify the ACME server, e.g. for a local Pebble instance")
+	cmd.Flags().StringVar(&config.Email, flag.EmailFlag, "", "ACME account contact email")
+	cmd.Flags().StringVar(&config.KeyType, flag.KeyTypeFlag, certificate.KeyTypeRSA, "certificate key type: rsa, ecdsa")
+	cmd.Flags().StringVar(&webroot, flag.WebrootFlag, "", "webroot already serving ACME challenges of host. Challenges are served from a temporary location if not specified")
+	cmd.Flags().StringVar(&stateDir, flag.StateDirFlag, state.DefaultDir, "webmng state directory")
+
+	return &cmd
+}
+
+// issueAcmeCertificate issues certificate for all names of the host and deploys it.
+// If the webroot is not specified and the host has no challenge route, challenges are served from a temporary location
+// removed when the certificate is deployed.
+func issueAcmeCertificate(ctx context.Context, code, serverName, webroot string, config acme.Config) ([]string, error) {
+	webServerManager, err := GetWebServerManager(code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := webServerManager.GetHostsByServerName(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("could not find host '%s'", serverName)
+	}
+
+	names := getCertificateNames(serverName, hosts)
+
+	// the webroot of an existing challenge route is reused, so the route is not replaced and removed with the temporary one
+	if webroot == "" {
+		if webroot, err = webServerManager.GetAcmeWebroot(serverName); err != nil {
+			return nil, err
+		}
+	}
+
+	temporaryWebroot := webroot == ""
+
+	if temporaryWebroot {
+		if webroot, err = state.GetDir(config.StateDir, acmeWebrootDir); err != nil {
+			return nil, err
+		}
+
+		errPrefix := fmt.Sprintf("could not enable ACME webroot for host '%s'", serverName)
+
+		if err = webServerManager.EnableAcmeWebroot(serverName, webroot); err != nil {
+			return nil, rollbackManagerChanges(webServerManager, fmt.Errorf("%s: %v", errPrefix, err))
+		}
+
+		if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+			return nil, err
+		}
+
+		if err = webserver.CheckAcmeWebroot(serverName, webroot, webserver.GetHttpPort(hosts)); err != nil {
+			err = fmt.Errorf("ACME webroot self-test failed: %v", err)
+		}
+	}
+
+	var cert webserver.Certificate
+
+	if err == nil {
+		cert, err = obtainAcmeCertificate(ctx, serverName, names, webroot, config)
+	}
+
+	// the manager is recreated since configuration could be changed by the temporary webroot
+	if webServerManager, err = getManagerForAcmeDeploy(code, serverName, temporaryWebroot, err); err != nil {
+		return nil, err
+	}
+
+	errPrefix := fmt.Sprintf("could not deploy certificate to host '%s'", serverName)
+	// the issued certificate is activated in the managed store, so hosts are switched to it atomically and back on rollback
+	certificates, err := activateStoreCertificates(webServerManager, serverName, []webserver.Certificate{cert})
+	os.RemoveAll(filepath.Dir(cert.KeyPath))
+
+	if err != nil {
+		return nil, rollbackManagerChanges(webServerManager, fmt.Errorf("%s: %v", errPrefix, err))
+	}
+
+	if err = webServerManager.DeployCertificates(serverName, certificates); err != nil {
+		return nil, rollbackManagerChanges(webServerManager, fmt.Errorf("%s: %v", errPrefix, err))
+	}
+
+	if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func obtainAcmeCertificate(ctx context.Context, serverName string, names []string, webroot string, config acme.Config) (webserver.Certificate, error) {
+	client, err := acme.GetClient(ctx, config)
+	if err != nil {
+		return webserver.Certificate{}, err
+	}
+
+	cert, err := client.Obtain(ctx, serverName, names, acme.WebrootProvider{Webroot: webroot})
+	if err != nil {
+		return webserver.Certificate{}, fmt.Errorf("could not issue certificate for host '%s': %v", serverName, err)
+	}
+
+	return cert, nil
+}
+
+// getManagerForAcmeDeploy returns a new manager with the temporary webroot disabled.
+// The issue error is returned after the temporary webroot is removed.
+func getManagerForAcmeDeploy(code, serverName string, temporaryWebroot bool, issueErr error) (webserver.WebServerManagerInterface, error) {
+	webServerManager, err := GetWebServerManager(code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !temporaryWebroot {
+		return webServerManager, issueErr
+	}
+
+	if err = webServerManager.DisableAcmeWebroot(serverName); err != nil {
+		return nil, rollbackManagerChanges(webServerManager, fmt.Errorf("could not disable ACME webroot for host '%s': %v", serverName, err))
+	}
+
+	if issueErr == nil {
+		return webServerManager, nil
+	}
+
+	if err = applyManagerChanges(webServerManager, code, "could not disable ACME webroot"); err != nil {
+		return nil, fmt.Errorf("%v\n%v", issueErr, err)
+	}
+
+	return nil, issueErr
+}
@@ -0,0 +1,114 @@
+package mng
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+func getAcmeWebrootCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "acme-webroot",
+		Short: "manage serving of ACME HTTP-01 challenges from a webroot directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getAcmeWebrootEnableCmd())
+	cmd.AddCommand(getAcmeWebrootDisableCmd())
+
+	return &cmd
+}
+
+func getAcmeWebrootEnableCmd() *cobra.Command {
+	var webroot string
+	var skipSelfTest bool
+
+	cmd := cobra.Command{
+		Use:   "enable",
+		Short: "serve ACME HTTP-01 challenges of host from the directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not enable ACME webroot for host '%s'", hostName)
+
+			if err = webServerManager.EnableAcmeWebroot(hostName, webroot); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if skipSelfTest {
+				return nil
+			}
+
+			hosts, err := webServerManager.GetHostsByServerName(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if err = webserver.CheckAcmeWebroot(hostName, webroot, webserver.GetHttpPort(hosts)); err != nil {
+				return writelnOutput(cmd, fmt.Sprintf("ACME webroot self-test failed: %v", err))
+			}
+
+			return writelnOutput(cmd, "ACME webroot self-test passed")
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&webroot, flag.DirFlag, "", "webroot directory, challenges are served from <dir>/.well-known/acme-challenge/")
+	cmd.MarkFlagRequired(flag.DirFlag)
+	cmd.Flags().BoolVar(&skipSelfTest, flag.SkipSelfTestFlag, false, "do not request a test token from the webserver")
+
+	return &cmd
+}
+
+func getAcmeWebrootDisableCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "disable",
+		Short: "stop serving ACME HTTP-01 challenges of host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not disable ACME webroot for host '%s'", hostName)
+
+			if err = webServerManager.DisableAcmeWebroot(hostName); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+
+	return &cmd
+}
@@ -21,6 +21,22 @@ func init() {
 	apacheCmd.AddCommand(getHostsCmd())
 	apacheCmd.AddCommand(getVersionCmd())
 	apacheCmd.AddCommand(getCheckCmd())
+	apacheCmd.AddCommand(getReloadCmd())
 	apacheCmd.AddCommand(getRestartCmd())
+	apacheCmd.AddCommand(getStartCmd())
+	apacheCmd.AddCommand(getStopCmd())
+	apacheCmd.AddCommand(getStatusCmd())
 	apacheCmd.AddCommand(getDeployCertificateCmd())
+	apacheCmd.AddCommand(getLintCmd())
+	apacheCmd.AddCommand(getTLSAuditCmd())
+	apacheCmd.AddCommand(getTLSPolicyCmd())
+	apacheCmd.AddCommand(getHeadersCmd())
+	apacheCmd.AddCommand(getStaplingCmd())
+	apacheCmd.AddCommand(getClientAuthCmd())
+	apacheCmd.AddCommand(getSelfSignedCmd())
+	apacheCmd.AddCommand(getAcmeWebrootCmd())
+	apacheCmd.AddCommand(getAcmeCmd())
+	apacheCmd.AddCommand(getLineagesCmd())
+	apacheCmd.AddCommand(getRenewDeployedCmd())
+	apacheCmd.AddCommand(getCertStoreCmd())
 }
@@ -0,0 +1,148 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/certstore"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+func getCertStoreCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "cert-store",
+		Short: "manage certificate versions of the managed store",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getCertStoreListCmd())
+	cmd.AddCommand(getCertStoreRollbackCmd())
+
+	return &cmd
+}
+
+func getCertStoreListCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "show certificate versions of host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := certstore.GetStore(stateDir)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			entries, err := store.GetEntries(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if isJson {
+				output, err := json.Marshal(entries)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			if len(entries) == 0 {
+				return writelnOutput(cmd, fmt.Sprintf("no certificates of host '%s' in the store", hostName))
+			}
+
+			var lines []string
+
+			for _, entry := range entries {
+				lines = append(lines, fmt.Sprintf("%s %s:", entry.Name, entry.KeyType))
+
+				for _, version := range entry.Versions {
+					line := "  " + version
+
+					if version == entry.Current {
+						line += " (current)"
+					}
+
+					lines = append(lines, line)
+				}
+			}
+
+			return writelnOutput(cmd, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&stateDir, flag.StateDirFlag, state.DefaultDir, "webmng state directory")
+
+	return &cmd
+}
+
+func getCertStoreRollbackCmd() *cobra.Command {
+	var keyType string
+
+	cmd := cobra.Command{
+		Use:   "rollback",
+		Short: "activate the previous certificate version of host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := certstore.GetStore(stateDir)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			entries, err := store.GetEntries(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not roll back certificate of host '%s'", hostName)
+			var activated []string
+
+			for _, entry := range entries {
+				if keyType != "" && entry.KeyType != keyType {
+					continue
+				}
+
+				version, err := store.ActivatePrevious(entry.Name, entry.KeyType, webServerManager.GetReverter(), webServerManager.GetChanges())
+				if err != nil {
+					return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+				}
+
+				activated = append(activated, fmt.Sprintf("%s %s: %s", entry.Name, entry.KeyType, version))
+			}
+
+			if len(activated) == 0 {
+				return writelnOutput(cmd, fmt.Sprintf("no certificates of host '%s' in the store", hostName))
+			}
+
+			if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			return writelnOutput(cmd, strings.Join(activated, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&keyType, flag.KeyTypeFlag, "", "key type of the certificate to roll back: rsa, ecdsa. All certificates are rolled back if not specified")
+	cmd.Flags().StringVar(&stateDir, flag.StateDirFlag, state.DefaultDir, "webmng state directory")
+
+	return &cmd
+}
@@ -2,6 +2,7 @@ package mng
 
 import (
 	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +12,12 @@ func getCheckCmd() *cobra.Command {
 		Short: "check webserver configuration",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
 			webServerManager, err := GetWebServerManager(code, nil)
 			if err != nil {
 				return writeOutput(cmd, err.Error())
@@ -0,0 +1,102 @@
+package mng
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+var clientAuthConfig webserver.ClientAuthConfig
+
+func getClientAuthCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "client-auth",
+		Short: "manage client certificate authentication (mTLS) of hosts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getClientAuthEnableCmd())
+	cmd.AddCommand(getClientAuthDisableCmd())
+
+	return &cmd
+}
+
+func getClientAuthEnableCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "enable",
+		Short: "require client certificates signed by the CA for host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not enable client certificate authentication for host '%s'", hostName)
+
+			if err = webServerManager.EnableClientAuth(hostName, clientAuthConfig); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&clientAuthConfig.CACertPath, flag.CACertFlag, "", "CA bundle path used to verify client certificates")
+	cmd.MarkFlagRequired(flag.CACertFlag)
+	cmd.Flags().StringVar(&clientAuthConfig.Verify, flag.VerifyFlag, webserver.ClientVerifyOn, "verification mode: on, optional, optional_no_ca")
+	cmd.Flags().IntVar(&clientAuthConfig.Depth, flag.DepthFlag, 0, "maximum depth of client certificate chains")
+	cmd.Flags().StringVar(&clientAuthConfig.Path, flag.PathFlag, "", "require client certificates for the location only (apache only)")
+	cmd.Flags().StringVar(&clientAuthConfig.ForwardHeader, flag.ForwardHeaderFlag, "", "request header to pass client certificate subject DN to the backend")
+
+	return &cmd
+}
+
+func getClientAuthDisableCmd() *cobra.Command {
+	var path string
+
+	cmd := cobra.Command{
+		Use:   "disable",
+		Short: "disable client certificate authentication for host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not disable client certificate authentication for host '%s'", hostName)
+
+			if err = webServerManager.DisableClientAuth(hostName, path); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&path, flag.PathFlag, "", "location to disable client certificate authentication for (apache only)")
+
+	return &cmd
+}
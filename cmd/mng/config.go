@@ -0,0 +1,270 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	apacheoptions "github.com/r2dtools/webmng/internal/apache/options"
+	nginxoptions "github.com/r2dtools/webmng/internal/nginx/options"
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+var configPath, instanceName string
+
+// optionFlags contains descriptions of global flags setting manager options
+var optionFlags = map[string]string{
+	apacheoptions.ServerRoot:        "webserver root directory",
+	apacheoptions.HostRoot:          "apache virtual host root directory (apache only)",
+	apacheoptions.HostFiles:         "apache virtual host config files to use (apache only)",
+	apacheoptions.ApacheCtl:         "apache2ctl command or path (apache only)",
+	apacheoptions.SslVhostlExt:      "postfix of created ssl virtual host config files (apache only)",
+	webserverOptions.HttpPort:       "http port",
+	webserverOptions.HttpsPort:      "port of created ssl hosts",
+	webserverOptions.ServerConfig:   "webserver main configuration file",
+	webserverOptions.EnabledDir:     "directory of enabled hosts configs",
+	webserverOptions.AvailableDir:   "directory of available hosts configs where created host configs are placed",
+	webserverOptions.ReloadCmd:      "command used to reload webserver",
+	webserverOptions.Root:           "alternate root directory, configuration paths are resolved relative to it",
+	webserverOptions.Offline:        "manage configuration files without webserver binary",
+	webserverOptions.ServerVersion:  "webserver version (offline mode)",
+	webserverOptions.Modules:        "comma separated list of available webserver modules (offline mode)",
+	webserverOptions.CheckCmd:       "command used to check configuration (offline mode)",
+	webserverOptions.CmdTimeout:     "timeout of webserver commands, e.g. 30s",
+	webserverOptions.ServiceBackend: "way the webserver process is controlled: auto, systemd or ctl",
+	webserverOptions.ServiceUnit:    "systemd unit of the webserver, detected if not specified",
+	webserverOptions.VerifyTimeout:  "timeout of the webserver health verification after reload, e.g. 10s",
+	webserverOptions.Probe:          "send local HTTP(S) requests to the changed hosts after reload",
+	webserverOptions.PortCheck:      "reaction on ports bound by other processes when listen directives are added: refuse, warn or off",
+	webserverOptions.Staged:         "test changes against a temporary copy of the configuration before replacing the live files",
+	webserverOptions.PidFile:        "webserver pid file used by ctl backend, detected if not specified",
+	webserverOptions.LockFile:       "lock file of the webserver instance, derived from the instance paths if not specified",
+	webserverOptions.LockWait:       "wait for the lock held by another webmng run, --lock-wait=false fails immediately (default true)",
+	webserverOptions.LockTimeout:    "timeout of waiting for the lock, e.g. 30s",
+	apacheoptions.Defines:           "comma separated list of apache defines NAME=value (offline mode, apache only)",
+	nginxoptions.Bin:                "nginx binary path (nginx only)",
+	nginxoptions.Prefix:             "nginx prefix path (nginx only)",
+}
+
+// boolOptionFlags contains options set by boolean flags
+var boolOptionFlags = []string{webserverOptions.Offline, webserverOptions.Probe, webserverOptions.Staged, webserverOptions.LockWait}
+
+func addOptionFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&configPath, flag.ConfigFlag, "", fmt.Sprintf("webmng config file. $%s or %s is used if not specified", options.ConfigPathEnv, options.DefaultConfigPath))
+	cmd.PersistentFlags().StringVar(&instanceName, flag.InstanceFlag, "", fmt.Sprintf("webserver instance defined in webmng config file. $%s is used if not specified", options.InstanceEnv))
+
+	for name, usage := range optionFlags {
+		if slices.Contains(boolOptionFlags, name) {
+			cmd.PersistentFlags().Bool(getOptionFlagName(name), false, usage)
+		} else {
+			cmd.PersistentFlags().String(getOptionFlagName(name), "", usage)
+		}
+	}
+}
+
+func getOptionFlagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+func getOptionDefaults(code string) (map[string]string, error) {
+	switch code {
+	case webserver.Apache:
+		return apacheoptions.GetDefaults(), nil
+	case webserver.Nginx:
+		return nginxoptions.GetDefaults(), nil
+	default:
+		return nil, fmt.Errorf("webserver %s is not supported", code)
+	}
+}
+
+// getConfigFile loads config file from --config flag, $WEBMNG_CONFIG or the default path.
+// Only the default config file may be missing.
+func getConfigFile() (*options.ConfigFile, error) {
+	if configPath != "" {
+		return options.LoadConfigFile(configPath, false)
+	}
+
+	if path := os.Getenv(options.ConfigPathEnv); path != "" {
+		return options.LoadConfigFile(path, false)
+	}
+
+	return options.LoadConfigFile(options.DefaultConfigPath, true)
+}
+
+// getInstanceName returns the instance selected by --instance flag or $WEBMNG_INSTANCE
+func getInstanceName() string {
+	if instanceName != "" {
+		return instanceName
+	}
+
+	return os.Getenv(options.InstanceEnv)
+}
+
+// getOptionValues returns effective manager options of the webserver instance.
+// Options of the webserver are used if instance is empty.
+func getOptionValues(code, instance string) ([]options.Value, *options.ConfigFile, error) {
+	defaults, err := getOptionDefaults(code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configFile, err := getConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	flags := make(map[string]string)
+
+	for name := range defaults {
+		names = append(names, name)
+		optionFlag := RootCmd.PersistentFlags().Lookup(getOptionFlagName(name))
+
+		if optionFlag != nil && optionFlag.Changed {
+			flags[name] = optionFlag.Value.String()
+		}
+	}
+
+	fileOptions, err := configFile.GetOptions(code, instance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := options.Resolve(defaults, flags, options.GetEnvOptions(names), fileOptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", configFile.Path, err)
+	}
+
+	for _, value := range values {
+		switch value.Name {
+		case webserverOptions.HttpPort, webserverOptions.HttpsPort:
+			err = webserverOptions.ValidatePort(value.Value)
+		case webserverOptions.Offline, webserverOptions.Probe, webserverOptions.Staged, webserverOptions.LockWait:
+			err = webserverOptions.ValidateBool(value.Value)
+		case webserverOptions.CmdTimeout, webserverOptions.VerifyTimeout, webserverOptions.LockTimeout:
+			err = webserverOptions.ValidateDuration(value.Value)
+		case webserverOptions.ServiceBackend:
+			err = validateServiceBackend(value.Value)
+		case webserverOptions.PortCheck:
+			err = validatePortCheck(value.Value)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid option '%s': %v", value.Name, err)
+		}
+	}
+
+	return values, configFile, nil
+}
+
+func getConfigCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "config",
+		Short: "manage webmng configuration",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getConfigShowCmd())
+
+	return &cmd
+}
+
+func getConfigShowCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "show",
+		Short: "show effective manager options and their sources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			codes := []string{webserver.Apache, webserver.Nginx}
+			serverValues := make(map[string][]options.Value)
+			instance := getInstanceName()
+
+			configFile, err := getConfigFile()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			// only options of the instance webserver are shown for the selected instance
+			if instance != "" {
+				if _, ok := configFile.Instances[instance]; !ok {
+					return writeOutput(cmd, fmt.Sprintf("instance '%s' is not defined in config file '%s'", instance, configFile.Path))
+				}
+
+				codes = []string{configFile.Instances[instance].WebServer}
+			}
+
+			for _, code := range codes {
+				values, _, err := getOptionValues(code, instance)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				serverValues[code] = values
+			}
+
+			if isJson {
+				output, err := json.Marshal(serverValues)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			lines := []string{fmt.Sprintf("config file: %s", configFile.Path)}
+
+			for _, code := range codes {
+				lines = append(lines, code+":")
+
+				for _, value := range serverValues[code] {
+					lines = append(lines, fmt.Sprintf("  %s = %q (%s)", value.Name, value.Value, getOptionSourceDetails(value, configFile)))
+				}
+			}
+
+			return writelnOutput(cmd, strings.Join(lines, "\n"))
+		},
+	}
+
+	return &cmd
+}
+
+func getOptionSourceDetails(value options.Value, configFile *options.ConfigFile) string {
+	switch value.Source {
+	case options.SourceFlag:
+		return "flag --" + getOptionFlagName(value.Name)
+	case options.SourceEnv:
+		return "env " + options.GetEnvName(value.Name)
+	case options.SourceFile:
+		return "file " + configFile.Path
+	default:
+		return value.Source
+	}
+}
+
+func validateServiceBackend(backend string) error {
+	backends := []string{webserver.ServiceBackendAuto, webserver.ServiceBackendSystemd, webserver.ServiceBackendCtl}
+
+	if !slices.Contains(backends, backend) {
+		return fmt.Errorf("unknown service backend '%s': must be one of %s", backend, strings.Join(backends, ", "))
+	}
+
+	return nil
+}
+
+func validatePortCheck(mode string) error {
+	modes := []string{procstat.PortCheckRefuse, procstat.PortCheckWarn, procstat.PortCheckOff}
+
+	if !slices.Contains(modes, mode) {
+		return fmt.Errorf("unknown port check mode '%s': must be one of %s", mode, strings.Join(modes, ", "))
+	}
+
+	return nil
+}
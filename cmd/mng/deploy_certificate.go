@@ -1,20 +1,33 @@
 package mng
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/certstore"
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/state"
 	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/verify"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 )
 
 var (
 	hostName,
-	certPath,
-	certKeyPath,
 	certChainPath,
-	certFullChainPath string
+	httpsPort string
+	certPaths,
+	certKeyPaths,
+	certChainPaths,
+	certFullChainPaths,
+	resolvers []string
+	enableStapling,
+	useStore bool
 )
 
 func getDeployCertificateCmd() *cobra.Command {
@@ -23,60 +36,287 @@ func getDeployCertificateCmd() *cobra.Command {
 		Short: "deploy certificate to host",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			code := cmd.Flag(flag.WebServerFlag).Value.String()
-			webServerManager, err := GetWebServerManager(code, nil)
 
+			// the command flag shadows the global option flag of the same name, so its value is passed to the option
+			if cmd.Flags().Changed(flag.HttpsPortFlag) {
+				if err := RootCmd.PersistentFlags().Set(getOptionFlagName(webserverOptions.HttpsPort), httpsPort); err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+			}
+
+			instanceLock, err := lockInstance(code, lock.Exclusive)
 			if err != nil {
 				return writeOutput(cmd, err.Error())
 			}
+			defer instanceLock.Release()
 
-			if err = webServerManager.DeployCertificate(hostName, certPath, certKeyPath, certChainPath, certFullChainPath); err != nil {
-				err = fmt.Errorf("could not deploy certificate to virtual host '%s': %v", hostName, err)
+			webServerManager, err := GetWebServerManager(code, nil)
 
-				return rollbackChanges(webServerManager, cmd, err)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
 			}
 
-			if err = webServerManager.SaveChanges(); err != nil {
-				err = fmt.Errorf("could not deploy certificate to host '%s': could not save changes for configuration: %v", hostName, err)
+			certificates := getCertificatesFromFlags()
 
-				return rollbackChanges(webServerManager, cmd, err)
+			if useStore {
+				if certificates, err = activateStoreCertificates(webServerManager, hostName, certificates); err != nil {
+					err = fmt.Errorf("could not deploy certificate to virtual host '%s': %v", hostName, err)
+
+					return rollbackChanges(webServerManager, cmd, err)
+				}
 			}
 
-			if err = webServerManager.CheckConfiguration(); err != nil {
-				err = fmt.Errorf("could not deploy certificate to host '%s': nginx configuration is invalid: %v", hostName, err)
+			if err = webServerManager.DeployCertificates(hostName, certificates); err != nil {
+				err = fmt.Errorf("could not deploy certificate to virtual host '%s': %v", hostName, err)
 
 				return rollbackChanges(webServerManager, cmd, err)
 			}
 
-			if err = webServerManager.CommitChanges(); err != nil {
-				return writeOutput(cmd, err.Error())
-			}
+			if enableStapling {
+				staplingConfig := webserver.StaplingConfig{Resolvers: resolvers}
 
-			if err = webServerManager.Restart(); err != nil {
-				return writeOutput(cmd, err.Error())
+				// issuers of several certificates are collected from their full chains
+				if len(certChainPaths) == 1 {
+					staplingConfig.ChainPath = certChainPaths[0]
+				}
+
+				if err = webServerManager.EnableStapling(hostName, staplingConfig); err != nil {
+					err = fmt.Errorf("could not deploy certificate to virtual host '%s': %v", hostName, err)
+
+					return rollbackChanges(webServerManager, cmd, err)
+				}
 			}
 
-			return nil
+			return applyChanges(webServerManager, cmd, fmt.Sprintf("could not deploy certificate to host '%s'", hostName))
 		},
 	}
 
 	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
 	cmd.MarkFlagRequired(flag.HostFlag)
-	cmd.Flags().StringVar(&certPath, flag.CertPathFlag, "", "certificate path")
-	cmd.Flags().StringVar(&certKeyPath, flag.CertKeyPathFlag, "", "certificate key path")
+	cmd.Flags().StringArrayVar(&certPaths, flag.CertPathFlag, nil, "certificate path")
+	cmd.Flags().StringArrayVar(&certKeyPaths, flag.CertKeyPathFlag, nil, "certificate key path")
 	cmd.MarkFlagRequired(flag.CertKeyPathFlag)
-	cmd.Flags().StringVar(&certChainPath, flag.CertChainPathFlag, "", "certificate chain path")
-	cmd.Flags().StringVar(&certFullChainPath, flag.CertFullChainPathFlag, "", "certificate full chain path")
+	cmd.Flags().StringArrayVar(&certChainPaths, flag.CertChainPathFlag, nil, "certificate chain path")
+	cmd.Flags().StringArrayVar(&certFullChainPaths, flag.CertFullChainPathFlag, nil, "certificate full chain path")
+	cmd.Flags().BoolVar(&enableStapling, flag.StaplingFlag, false, "enable OCSP stapling")
+	cmd.Flags().StringArrayVar(&resolvers, flag.ResolverFlag, nil, "DNS resolver for OCSP stapling (nginx only). System resolvers are used if not specified")
+	cmd.Flags().BoolVar(&useStore, flag.StoreFlag, false, "copy certificates to the managed store and point host at its current version")
+	cmd.Flags().StringVar(&stateDir, flag.StateDirFlag, state.DefaultDir, "webmng state directory keeping the managed store")
+	cmd.Flags().StringVar(&httpsPort, flag.HttpsPortFlag, "", "port of created ssl hosts, alias of the global --https-port option")
 
 	return &cmd
 }
 
+// getCertificatesFromFlags returns certificates to deploy. Certificate flags could be specified several times
+// to deploy certificates with different key types (RSA and ECDSA), values are paired by their order.
+func getCertificatesFromFlags() []webserver.Certificate {
+	count := len(certKeyPaths)
+
+	for _, paths := range [][]string{certPaths, certChainPaths, certFullChainPaths} {
+		if len(paths) > count {
+			count = len(paths)
+		}
+	}
+
+	certificates := make([]webserver.Certificate, count)
+
+	for i := range certificates {
+		certificates[i] = webserver.Certificate{
+			CertPath:      getFlagValue(certPaths, i),
+			KeyPath:       getFlagValue(certKeyPaths, i),
+			ChainPath:     getFlagValue(certChainPaths, i),
+			FullChainPath: getFlagValue(certFullChainPaths, i),
+		}
+	}
+
+	return certificates
+}
+
+func getFlagValue(values []string, index int) string {
+	if index < len(values) {
+		return values[index]
+	}
+
+	return ""
+}
+
+// activateStoreCertificates imports certificates to the managed store as new versions and activates them.
+// Returns certificates referenced through the store current symlinks.
+func activateStoreCertificates(webServerManager webserver.WebServerManagerInterface, serverName string, certificates []webserver.Certificate) ([]webserver.Certificate, error) {
+	store, err := certstore.GetStore(stateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var storeCertificates []webserver.Certificate
+
+	for _, cert := range certificates {
+		keyType, version, err := store.Import(serverName, cert)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = store.Activate(serverName, keyType, version, webServerManager.GetReverter(), webServerManager.GetChanges()); err != nil {
+			return nil, err
+		}
+
+		storeCertificates = append(storeCertificates, store.GetCertificate(serverName, keyType))
+	}
+
+	return storeCertificates, nil
+}
+
+// applyChanges saves configuration changes, checks the configuration, reloads the webserver and verifies it is healthy.
+// Changes are rolled back if they could not be saved or the configuration became invalid.
+// If reload or verification fails, the previous configuration is restored and reloaded.
+func applyChanges(webServerManager webserver.WebServerManagerInterface, cmd *cobra.Command, errPrefix string) error {
+	if err := applyManagerChanges(webServerManager, cmd.Flag(flag.WebServerFlag).Value.String(), errPrefix); err != nil {
+		return writeOutput(cmd, err.Error())
+	}
+
+	return nil
+}
+
 func rollbackChanges(webServerManager webserver.WebServerManagerInterface, cmd *cobra.Command, err error) error {
+	return writeOutput(cmd, rollbackManagerChanges(webServerManager, err).Error())
+}
+
+// applyManagerChanges does the same as applyChanges, but returns the error instead of writing it
+func applyManagerChanges(webServerManager webserver.WebServerManagerInterface, code, errPrefix string) error {
+	if err := webServerManager.SaveChanges(); err != nil {
+		err = fmt.Errorf("%s: could not save changes for configuration: %v", errPrefix, err)
+
+		return rollbackManagerChanges(webServerManager, err)
+	}
+
+	if err := webServerManager.CheckConfiguration(); err != nil {
+		err = fmt.Errorf("%s: %s configuration is invalid: %v", errPrefix, code, err)
+
+		return rollbackManagerChanges(webServerManager, err)
+	}
+
+	verifier, snapshot, err := getManagerVerifier(webServerManager, code)
+	if err != nil {
+		return rollbackManagerChanges(webServerManager, fmt.Errorf("%s: %v", errPrefix, err))
+	}
+
+	restore := webServerManager.Reload
+
+	if verifier != nil {
+		restore = func() error {
+			return verifier.Restore(snapshot)
+		}
+	}
+
+	if err := webServerManager.Reload(); err != nil {
+		err = fmt.Errorf("%s: could not reload %s: %v", errPrefix, code, err)
+
+		return restoreManagerChanges(webServerManager, restore, err)
+	}
+
+	if verifier != nil {
+		if err := verifier.Verify(snapshot); err != nil {
+			err = fmt.Errorf("%s: %s verification failed after reload: %v", errPrefix, code, err)
+
+			return restoreManagerChanges(webServerManager, restore, err)
+		}
+	}
+
+	return webServerManager.CommitChanges()
+}
+
+// getManagerVerifier returns verifier of the webserver health after reload and the state of its processes before reload.
+// Nil verifier is returned in offline mode.
+func getManagerVerifier(webServerManager webserver.WebServerManagerInterface, code string) (*verify.Verifier, verify.Snapshot, error) {
+	params, err := getManagerParams(code, nil)
+	if err != nil {
+		return nil, verify.Snapshot{}, err
+	}
+
+	managerOptions := options.Options{Params: params, Defaults: webserverOptions.GetDefaults()}
+
+	if webserverOptions.IsOffline(managerOptions) {
+		return nil, verify.Snapshot{}, nil
+	}
+
+	snapshot, err := verify.TakeSnapshot(webServerManager)
+	if err != nil {
+		return nil, snapshot, err
+	}
+
+	var probes []verify.Probe
+
+	if webserverOptions.IsProbeEnabled(managerOptions) {
+		hosts, err := getChangedHosts(webServerManager)
+		if err != nil {
+			return nil, snapshot, err
+		}
+
+		probes = verify.GetHostProbes(hosts, managerOptions.Get(webserverOptions.HttpPort))
+	}
+
+	verifier := verify.GetVerifier(webServerManager, webserverOptions.GetVerifyTimeout(managerOptions), probes)
+
+	return &verifier, snapshot, nil
+}
+
+// getChangedHosts returns hosts which config files are changed since the last commit
+func getChangedHosts(webServerManager webserver.WebServerManagerInterface) ([]webserver.Host, error) {
+	hosts, err := webServerManager.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	changedFiles := webServerManager.GetReverter().GetChangedFiles()
+	var changedHosts []webserver.Host
+
+	for _, host := range hosts {
+		if slices.Contains(changedFiles, host.FilePath) {
+			changedHosts = append(changedHosts, host)
+		}
+	}
+
+	return changedHosts, nil
+}
+
+// restoreManagerChanges rolls back changes already applied to the webserver and applies the previous configuration via restore
+func restoreManagerChanges(webServerManager webserver.WebServerManagerInterface, restore func() error, err error) error {
 	var errMessages []string
 	errMessages = append(errMessages, err.Error())
 
 	if err = webServerManager.RollbackChanges(); err != nil {
 		errMessages = append(errMessages, err.Error())
+
+		return errors.New(strings.Join(errMessages, "\n"))
+	}
+
+	if err = restore(); err != nil {
+		errMessages = append(errMessages, fmt.Sprintf("could not reload webserver with restored configuration: %v", err))
+	}
+
+	return errors.New(strings.Join(errMessages, "\n"))
+}
+
+// rollbackManagerChanges rolls back changes and returns the error extended with the rollback error if any
+func rollbackManagerChanges(webServerManager webserver.WebServerManagerInterface, err error) error {
+	var errMessages []string
+	errMessages = append(errMessages, err.Error())
+
+	if err = webServerManager.RollbackChanges(); err != nil {
+		errMessages = append(errMessages, err.Error())
+	}
+
+	return errors.New(strings.Join(errMessages, "\n"))
+}
+
+// getCertificateNames returns certificate names of the hosts, the requested host name goes first to become the common name
+func getCertificateNames(serverName string, hosts []webserver.Host) []string {
+	names := webserver.GetHostsNames(hosts)
+	serverName = strings.ToLower(serverName)
+
+	if index := slices.Index(names, serverName); index > 0 {
+		names = append([]string{serverName}, slices.Delete(names, index, index+1)...)
 	}
 
-	return writeOutput(cmd, strings.Join(errMessages, "\n"))
+	return names
 }
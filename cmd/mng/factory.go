@@ -2,15 +2,30 @@ package mng
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/r2dtools/webmng/internal/apache"
 	"github.com/r2dtools/webmng/internal/nginx"
 	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/options"
 	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
 )
 
+// GetWebServerManager creates the webserver manager. Options set via flags, env and config file are overridden by params.
 func GetWebServerManager(code string, params map[string]string) (webserver.WebServerManagerInterface, error) {
-	logger := logger.NilLogger{}
+	params, err := getManagerParams(code, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return createWebServerManager(code, params)
+}
+
+// createWebServerManager creates the webserver manager with the resolved options, e.g. of the instance other than selected
+func createWebServerManager(code string, params map[string]string) (webserver.WebServerManagerInterface, error) {
+	logger := getLogger()
 
 	switch code {
 	case webserver.Apache:
@@ -21,3 +36,84 @@ func GetWebServerManager(code string, params map[string]string) (webserver.WebSe
 		return nil, fmt.Errorf("webserver %s is not supported", code)
 	}
 }
+
+// getNginxManager creates nginx manager for commands using nginx specific features
+func getNginxManager() (*nginx.NginxManager, error) {
+	params, err := getManagerParams(webserver.Nginx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return nginx.GetNginxManager(params, getLogger())
+}
+
+// getManagerParams returns options of the selected instance set via flags, env and config file overridden by params
+func getManagerParams(code string, params map[string]string) (map[string]string, error) {
+	values, _, err := getOptionValues(code, getInstanceName())
+	if err != nil {
+		return nil, err
+	}
+
+	managerParams := options.GetParams(values)
+
+	for name, value := range params {
+		managerParams[name] = value
+	}
+
+	return managerParams, nil
+}
+
+// lockInstance acquires the lock of the selected webserver instance before its configuration is read.
+// Commands changing the configuration or the webserver state take the exclusive lock, read-only commands take the shared one.
+func lockInstance(code string, mode lock.Mode) (*lock.Lock, error) {
+	params, err := getManagerParams(code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := getOptionDefaults(code)
+	if err != nil {
+		return nil, err
+	}
+
+	serverRoot, configFile, err := getInstancePaths(code, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return lock.AcquireInstance(options.Options{Params: params, Defaults: defaults}, code, serverRoot, configFile, mode)
+}
+
+// getInstancePaths returns server root and main config file of the instance as they are detected by its manager
+func getInstancePaths(code string, params map[string]string) (string, string, error) {
+	switch code {
+	case webserver.Apache:
+		return apache.GetInstancePaths(params)
+	case webserver.Nginx:
+		return nginx.GetInstancePaths(params)
+	default:
+		return "", "", fmt.Errorf("webserver %s is not supported", code)
+	}
+}
+
+// getLogger returns logger writing warnings to stderr, so they do not mix with the command output
+func getLogger() logger.LoggerInterface {
+	return logger.WriterLogger{Writer: os.Stderr}
+}
+
+// webServerCli checks webserver instance without parsing its configuration
+type webServerCli interface {
+	GetVersion() (string, error)
+	TestConfiguration() error
+}
+
+func getWebServerCli(code string, params map[string]string) (webServerCli, error) {
+	switch code {
+	case webserver.Apache:
+		return apache.GetApacheCtl(params)
+	case webserver.Nginx:
+		return nginx.GetNginxCli(params)
+	default:
+		return nil, fmt.Errorf("webserver %s is not supported", code)
+	}
+}
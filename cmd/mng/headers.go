@@ -0,0 +1,193 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+var (
+	headerValues []string
+	hsts,
+	hstsIncludeSubDomains,
+	hstsPreload bool
+	hstsMaxAge int
+)
+
+func getHeadersCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "headers",
+		Short: "manage response headers of hosts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getHeadersListCmd())
+	cmd.AddCommand(getHeadersSetCmd())
+	cmd.AddCommand(getHeadersUnsetCmd())
+
+	return &cmd
+}
+
+func getHeadersListCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "list",
+		Short: "show response headers of hosts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			hostsHeaders, err := webServerManager.GetHeaders(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if isJson {
+				output, err := json.Marshal(hostsHeaders)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			var outputParts []string
+
+			for _, hostHeaders := range hostsHeaders {
+				outputParts = append(outputParts, formatHostHeaders(hostHeaders))
+			}
+
+			return writelnOutput(cmd, strings.Join(outputParts, "\n\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name. All hosts are shown if not specified")
+
+	return &cmd
+}
+
+func getHeadersSetCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "set",
+		Short: "set response headers of host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var hostHeaders []headers.Header
+
+			for _, headerValue := range headerValues {
+				header, err := headers.Parse(headerValue)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				hostHeaders = append(hostHeaders, header)
+			}
+
+			if hsts {
+				header, err := headers.GetHstsHeader(hstsMaxAge, hstsIncludeSubDomains, hstsPreload)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				hostHeaders = append(hostHeaders, header)
+			}
+
+			if len(hostHeaders) == 0 {
+				return writelnOutput(cmd, "no headers specified")
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not set headers of host '%s'", hostName)
+
+			if err = webServerManager.SetHeaders(hostName, hostHeaders); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringArrayVar(&headerValues, flag.HeaderFlag, nil, "header in format 'Name: value'. Could be specified several times")
+	cmd.Flags().BoolVar(&hsts, flag.HstsFlag, false, "set Strict-Transport-Security header for ssl hosts")
+	cmd.Flags().IntVar(&hstsMaxAge, flag.HstsMaxAgeFlag, headers.DefaultHstsMaxAge, "HSTS max-age in seconds")
+	cmd.Flags().BoolVar(&hstsIncludeSubDomains, flag.HstsIncludeSubDomainsFlag, false, "add includeSubDomains to HSTS header")
+	cmd.Flags().BoolVar(&hstsPreload, flag.HstsPreloadFlag, false, "add preload to HSTS header")
+
+	return &cmd
+}
+
+func getHeadersUnsetCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "unset",
+		Short: "remove response headers of host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(headerValues) == 0 {
+				return writelnOutput(cmd, "no headers specified")
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not unset headers of host '%s'", hostName)
+
+			if err = webServerManager.UnsetHeaders(hostName, headerValues); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringArrayVar(&headerValues, flag.HeaderFlag, nil, "header name. Could be specified several times")
+
+	return &cmd
+}
+
+func formatHostHeaders(hostHeaders webserver.HostHeaders) string {
+	lines := []string{fmt.Sprintf("%s (%s):", hostHeaders.Host.ServerName, hostHeaders.Host.FilePath)}
+
+	for _, header := range hostHeaders.Headers {
+		lines = append(lines, "  "+header.ToString())
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -18,6 +19,12 @@ func getHostsCmd() *cobra.Command {
 			var err error
 
 			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
 			webServerManager, err := GetWebServerManager(code, nil)
 			if err != nil {
 				return writeOutput(cmd, err.Error())
@@ -0,0 +1,142 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/spf13/cobra"
+)
+
+const (
+	instanceStatusOk          = "ok"
+	instanceStatusUnavailable = "unavailable"
+	instanceStatusInvalid     = "invalid configuration"
+)
+
+type instanceStatus struct {
+	Name      string `json:"name"`
+	WebServer string `json:"webserver"`
+	Version   string `json:"version"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	// Service is nil if the service state could not be detected, e.g. in offline mode
+	Service      *webserver.ServiceStatus `json:"service,omitempty"`
+	ServiceError string                   `json:"service_error,omitempty"`
+}
+
+func getInstancesCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "instances",
+		Short: "show webserver instances defined in webmng config file and their status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, err := getConfigFile()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			var statuses []instanceStatus
+
+			for _, instance := range configFile.GetInstances() {
+				statuses = append(statuses, getInstanceStatus(instance))
+			}
+
+			if isJson {
+				output, err := json.Marshal(statuses)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			if len(statuses) == 0 {
+				return writelnOutput(cmd, fmt.Sprintf("no instances defined in config file '%s'", configFile.Path))
+			}
+
+			var lines []string
+
+			for _, status := range statuses {
+				webServer := strings.TrimSpace(status.WebServer + " " + status.Version)
+				line := fmt.Sprintf("%s (%s): %s", status.Name, webServer, status.Status)
+
+				if status.Error != "" {
+					line += ": " + strings.TrimSuffix(strings.Join(strings.Fields(status.Error), " "), ":")
+				}
+
+				if status.Service != nil {
+					line += fmt.Sprintf(", service: %s", status.Service.State)
+
+					if status.Service.Pid != 0 {
+						line += fmt.Sprintf(" (pid %d)", status.Service.Pid)
+					}
+				} else if status.ServiceError != "" {
+					line += ", service: unknown: " + strings.Join(strings.Fields(status.ServiceError), " ")
+				}
+
+				lines = append(lines, line)
+			}
+
+			return writelnOutput(cmd, strings.Join(lines, "\n"))
+		},
+	}
+
+	return &cmd
+}
+
+// getInstanceStatus checks that the instance binary is available and its configuration is valid.
+// Service state is reported for the instances with valid configuration.
+func getInstanceStatus(instance options.Instance) instanceStatus {
+	status := instanceStatus{Name: instance.Name, WebServer: instance.WebServer, Status: instanceStatusUnavailable}
+	values, _, err := getOptionValues(instance.WebServer, instance.Name)
+
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	params := options.GetParams(values)
+	cli, err := getWebServerCli(instance.WebServer, params)
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	if status.Version, err = cli.GetVersion(); err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	if err = cli.TestConfiguration(); err != nil {
+		status.Status = instanceStatusInvalid
+		status.Error = err.Error()
+
+		return status
+	}
+
+	manager, err := createWebServerManager(instance.WebServer, params)
+	if err != nil {
+		status.Status = instanceStatusInvalid
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Status = instanceStatusOk
+
+	serviceStatus, err := manager.Status()
+	if err != nil {
+		status.ServiceError = err.Error()
+
+		return status
+	}
+
+	status.Service = &serviceStatus
+
+	return status
+}
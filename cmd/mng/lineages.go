@@ -0,0 +1,164 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/lineage"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+func getLineagesCmd() *cobra.Command {
+	var certbotDir, acmeShDir string
+	var relink bool
+
+	cmd := cobra.Command{
+		Use:   "lineages",
+		Short: "show certificates of certbot and acme.sh used by hosts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lineages, skipped, err := lineage.LoadCertbotLineages(certbotDir)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			acmeShLineages, acmeShSkipped, err := lineage.LoadAcmeShLineages(acmeShDir)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			lineages = append(lineages, acmeShLineages...)
+			skipped = append(skipped, acmeShSkipped...)
+			// relinking changes the configuration, so the exclusive lock is required
+			mode := lock.Shared
+
+			if relink {
+				mode = lock.Exclusive
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, mode)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			configs, err := webServerManager.GetTLSConfigs("")
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			report := lineage.GetReport(lineages, configs)
+			report.Skipped = skipped
+
+			if relink {
+				return relinkStaleHosts(webServerManager, cmd, report)
+			}
+
+			if isJson {
+				output, err := json.Marshal(report)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			return writelnOutput(cmd, formatLineageReport(lineages, report))
+		},
+	}
+
+	cmd.Flags().StringVar(&certbotDir, flag.CertbotDirFlag, lineage.DefaultCertbotDir, "certbot configuration directory")
+	cmd.Flags().StringVar(&acmeShDir, flag.AcmeShDirFlag, lineage.DefaultAcmeShDir, "acme.sh home directory")
+	cmd.Flags().BoolVar(&relink, flag.RelinkFlag, false, "point hosts using certbot archive files at live symlinks")
+
+	return &cmd
+}
+
+// relinkStaleHosts deploys live certificate files of lineages to hosts pointing at archive files
+func relinkStaleHosts(webServerManager webserver.WebServerManagerInterface, cmd *cobra.Command, report lineage.Report) error {
+	serverNames := report.GetStaleServerNames()
+
+	if len(serverNames) == 0 {
+		return writelnOutput(cmd, "no hosts point at archive files")
+	}
+
+	for _, serverName := range serverNames {
+		var certificates []webserver.Certificate
+		var lineageNames []string
+
+		for _, usage := range report.Usages {
+			if usage.Stale && usage.Host.ServerName == serverName && !slices.Contains(lineageNames, usage.Lineage.Name) {
+				lineageNames = append(lineageNames, usage.Lineage.Name)
+				certificates = append(certificates, usage.Lineage.Certificate)
+			}
+		}
+
+		if err := webServerManager.DeployCertificates(serverName, certificates); err != nil {
+			err = fmt.Errorf("could not point host '%s' at live certificate files: %v", serverName, err)
+
+			return rollbackChanges(webServerManager, cmd, err)
+		}
+	}
+
+	if err := applyManagerChanges(webServerManager, cmd.Flag(flag.WebServerFlag).Value.String(), "could not relink hosts"); err != nil {
+		return writeOutput(cmd, err.Error())
+	}
+
+	return writelnOutput(cmd, fmt.Sprintf("hosts are pointed at live certificate files: %s", strings.Join(serverNames, ", ")))
+}
+
+func formatLineageReport(lineages []lineage.Lineage, report lineage.Report) string {
+	var lines []string
+
+	for _, skipped := range report.Skipped {
+		lines = append(lines, fmt.Sprintf("%s %s is skipped: %s", skipped.Source, skipped.Name, skipped.Error))
+	}
+
+	if len(lineages) == 0 {
+		return strings.Join(append(lines, "no certbot or acme.sh certificates found"), "\n")
+	}
+
+	for i, l := range lineages {
+		lines = append(lines, fmt.Sprintf(
+			"%s %s: %s, expires %s",
+			l.Source,
+			l.Name,
+			strings.Join(l.Domains, ", "),
+			l.NotAfter.Format(time.RFC3339),
+		))
+		lines = append(lines, fmt.Sprintf("  certificate: %s", l.Certificate.GetLeafPath()))
+		used := false
+
+		for _, usage := range report.Usages {
+			if usage.Lineage != &lineages[i] {
+				continue
+			}
+
+			used = true
+			line := fmt.Sprintf("  used by %s (%s): %s", usage.Host.ServerName, usage.Host.FilePath, usage.CertPath)
+
+			if usage.Stale {
+				line += " [stale archive path, use --relink]"
+			}
+
+			lines = append(lines, line)
+		}
+
+		if !used {
+			lines = append(lines, "  not used by any host")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package mng
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+func getLintCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "lint",
+		Short: "analyse webserver configuration. Exit code: 0 - ok, 1 - warnings, 2 - errors",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			findings, err := webServerManager.Lint()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if findings == nil {
+				findings = []webserver.Finding{}
+			}
+
+			if isJson {
+				output, err := json.Marshal(findings)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				err = writeOutput(cmd, string(output))
+			} else if len(findings) == 0 {
+				err = writelnOutput(cmd, "ok")
+			} else {
+				var lines []string
+
+				for _, finding := range findings {
+					lines = append(lines, finding.ToString())
+				}
+
+				err = writelnOutput(cmd, strings.Join(lines, "\n"))
+			}
+
+			if err != nil {
+				return err
+			}
+
+			os.Exit(webserver.GetHighestSeverity(findings).ExitCode())
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
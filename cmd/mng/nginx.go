@@ -21,6 +21,23 @@ func init() {
 	nginxCmd.AddCommand(getHostsCmd())
 	nginxCmd.AddCommand(getVersionCmd())
 	nginxCmd.AddCommand(getCheckCmd())
+	nginxCmd.AddCommand(getReloadCmd())
 	nginxCmd.AddCommand(getRestartCmd())
+	nginxCmd.AddCommand(getStartCmd())
+	nginxCmd.AddCommand(getStopCmd())
+	nginxCmd.AddCommand(getStatusCmd())
 	nginxCmd.AddCommand(getDeployCertificateCmd())
+	nginxCmd.AddCommand(getLintCmd())
+	nginxCmd.AddCommand(getTLSAuditCmd())
+	nginxCmd.AddCommand(getTLSPolicyCmd())
+	nginxCmd.AddCommand(getHeadersCmd())
+	nginxCmd.AddCommand(getStaplingCmd())
+	nginxCmd.AddCommand(getClientAuthCmd())
+	nginxCmd.AddCommand(getSelfSignedCmd())
+	nginxCmd.AddCommand(getAcmeWebrootCmd())
+	nginxCmd.AddCommand(getAcmeCmd())
+	nginxCmd.AddCommand(getLineagesCmd())
+	nginxCmd.AddCommand(getRenewDeployedCmd())
+	nginxCmd.AddCommand(getCertStoreCmd())
+	nginxCmd.AddCommand(getNginxInfoCmd())
 }
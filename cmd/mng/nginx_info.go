@@ -0,0 +1,73 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+func getNginxInfoCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "info",
+		Short: "show nginx build configuration, paths and available features",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			instanceLock, err := lockInstance(webserver.Nginx, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			nginxManager, err := getNginxManager()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			info, err := nginxManager.GetInfo()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if isJson {
+				output, err := json.Marshal(info)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			var features []string
+
+			for feature, available := range info.Features {
+				features = append(features, fmt.Sprintf("%s=%t", feature, available))
+			}
+
+			sort.Strings(features)
+
+			lines := []string{
+				"version: " + info.Version,
+				"compiler: " + info.Compiler,
+				"openssl: " + info.OpenSSLVersion,
+				"prefix: " + info.Prefix,
+				"conf path: " + info.ConfPath,
+				"pid path: " + info.PidPath,
+				"server root: " + info.ServerRoot,
+				"config file: " + info.ConfigFile,
+				"modules: " + strings.Join(info.Modules, ", "),
+				"dynamic modules: " + strings.Join(info.DynamicModules, ", "),
+				"loaded modules: " + strings.Join(info.LoadedModules, ", "),
+				"added modules: " + strings.Join(info.AddedModules, ", "),
+				"features: " + strings.Join(features, ", "),
+			}
+
+			return writelnOutput(cmd, strings.Join(lines, "\n"))
+		},
+	}
+
+	return &cmd
+}
@@ -0,0 +1,145 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+// portUsage is a port configured for the webserver hosts or bound by the webserver
+type portUsage struct {
+	WebServer  string `json:"webserver"`
+	Configured bool   `json:"configured"`
+	procstat.PortStatus
+}
+
+type portsReport struct {
+	Ports []portUsage `json:"ports"`
+	// Unavailable contains errors of the webservers which ports could not be detected
+	Unavailable map[string]string `json:"unavailable,omitempty"`
+}
+
+func getPortsCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ports",
+		Short: "show ports configured and bound by apache and nginx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := portsReport{Unavailable: make(map[string]string)}
+
+			for _, code := range []string{webserver.Apache, webserver.Nginx} {
+				ports, err := getWebServerPorts(code)
+
+				if err != nil {
+					report.Unavailable[code] = err.Error()
+
+					continue
+				}
+
+				report.Ports = append(report.Ports, ports...)
+			}
+
+			slices.SortStableFunc(report.Ports, func(a, b portUsage) int {
+				aNumber, _ := strconv.Atoi(a.Port)
+				bNumber, _ := strconv.Atoi(b.Port)
+
+				return aNumber - bNumber
+			})
+
+			if isJson {
+				output, err := json.Marshal(report)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			return writeOutput(cmd, formatPortsReport(report))
+		},
+	}
+
+	return &cmd
+}
+
+// getWebServerPorts compares ports configured for the webserver hosts with the ports bound by the webserver
+func getWebServerPorts(code string) ([]portUsage, error) {
+	instanceLock, err := lockInstance(code, lock.Shared)
+	if err != nil {
+		return nil, err
+	}
+	defer instanceLock.Release()
+
+	webServerManager, err := GetWebServerManager(code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := getServiceStatus(webServerManager)
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []portUsage
+	var portNumbers []string
+
+	for _, port := range status.Ports {
+		ports = append(ports, portUsage{WebServer: code, Configured: true, PortStatus: port})
+		portNumbers = append(portNumbers, port.Port)
+	}
+
+	if status.Process == nil {
+		return ports, nil
+	}
+
+	// ports bound without hosts, e.g. apache Listen directive without virtual hosts
+	for _, socket := range status.Process.Sockets {
+		if !slices.Contains(portNumbers, socket.Port) {
+			ports = append(ports, portUsage{WebServer: code, PortStatus: procstat.PortStatus{Port: socket.Port, Bound: true}})
+			portNumbers = append(portNumbers, socket.Port)
+		}
+	}
+
+	return ports, nil
+}
+
+func formatPortsReport(report portsReport) string {
+	var lines []string
+
+	for _, port := range report.Ports {
+		var states []string
+
+		if port.Configured {
+			states = append(states, "configured")
+		} else {
+			states = append(states, "not configured")
+		}
+
+		switch {
+		case port.Bound:
+			states = append(states, "bound")
+		case port.Conflict && port.OwnerPid != 0:
+			states = append(states, fmt.Sprintf("NOT BOUND, used by %s (pid %d)", port.OwnerName, port.OwnerPid))
+		case port.Conflict:
+			states = append(states, "NOT BOUND, used by another process")
+		default:
+			states = append(states, "NOT BOUND")
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s: %s", port.Port, port.WebServer, strings.Join(states, ", ")))
+	}
+
+	for _, code := range []string{webserver.Apache, webserver.Nginx} {
+		if err, ok := report.Unavailable[code]; ok {
+			lines = append(lines, fmt.Sprintf("%s: unavailable: %s", code, strings.Join(strings.Fields(err), " ")))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
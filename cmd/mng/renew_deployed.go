@@ -0,0 +1,149 @@
+package mng
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+// environment variables set by certbot for --deploy-hook commands
+const (
+	renewedLineageEnv = "RENEWED_LINEAGE"
+	renewedDomainsEnv = "RENEWED_DOMAINS"
+)
+
+func getRenewDeployedCmd() *cobra.Command {
+	var cert webserver.Certificate
+
+	cmd := cobra.Command{
+		Use:   "renew-deployed",
+		Short: "deploy renewed certificate to all hosts using a certificate for the same names",
+		Long: "Deploy renewed certificate to all hosts using a certificate for the same names.\n" +
+			"If certificate flags are not specified, the certificate is taken from certbot deploy hook environment: " +
+			renewedLineageEnv + " and " + renewedDomainsEnv + ".",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the certificate is taken from the environment if the command is run as certbot deploy hook
+			isHook := cert == (webserver.Certificate{})
+
+			renewedCert, names, err := getRenewedCertificate(cert)
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			leaf, err := certificate.LoadLeafCertificate(renewedCert.GetLeafPath())
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			keyType, err := certificate.LoadKeyType(renewedCert.GetLeafPath())
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			if len(names) == 0 {
+				names = certificate.GetNames(leaf)
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			configs, err := webServerManager.GetTLSConfigs("")
+			if err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			fingerprint := certificate.GetFingerprint(leaf)
+			usages := webserver.GetOutdatedUsages(webserver.GetCertificateUsages(configs), names, keyType, fingerprint)
+
+			if len(usages) == 0 {
+				return writelnOutput(cmd, fmt.Sprintf("no hosts use outdated certificates for %s", strings.Join(names, ", ")))
+			}
+
+			serverNames := webserver.GetUsagesServerNames(usages)
+
+			for _, serverName := range serverNames {
+				if err = webServerManager.DeployCertificates(serverName, []webserver.Certificate{renewedCert}); err != nil {
+					err = fmt.Errorf("could not deploy renewed certificate to host '%s': %v", serverName, err)
+
+					return writeRenewError(cmd, isHook, rollbackManagerChanges(webServerManager, err))
+				}
+			}
+
+			if err = applyManagerChanges(webServerManager, code, "could not deploy renewed certificate"); err != nil {
+				return writeRenewError(cmd, isHook, err)
+			}
+
+			var lines []string
+
+			for _, usage := range usages {
+				lines = append(lines, fmt.Sprintf("%s (%s): %s -> %s", usage.Host.ServerName, usage.CertPath, usage.Fingerprint, fingerprint))
+			}
+
+			return writelnOutput(cmd, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&cert.CertPath, flag.CertPathFlag, "", "renewed certificate path")
+	cmd.Flags().StringVar(&cert.KeyPath, flag.CertKeyPathFlag, "", "renewed certificate key path")
+	cmd.Flags().StringVar(&cert.ChainPath, flag.CertChainPathFlag, "", "renewed certificate chain path")
+	cmd.Flags().StringVar(&cert.FullChainPath, flag.CertFullChainPathFlag, "", "renewed certificate full chain path")
+
+	return &cmd
+}
+
+// writeRenewError writes the error. Certbot deploy hook exits with non-zero code, so certbot reports the failed deploy.
+func writeRenewError(cmd *cobra.Command, isHook bool, err error) error {
+	if outputErr := writelnOutput(cmd, err.Error()); outputErr != nil || !isHook {
+		return outputErr
+	}
+
+	os.Exit(1)
+
+	return nil
+}
+
+// getRenewedCertificate returns the certificate from flags or from certbot deploy hook environment
+func getRenewedCertificate(cert webserver.Certificate) (webserver.Certificate, []string, error) {
+	var names []string
+
+	if cert == (webserver.Certificate{}) {
+		lineage := os.Getenv(renewedLineageEnv)
+
+		if lineage == "" {
+			return cert, nil, fmt.Errorf("certificate is not specified and %s is not set", renewedLineageEnv)
+		}
+
+		cert = webserver.GetCertificateInDir(lineage)
+		names = strings.Fields(os.Getenv(renewedDomainsEnv))
+	}
+
+	if cert.GetLeafPath() == "" {
+		return cert, nil, errors.New("certificate path is not specified")
+	}
+
+	if cert.KeyPath == "" {
+		return cert, nil, errors.New("certificate key path is not specified")
+	}
+
+	if err := certificate.CheckKeyPair(cert.GetLeafPath(), cert.KeyPath); err != nil {
+		return cert, nil, err
+	}
+
+	return cert, names, nil
+}
@@ -20,8 +20,12 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&webServer, flag.WebServerFlag, "w", "", "webserver name")
 	RootCmd.PersistentFlags().MarkHidden(flag.WebServerFlag)
 	RootCmd.PersistentFlags().BoolVarP(&isJson, flag.JsonOutput, "j", false, "show result in json format")
+	addOptionFlags(RootCmd)
 	RootCmd.AddCommand(apacheCmd)
 	RootCmd.AddCommand(nginxCmd)
+	RootCmd.AddCommand(getConfigCmd())
+	RootCmd.AddCommand(getInstancesCmd())
+	RootCmd.AddCommand(getPortsCmd())
 }
 
 func writeOutput(cmd *cobra.Command, output string) error {
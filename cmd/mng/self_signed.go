@@ -0,0 +1,88 @@
+package mng
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/selfsigned"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+var stateDir string
+
+func getSelfSignedCmd() *cobra.Command {
+	var validityDays int
+	var config selfsigned.Config
+
+	cmd := cobra.Command{
+		Use:   "self-signed",
+		Short: "generate self-signed or local CA signed certificate and deploy it to host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			hosts, err := webServerManager.GetHostsByServerName(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if len(hosts) == 0 {
+				return writelnOutput(cmd, fmt.Sprintf("could not find host '%s'", hostName))
+			}
+
+			config.Validity = time.Duration(validityDays) * 24 * time.Hour
+			config.StateDir = stateDir
+			result, err := selfsigned.Issue(hostName, getCertificateNames(hostName, hosts), config)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not deploy certificate to host '%s'", hostName)
+
+			if err = webServerManager.DeployCertificates(hostName, []webserver.Certificate{result.Certificate}); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			status := "is up to date"
+			if result.Rotated {
+				status = "is generated"
+			}
+
+			return writelnOutput(cmd, fmt.Sprintf(
+				"certificate %s for %s %s, valid until %s",
+				result.Certificate.CertPath,
+				strings.Join(result.Names, ", "),
+				status,
+				result.NotAfter.Format(time.RFC3339),
+			))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&config.KeyType, flag.KeyTypeFlag, selfsigned.DefaultKeyType, "certificate key type: rsa, ecdsa")
+	cmd.Flags().IntVar(&validityDays, flag.DaysFlag, 365, "certificate validity in days")
+	cmd.Flags().StringVar(&config.OutputDir, flag.OutputDirFlag, "", "directory for certificate files, <state-dir>/certificates by default")
+	cmd.Flags().BoolVar(&config.UseCA, flag.CAFlag, false, "sign certificate with the local CA kept in the state directory")
+	cmd.Flags().StringVar(&stateDir, flag.StateDirFlag, state.DefaultDir, "webmng state directory")
+
+	return &cmd
+}
@@ -0,0 +1,201 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
+	"github.com/spf13/cobra"
+)
+
+func getReloadCmd() *cobra.Command {
+	return getServiceActionCmd("reload", "reload webserver configuration without dropping connections", func(controller webserver.ServiceController) error {
+		return controller.Reload()
+	})
+}
+
+func getRestartCmd() *cobra.Command {
+	return getServiceActionCmd("restart", "restart webserver", func(controller webserver.ServiceController) error {
+		return controller.Restart()
+	})
+}
+
+func getStartCmd() *cobra.Command {
+	return getServiceActionCmd("start", "start webserver", func(controller webserver.ServiceController) error {
+		return controller.Start()
+	})
+}
+
+func getStopCmd() *cobra.Command {
+	var graceful bool
+
+	cmd := getServiceActionCmd("stop", "stop webserver", func(controller webserver.ServiceController) error {
+		return controller.Stop(graceful)
+	})
+	cmd.Flags().BoolVar(&graceful, flag.GracefulFlag, false, "wait for the current connections to be served")
+
+	return cmd
+}
+
+// serviceStatusOutput is a status of the webserver service, its processes and ports
+type serviceStatusOutput struct {
+	webserver.ServiceStatus
+	Process *procstat.ProcessStatus `json:"process,omitempty"`
+	Ports   []procstat.PortStatus   `json:"ports"`
+}
+
+func getStatusCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "status",
+		Short: "show webserver service status, processes and ports",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			status, err := getServiceStatus(webServerManager)
+
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if isJson {
+				output, err := json.Marshal(status)
+
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			return writeOutput(cmd, formatServiceStatus(status))
+		},
+	}
+
+	return &cmd
+}
+
+func getServiceStatus(webServerManager webserver.WebServerManagerInterface) (serviceStatusOutput, error) {
+	var status serviceStatusOutput
+	var serverSockets []procstat.Socket
+	var err error
+
+	if status.ServiceStatus, err = webServerManager.Status(); err != nil {
+		return status, err
+	}
+
+	if status.Running && status.Pid != 0 {
+		processStatus, err := procstat.GetProcessStatus(status.Pid)
+		if err != nil {
+			return status, err
+		}
+
+		status.Process = &processStatus
+		serverSockets = processStatus.Sockets
+	}
+
+	hosts, err := webServerManager.GetHosts()
+	if err != nil {
+		return status, err
+	}
+
+	sockets, err := procstat.GetListeningSockets()
+	if err != nil {
+		return status, err
+	}
+
+	status.Ports = procstat.ComparePorts(procstat.GetConfiguredPorts(hosts), serverSockets, sockets)
+
+	return status, nil
+}
+
+func formatServiceStatus(status serviceStatusOutput) string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("state: %s", status.State), fmt.Sprintf("backend: %s", status.Backend))
+
+	if status.Unit != "" {
+		lines = append(lines, fmt.Sprintf("unit: %s", status.Unit))
+	}
+
+	if process := status.Process; process != nil {
+		var sockets []string
+
+		for _, socket := range process.Sockets {
+			sockets = append(sockets, socket.String())
+		}
+
+		lines = append(
+			lines,
+			fmt.Sprintf("pid: %d", process.Pid),
+			fmt.Sprintf("workers: %d", process.Workers),
+			fmt.Sprintf("uptime: %s", process.Uptime()),
+			fmt.Sprintf("rss: %.1f MiB", float64(process.Rss)/(1024*1024)),
+			fmt.Sprintf("cpu: %.1f%%", process.Cpu),
+			fmt.Sprintf("config generation: %s", process.ConfigGeneration.Format(time.RFC3339)),
+			fmt.Sprintf("sockets: %s", strings.Join(sockets, " ")),
+		)
+	} else if status.Pid != 0 {
+		lines = append(lines, fmt.Sprintf("pid: %d", status.Pid))
+	}
+
+	lines = append(lines, "ports:")
+
+	for _, port := range status.Ports {
+		switch {
+		case port.Bound:
+			lines = append(lines, fmt.Sprintf("    %s: bound", port.Port))
+		case port.Conflict && port.OwnerPid != 0:
+			lines = append(lines, fmt.Sprintf("    %s: NOT BOUND, used by %s (pid %d)", port.Port, port.OwnerName, port.OwnerPid))
+		case port.Conflict:
+			lines = append(lines, fmt.Sprintf("    %s: NOT BOUND, used by another process", port.Port))
+		default:
+			lines = append(lines, fmt.Sprintf("    %s: NOT BOUND", port.Port))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func getServiceActionCmd(use, short string, action func(controller webserver.ServiceController) error) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if err = action(webServerManager); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			return writelnOutput(cmd, "ok")
+		},
+	}
+
+	return &cmd
+}
@@ -0,0 +1,94 @@
+package mng
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/spf13/cobra"
+)
+
+func getStaplingCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "stapling",
+		Short: "manage OCSP stapling of hosts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getStaplingEnableCmd())
+	cmd.AddCommand(getStaplingDisableCmd())
+
+	return &cmd
+}
+
+func getStaplingEnableCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "enable",
+		Short: "enable OCSP stapling for host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not enable OCSP stapling for host '%s'", hostName)
+			staplingConfig := webserver.StaplingConfig{ChainPath: certChainPath, Resolvers: resolvers}
+
+			if err = webServerManager.EnableStapling(hostName, staplingConfig); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+	cmd.Flags().StringVar(&certChainPath, flag.CertChainPathFlag, "", "issuer certificates path (nginx only). Certificates following the leaf one are used if not specified")
+	cmd.Flags().StringArrayVar(&resolvers, flag.ResolverFlag, nil, "DNS resolver (nginx only). System resolvers are used if not specified")
+
+	return &cmd
+}
+
+func getStaplingDisableCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "disable",
+		Short: "disable OCSP stapling for host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not disable OCSP stapling for host '%s'", hostName)
+
+			if err = webServerManager.DisableStapling(hostName); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			return applyChanges(webServerManager, cmd, errPrefix)
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.MarkFlagRequired(flag.HostFlag)
+
+	return &cmd
+}
@@ -0,0 +1,103 @@
+package mng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsaudit"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/spf13/cobra"
+)
+
+var tlsProfileName string
+
+func getTLSAuditCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "tls-audit",
+		Short: "audit TLS configuration of hosts against Mozilla profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := tlsprofile.GetProfile(tlsProfileName); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			configs, err := webServerManager.GetTLSConfigs(hostName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			reports := []tlsaudit.Report{}
+
+			for _, config := range configs {
+				reports = append(reports, tlsaudit.Audit(config))
+			}
+
+			if isJson {
+				output, err := json.Marshal(reports)
+				if err != nil {
+					return writeOutput(cmd, err.Error())
+				}
+
+				return writeOutput(cmd, string(output))
+			}
+
+			if len(reports) == 0 {
+				return writelnOutput(cmd, "no ssl hosts found")
+			}
+
+			var outputParts []string
+
+			for _, report := range reports {
+				outputParts = append(outputParts, formatTLSReport(report, tlsProfileName))
+			}
+
+			return writelnOutput(cmd, strings.Join(outputParts, "\n\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name. All ssl hosts are audited if not specified")
+	cmd.Flags().StringVar(&tlsProfileName, flag.ProfileFlag, tlsprofile.Intermediate, "profile to show remediation for: modern, intermediate, old")
+
+	return &cmd
+}
+
+func formatTLSReport(report tlsaudit.Report, profile string) string {
+	lines := []string{fmt.Sprintf("%s (%s): grade %s", report.ServerName, report.FilePath, report.Grade)}
+	result, ok := report.GetProfileResult(profile)
+
+	if !ok {
+		return lines[0]
+	}
+
+	lines = append(lines, fmt.Sprintf("  %s profile:", result.Profile))
+
+	for _, check := range result.Checks {
+		line := fmt.Sprintf("    [%s] %s: %s", check.Status, check.Setting, check.Value)
+
+		if check.Message != "" {
+			line += " - " + check.Message
+		}
+
+		lines = append(lines, line)
+
+		for _, remediation := range check.Remediation {
+			lines = append(lines, "        "+remediation)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,99 @@
+package mng
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	allHosts,
+	useSnippet bool
+)
+
+func getTLSPolicyCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "tls-policy",
+		Short: "manage TLS policy of hosts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(getTLSPolicyApplyCmd())
+
+	return &cmd
+}
+
+func getTLSPolicyApplyCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "apply",
+		Short: "apply Mozilla TLS profile to hosts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (hostName == "") == !allHosts {
+				return writelnOutput(cmd, "either --host or --all must be specified")
+			}
+
+			profile, err := tlsprofile.GetProfile(tlsProfileName)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Exclusive)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
+			webServerManager, err := GetWebServerManager(code, nil)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			version, err := webServerManager.GetVersion()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			openSSLVersion, err := webServerManager.GetOpenSSLVersion()
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			profile, warnings, err := profile.AdaptToVersion(code, version, openSSLVersion)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			errPrefix := fmt.Sprintf("could not apply %s TLS profile", profile.Name)
+
+			if err = webServerManager.ApplyTLSProfile(hostName, profile, useSnippet); err != nil {
+				return rollbackChanges(webServerManager, cmd, fmt.Errorf("%s: %v", errPrefix, err))
+			}
+
+			// warnings are not relevant if the profile is not applied
+			if err = applyManagerChanges(webServerManager, code, errPrefix); err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+
+			if len(warnings) > 0 {
+				return writelnOutput(cmd, strings.Join(warnings, "\n"))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tlsProfileName, flag.ProfileFlag, "", "profile to apply: modern, intermediate, old")
+	cmd.MarkFlagRequired(flag.ProfileFlag)
+	cmd.Flags().StringVar(&hostName, flag.HostFlag, "", "host name")
+	cmd.Flags().BoolVar(&allHosts, flag.AllFlag, false, "apply the profile to all ssl hosts")
+	cmd.Flags().BoolVar(&useSnippet, flag.SnippetFlag, false, "write directives to a shared file included by the hosts")
+
+	return &cmd
+}
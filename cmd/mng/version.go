@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/r2dtools/webmng/cmd/flag"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,12 @@ func getVersionCmd() *cobra.Command {
 		Short: "Show webserver version",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			code := cmd.Flag(flag.WebServerFlag).Value.String()
+			instanceLock, err := lockInstance(code, lock.Shared)
+			if err != nil {
+				return writeOutput(cmd, err.Error())
+			}
+			defer instanceLock.Release()
+
 			webServerManager, err := GetWebServerManager(code, nil)
 
 			if err != nil {
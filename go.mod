@@ -10,6 +10,7 @@ require (
 	github.com/spf13/cobra v1.7.0
 	github.com/stretchr/testify v1.8.4
 	github.com/unknwon/com v1.0.1
+	golang.org/x/crypto v0.11.0
 	golang.org/x/exp v0.0.0-20230817173708-d852ddb80c63
 	gopkg.in/yaml.v3 v3.0.1
 	honnef.co/go/augeas v0.0.0-20161110001225-ca62e35ed6b8
@@ -0,0 +1,148 @@
+package apache
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+// acmeChallengeRewriteRule stops mod_rewrite processing of the host for challenge requests.
+// Host level rewrite rules are applied before Alias, so the rule is inserted ahead of them.
+var acmeChallengeRewriteRule = []string{`^/\.well-known/acme-challenge/`, "-", "[L]"}
+
+// EnableAcmeWebroot serves ACME HTTP-01 challenges of hosts with the server name from the webroot directory.
+// Redirect directives of mod_alias are not exempted, ACME servers follow redirects to the ssl host served from the same webroot.
+func (m *ApacheManager) EnableAcmeWebroot(serverName, webroot string) error {
+	if !m.parser.ModuleExists("alias_module") {
+		return m.enableModule("alias", false)
+	}
+
+	aHosts := m.getApacheHostsWithServerName(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	challengeDir := webserver.GetAcmeChallengeDir(m.getAbsPath(webroot))
+
+	for _, aHost := range aHosts {
+		m.disableHostAcmeWebroot(aHost.AugPath)
+
+		if err := m.parser.AddDirective(aHost.AugPath, "Alias", []string{webserver.AcmeChallengePath, challengeDir + "/"}); err != nil {
+			return fmt.Errorf("could not add 'Alias' directive to host %s: %v", aHost.ServerName, err)
+		}
+
+		dirPath, err := m.parser.GetSection(aHost.AugPath, "Directory", challengeDir, true)
+		if err != nil {
+			return err
+		}
+
+		dirDirectives := [][]string{{"Require", "all", "granted"}, {"Options", "None"}, {"AllowOverride", "None"}}
+
+		if m.parser.ModuleExists("rewrite_module") {
+			dirDirectives = append(dirDirectives, []string{"RewriteEngine", "Off"})
+		}
+
+		for _, directive := range dirDirectives {
+			if err := m.parser.AddDirective(dirPath, directive[0], directive[1:]); err != nil {
+				return fmt.Errorf("could not add '%s' directive to host %s: %v", directive[0], aHost.ServerName, err)
+			}
+		}
+
+		for _, rewritePath := range getRewriteContextPaths(aHost.AugPath) {
+			// the first rewrite directive of each context
+			rewriteMatches, err := m.parser.Augeas.Match(fmt.Sprintf(
+				"%s/directive[self::directive=~regexp('RewriteCond|RewriteRule', 'i')][1]",
+				rewritePath,
+			))
+			if err != nil {
+				return err
+			}
+
+			for _, rewriteMatch := range rewriteMatches {
+				if err := m.parser.InsertDirective(rewriteMatch, "RewriteRule", acmeChallengeRewriteRule); err != nil {
+					return fmt.Errorf("could not add 'RewriteRule' directive to host %s: %v", aHost.ServerName, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetAcmeWebroot returns the webroot ACME HTTP-01 challenges of hosts with the server name are served from by Alias directive.
+// Empty webroot is returned if hosts have no challenge Alias.
+func (m *ApacheManager) GetAcmeWebroot(serverName string) (string, error) {
+	for _, aHost := range m.getApacheHostsWithServerName(serverName) {
+		matches, err := m.parser.Augeas.Match(fmt.Sprintf(
+			"%s/directive[self::directive=~regexp('Alias', 'i')][arg[1]='%s']/arg[2]",
+			aHost.AugPath,
+			webserver.AcmeChallengePath,
+		))
+		if err != nil {
+			return "", err
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		challengeDir, err := m.parser.GetArg(matches[0])
+		if err != nil {
+			return "", err
+		}
+
+		challengeDir = strings.TrimSuffix(m.getAbsPath(challengeDir), "/")
+		suffix := webserver.GetAcmeChallengeDir("/")
+
+		if !strings.HasSuffix(challengeDir, suffix) {
+			return "", fmt.Errorf("could not detect webroot of ACME challenge Alias of host '%s'", aHost.ServerName)
+		}
+
+		return filepath.Join("/", strings.TrimSuffix(challengeDir, suffix)), nil
+	}
+
+	return "", nil
+}
+
+// DisableAcmeWebroot removes ACME HTTP-01 challenge configuration from hosts with the server name
+func (m *ApacheManager) DisableAcmeWebroot(serverName string) error {
+	aHosts := m.getApacheHostsWithServerName(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, aHost := range aHosts {
+		m.disableHostAcmeWebroot(aHost.AugPath)
+	}
+
+	return nil
+}
+
+func (m *ApacheManager) disableHostAcmeWebroot(hostPath string) {
+	m.parser.Augeas.Remove(fmt.Sprintf(
+		"%s/directive[self::directive=~regexp('Alias', 'i')][arg[1]='%s']",
+		hostPath,
+		webserver.AcmeChallengePath,
+	))
+	m.parser.Augeas.Remove(fmt.Sprintf(
+		"%s/*[label()=~regexp('Directory', 'i')][arg=~regexp('\"?.*/\\.well-known/acme-challenge/?\"?')]",
+		hostPath,
+	))
+
+	for _, rewritePath := range getRewriteContextPaths(hostPath) {
+		m.parser.Augeas.Remove(fmt.Sprintf(
+			"%s/directive[self::directive=~regexp('RewriteRule', 'i')][arg[1]='%s']",
+			rewritePath,
+			acmeChallengeRewriteRule[0],
+		))
+	}
+}
+
+// getRewriteContextPaths returns host level paths where rewrite rules are applied: the host itself and its IfModule sections
+func getRewriteContextPaths(hostPath string) []string {
+	return []string{hostPath, hostPath + "/IfModule"}
+}
@@ -1,34 +1,86 @@
 package apachectl
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strings"
 
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/unknwon/com"
 )
 
+var errOffline = errors.New("apache process could not be controlled in offline mode")
+
 type ApacheCtl struct {
+	runner  runner.Runner
 	binPath string
+	// args are passed to every apachectl call: -d server root, -f config file
+	args      []string
+	reloadCmd []string
+	// offline apachectl does not call apache binary: version, modules and defines are taken from options,
+	// configuration check and restart are delegated to user commands or skipped
+	offline  bool
+	version  string
+	modules  []string
+	defines  []string
+	checkCmd []string
 }
 
-func GetApacheCtl() (ApacheCtl, error) {
+// GetApacheCtl returns apachectl for the apache instance running commands via cmdRunner. The binary is detected if binPath is empty.
+// If reloadCmd is not empty it is used to restart apache instead of apachectl.
+func GetApacheCtl(binPath, serverRoot, configFile, reloadCmd string, cmdRunner runner.Runner) (ApacheCtl, error) {
 	var apacheCtl ApacheCtl
+	var err error
 
-	binPath, err := detectCtlCmd()
-	if err != nil {
-		return apacheCtl, err
+	if binPath == "" {
+		if binPath, err = detectCtlCmd(cmdRunner); err != nil {
+			return apacheCtl, err
+		}
 	}
 
+	apacheCtl.runner = cmdRunner
 	apacheCtl.binPath = binPath
+	apacheCtl.reloadCmd = strings.Fields(reloadCmd)
+
+	if serverRoot != "" {
+		apacheCtl.args = append(apacheCtl.args, "-d", serverRoot)
+	}
+
+	if configFile != "" {
+		apacheCtl.args = append(apacheCtl.args, "-f", configFile)
+	}
 
 	return apacheCtl, nil
 }
 
+// GetOfflineApacheCtl returns apachectl that does not require apache binary.
+// defines are NAME=value or NAME items. checkCmd and reloadCmd are run instead of configuration check and restart if they are not empty.
+func GetOfflineApacheCtl(version string, modules, defines []string, checkCmd, reloadCmd string, cmdRunner runner.Runner) ApacheCtl {
+	return ApacheCtl{
+		runner:    cmdRunner,
+		offline:   true,
+		version:   version,
+		modules:   modules,
+		defines:   defines,
+		checkCmd:  strings.Fields(checkCmd),
+		reloadCmd: strings.Fields(reloadCmd),
+	}
+}
+
+// IsOffline checks if apachectl works without apache binary
+func (a ApacheCtl) IsOffline() bool {
+	return a.offline
+}
+
 // ParseIncludes returns Include directives from httpd process and returns a list of their values.
+// Includes are not known in offline mode and only parsed from the configuration.
 func (a ApacheCtl) ParseIncludes() ([]string, error) {
+	if a.offline {
+		return nil, nil
+	}
+
 	params := []string{"-t", "-D", "DUMP_INCLUDES"}
 
 	return a.parseCmdOutput(params, `\(.*\) (.*)`, 1)
@@ -36,6 +88,16 @@ func (a ApacheCtl) ParseIncludes() ([]string, error) {
 
 // ParseModules return the list of loaded module names.
 func (a ApacheCtl) ParseModules() ([]string, error) {
+	if a.offline {
+		var modules []string
+
+		for _, module := range a.modules {
+			modules = append(modules, strings.TrimSuffix(module, "_module"))
+		}
+
+		return modules, nil
+	}
+
 	params := []string{"-t", "-D", "DUMP_MODULES"}
 
 	output, err := a.parseCmdOutput(params, `(.*)_module`, 1)
@@ -53,11 +115,16 @@ func (a ApacheCtl) ParseModules() ([]string, error) {
 
 // ParseDefines returns a map of the defined variables.
 func (a ApacheCtl) ParseDefines() (map[string]string, error) {
-	params := []string{"-t", "-D", "DUMP_RUN_CFG"}
-	items, err := a.parseCmdOutput(params, `Define: ([^ \n]*)`, 1)
+	items := a.defines
 
-	if err != nil {
-		return nil, err
+	if !a.offline {
+		var err error
+		params := []string{"-t", "-D", "DUMP_RUN_CFG"}
+		items, err = a.parseCmdOutput(params, `Define: ([^ \n]*)`, 1)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	variables := make(map[string]string)
@@ -85,6 +152,14 @@ func (a ApacheCtl) ParseDefines() (map[string]string, error) {
 
 // GetVersion returns apache version
 func (a ApacheCtl) GetVersion() (string, error) {
+	if a.offline {
+		if a.version == "" {
+			return "", errors.New("apache version is not specified in offline mode")
+		}
+
+		return a.version, nil
+	}
+
 	params := []string{"-v"}
 	result, err := a.parseCmdOutput(params, `(?i)Apache/([0-9\.]*)`, 1)
 
@@ -101,6 +176,14 @@ func (a ApacheCtl) GetVersion() (string, error) {
 
 // TestConfiguration checks the syntax of apache configuration files
 func (a ApacheCtl) TestConfiguration() error {
+	if a.offline {
+		if len(a.checkCmd) > 0 {
+			return a.runCmd(a.checkCmd)
+		}
+
+		return nil
+	}
+
 	if _, err := a.execCmd([]string{"-t"}); err != nil {
 		return err
 	}
@@ -108,9 +191,55 @@ func (a ApacheCtl) TestConfiguration() error {
 	return nil
 }
 
+// TestConfigurationAt tests configuration of the other tree, e.g. staged copy of the configuration
+func (a ApacheCtl) TestConfigurationAt(serverRoot, configFile string) error {
+	if a.offline {
+		return errOffline
+	}
+
+	_, err := a.runner.Run(context.Background(), runner.Command{Name: a.binPath, Args: []string{"-d", serverRoot, "-f", configFile, "-t"}})
+
+	return err
+}
+
+// Reload gracefully restarts apache, current connections are not aborted
+func (a ApacheCtl) Reload() error {
+	if len(a.reloadCmd) > 0 {
+		return a.runCmd(a.reloadCmd)
+	}
+
+	if a.offline {
+		return nil
+	}
+
+	return a.signal("graceful")
+}
+
 // Restart restarts apache webserver
 func (a ApacheCtl) Restart() error {
-	if _, err := a.execCmd([]string{"-k", "restart"}); err != nil {
+	return a.signal("restart")
+}
+
+// Start starts apache webserver
+func (a ApacheCtl) Start() error {
+	return a.signal("start")
+}
+
+// Stop stops apache webserver. Graceful stop waits for the current requests to be served.
+func (a ApacheCtl) Stop(graceful bool) error {
+	if graceful {
+		return a.signal("graceful-stop")
+	}
+
+	return a.signal("stop")
+}
+
+func (a ApacheCtl) signal(signal string) error {
+	if a.offline {
+		return errOffline
+	}
+
+	if _, err := a.execCmd([]string{"-k", signal}); err != nil {
 		return err
 	}
 
@@ -141,24 +270,28 @@ func (a ApacheCtl) parseCmdOutput(params []string, regexpStr string, captureGrou
 }
 
 func (a ApacheCtl) execCmd(params []string) ([]byte, error) {
-	cmd := exec.Command(a.binPath, params...)
-	output, err := cmd.CombinedOutput()
+	args := append(append([]string{}, a.args...), params...)
+	result, err := a.runner.Run(context.Background(), runner.Command{Name: a.binPath, Args: args})
 
 	if err != nil {
-		return nil, fmt.Errorf("%v: %s", err, output)
+		return nil, err
 	}
 
-	return output, nil
+	return result.Output(), nil
 }
 
-func detectCtlCmd() (string, error) {
+func (a ApacheCtl) runCmd(command []string) error {
+	_, err := a.runner.Run(context.Background(), runner.Command{Name: command[0], Args: command[1:]})
+
+	return err
+}
+
+func detectCtlCmd(cmdRunner runner.Runner) (string, error) {
 	ctlCmds := []string{"apache2ctl", "httpd"}
 	ctlPaths := []string{"/usr/sbin/apache2ctl", "/etc/sbin/httpd", "/usr/sbin/httpd"}
 
 	for _, ctlCmd := range ctlCmds {
-		cmd := exec.Command("which", ctlCmd)
-
-		if _, err := cmd.Output(); err == nil {
+		if _, err := cmdRunner.LookPath(ctlCmd); err == nil {
 			return ctlCmd, nil
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/r2dtools/webmng/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,8 +57,56 @@ func TestParseDefines(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestApacheCtlWithFakeRunner(t *testing.T) {
+	cmdRunner := runner.GetFakeRunner().
+		AddPath("apache2ctl", "/usr/sbin/apache2ctl").
+		On("apache2ctl -d /etc/apache2 -v", runner.FakeResponse{Stdout: "Server version: Apache/2.4.57 (Debian)\n"}).
+		On("apache2ctl -d /etc/apache2 -t -D DUMP_MODULES", runner.FakeResponse{Stdout: "Loaded Modules:\n core_module (static)\n ssl_module (shared)\n"}).
+		On("apache2ctl -d /etc/apache2 -t -D DUMP_RUN_CFG", runner.FakeResponse{Stdout: "Define: DUMP_RUN_CFG\nDefine: APACHE_RUN_USER=www-data\nDefine: ENABLE_USR_LIB_CGI_BIN\n"}).
+		On("apache2ctl -d /etc/apache2 -t", runner.FakeResponse{Stderr: "AH00526: Syntax error on line 3", ExitCode: 1}).
+		On("apache2ctl -d /etc/apache2 -k restart", runner.FakeResponse{Err: runner.ErrTimeout})
+
+	apacheCtl, err := GetApacheCtl("", "/etc/apache2", "", "", cmdRunner)
+	assert.Nilf(t, err, "failed to create apachectl: %v", err)
+
+	version, err := apacheCtl.GetVersion()
+	assert.Nilf(t, err, "could not get apache version: %v", err)
+	assert.Equal(t, "2.4.57", version)
+
+	modules, err := apacheCtl.ParseModules()
+	assert.Nilf(t, err, "could not parse modules: %v", err)
+	assert.Equal(t, []string{"core", "ssl"}, modules)
+
+	defines, err := apacheCtl.ParseDefines()
+	assert.Nilf(t, err, "could not parse defines: %v", err)
+	assert.Equal(t, map[string]string{"APACHE_RUN_USER": "www-data", "ENABLE_USR_LIB_CGI_BIN": ""}, defines)
+
+	err = apacheCtl.TestConfiguration()
+	assert.NotNil(t, err, "invalid configuration must be reported")
+	assert.Contains(t, err.Error(), "AH00526")
+	assert.ErrorIs(t, apacheCtl.Restart(), runner.ErrTimeout)
+
+	cmdRunner.On("apache2ctl -d /etc/apache2 -k graceful", runner.FakeResponse{}).
+		On("apache2ctl -d /etc/apache2 -k graceful-stop", runner.FakeResponse{})
+	assert.Nil(t, apacheCtl.Reload())
+	assert.Nil(t, apacheCtl.Stop(true))
+	assert.Equal(t, "apache2ctl -d /etc/apache2 -k graceful-stop", cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-1])
+
+	cmdRunner.On("apache2ctl -d /tmp/stage -f /tmp/stage/apache2.conf -t", runner.FakeResponse{Stderr: "Syntax OK"})
+	err = apacheCtl.TestConfigurationAt("/tmp/stage", "/tmp/stage/apache2.conf")
+	assert.Nilf(t, err, "staged configuration must be valid: %v", err)
+
+	apacheCtl = GetOfflineApacheCtl("2.4.57", nil, nil, "", "", cmdRunner)
+	assert.Nil(t, apacheCtl.Reload(), "reload must be skipped in offline mode")
+	assert.NotNil(t, apacheCtl.Start(), "apache could not be started in offline mode")
+}
+
 func getApacheCtl(t *testing.T) ApacheCtl {
-	apacheCtl, err := GetApacheCtl()
+	if testing.Short() {
+		t.Skip("apache is required")
+	}
+
+	apacheCtl, err := GetApacheCtl("", "", "", "", runner.GetExecRunner(0))
 	assert.Nilf(t, err, "failed to create apachectl: %v", err)
 
 	return apacheCtl
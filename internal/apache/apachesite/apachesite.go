@@ -1,17 +1,18 @@
 package apachesite
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 
-	"github.com/r2dtools/webmng/pkg/utils"
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/unknwon/com"
 )
 
 // Site implements functionality for site enabling/disabling
 type ApacheSite struct {
+	runner                runner.Runner
 	dissiteBin, ensiteBin string
 }
 
@@ -36,21 +37,21 @@ func (s ApacheSite) Disable(hostConfigName string) error {
 }
 
 func (s ApacheSite) execCmd(command string, params []string) ([]byte, error) {
-	cmd := exec.Command(command, params...)
-	output, err := cmd.Output()
+	result, err := s.runner.Run(context.Background(), runner.Command{Name: command, Args: params})
 
 	if err != nil {
 		return nil, fmt.Errorf("could not execute '%s' command: %v", command, err)
 	}
 
-	return output, nil
+	return result.Stdout, nil
 }
 
-func GetApacheSite() (ApacheSite, error) {
+// GetApacheSite returns a2ensite/a2dissite wrapper running commands via cmdRunner
+func GetApacheSite(cmdRunner runner.Runner) (ApacheSite, error) {
 	ensiteBinPaths := []string{"/usr/sbin/a2ensite"}
 	dissiteBinPaths := []string{"/usr/sbin/a2dissite"}
 
-	ensiteBin, err := utils.GetCommandBinPath("a2ensite")
+	ensiteBin, err := cmdRunner.LookPath("a2ensite")
 	if err != nil {
 		for _, cmdPath := range ensiteBinPaths {
 			if com.IsFile(cmdPath) {
@@ -60,10 +61,10 @@ func GetApacheSite() (ApacheSite, error) {
 		}
 	}
 
-	dissiteBin, err := utils.GetCommandBinPath("a2dissite")
+	dissiteBin, err := cmdRunner.LookPath("a2dissite")
 	if err != nil {
 		for _, cmdPath := range dissiteBinPaths {
-			if !com.IsFile(cmdPath) {
+			if com.IsFile(cmdPath) {
 				dissiteBin = cmdPath
 				break
 			}
@@ -74,5 +75,5 @@ func GetApacheSite() (ApacheSite, error) {
 		return ApacheSite{}, errors.New("a2ensite/a2dissite binaries do not exist")
 	}
 
-	return ApacheSite{ensiteBin: ensiteBin, dissiteBin: dissiteBin}, nil
+	return ApacheSite{runner: cmdRunner, ensiteBin: ensiteBin, dissiteBin: dissiteBin}, nil
 }
@@ -0,0 +1,120 @@
+package apache
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+)
+
+const clientDNVariable = "%{SSL_CLIENT_S_DN}s"
+
+var clientVerifyModes = map[string]string{
+	webserver.ClientVerifyOn:           "require",
+	webserver.ClientVerifyOptional:     "optional",
+	webserver.ClientVerifyOptionalNoCA: "optional_no_ca",
+}
+
+// EnableClientAuth enables client certificate authentication for ssl hosts with the server name.
+// If the path is specified, client certificates are verified within <Location path> only.
+func (m *ApacheManager) EnableClientAuth(serverName string, config webserver.ClientAuthConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	if config.ForwardHeader != "" && !m.parser.ModuleExists("headers_module") {
+		return m.enableModule("headers", false)
+	}
+
+	aHosts := m.getSslApacheHosts(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, aHost := range aHosts {
+		// SSLCACertificateFile is not allowed in <Location>
+		m.removeHostDirective(aHost.AugPath, tlsprofile.Directive{Name: "SSLCACertificateFile"})
+
+		if err := m.parser.AddDirective(aHost.AugPath, "SSLCACertificateFile", []string{m.getAbsPath(config.CACertPath)}); err != nil {
+			return fmt.Errorf("could not add 'SSLCACertificateFile' directive to host %s: %v", aHost.ServerName, err)
+		}
+
+		targetPath, err := m.getClientAuthPath(aHost, config.Path, true)
+		if err != nil {
+			return err
+		}
+
+		directives := []tlsprofile.Directive{{Name: "SSLVerifyClient", Values: []string{clientVerifyModes[config.Verify]}}}
+
+		if config.Depth > 0 {
+			directives = append(directives, tlsprofile.Directive{Name: "SSLVerifyDepth", Values: []string{strconv.Itoa(config.Depth)}})
+		}
+
+		m.removeClientAuthDirectives(targetPath)
+
+		if config.ForwardHeader != "" {
+			directives = append(directives, tlsprofile.Directive{
+				Name:   "RequestHeader",
+				Values: []string{"set", config.ForwardHeader, headers.Quote(clientDNVariable)},
+			})
+		}
+
+		for _, directive := range directives {
+			if err := m.parser.AddDirective(targetPath, directive.Name, directive.Values); err != nil {
+				return fmt.Errorf("could not add '%s' directive to host %s: %v", directive.Name, aHost.ServerName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DisableClientAuth disables client certificate authentication for ssl hosts with the server name.
+// If the path is specified, only authentication within <Location path> is disabled.
+func (m *ApacheManager) DisableClientAuth(serverName, path string) error {
+	aHosts := m.getSslApacheHosts(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, aHost := range aHosts {
+		targetPath, err := m.getClientAuthPath(aHost, path, false)
+		if err != nil {
+			return err
+		}
+
+		if targetPath == "" {
+			continue
+		}
+
+		m.removeClientAuthDirectives(targetPath)
+
+		if path == "" {
+			m.removeHostDirective(aHost.AugPath, tlsprofile.Directive{Name: "SSLCACertificateFile"})
+		}
+	}
+
+	return nil
+}
+
+// getClientAuthPath returns augeas path of the host or of its <Location path> section
+func (m *ApacheManager) getClientAuthPath(aHost apacheHost, path string, create bool) (string, error) {
+	if path == "" {
+		return aHost.AugPath, nil
+	}
+
+	return m.parser.GetSection(aHost.AugPath, "Location", path, create)
+}
+
+func (m *ApacheManager) removeClientAuthDirectives(augPath string) {
+	m.removeHostDirective(augPath, tlsprofile.Directive{Name: "SSLVerifyClient"})
+	m.removeHostDirective(augPath, tlsprofile.Directive{Name: "SSLVerifyDepth"})
+	m.parser.Augeas.Remove(fmt.Sprintf(
+		"%s/directive[self::directive=~regexp('RequestHeader', 'i')][arg=~regexp('.*SSL_CLIENT_S_DN.*')]",
+		augPath,
+	))
+}
@@ -0,0 +1,151 @@
+package apache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"golang.org/x/exp/slices"
+)
+
+// headerSetActions are mod_headers actions which add a header to the response
+var headerSetActions = []string{"set", "append", "add", "merge", "setifempty"}
+
+// GetHeaders returns response headers set by mod_headers for hosts. All hosts are used if serverName is empty.
+func (m *ApacheManager) GetHeaders(serverName string) ([]webserver.HostHeaders, error) {
+	globalMatches, err := m.getGlobalDirectiveMatches("Header")
+	if err != nil {
+		return nil, err
+	}
+
+	var hostsHeaders []webserver.HostHeaders
+
+	for _, aHost := range m.getApacheHostsWithServerName(serverName) {
+		hostMatches, err := m.parser.FindDirective("Header", "", aHost.AugPath, false)
+		if err != nil {
+			return nil, err
+		}
+
+		directives, err := m.groupDirectiveArgs(append(append([]string{}, globalMatches...), hostMatches...))
+		if err != nil {
+			return nil, err
+		}
+
+		hostHeaders := webserver.HostHeaders{Host: aHost.Host}
+
+		for _, args := range directives {
+			if header, ok := parseHeaderDirective(args); ok {
+				hostHeaders.Headers = append(hostHeaders.Headers, header)
+			}
+		}
+
+		hostsHeaders = append(hostsHeaders, hostHeaders)
+	}
+
+	return hostsHeaders, nil
+}
+
+// SetHeaders sets response headers for hosts with the server name. Headers are sent for all response codes.
+func (m *ApacheManager) SetHeaders(serverName string, hostHeaders []headers.Header) error {
+	if !m.parser.ModuleExists("headers_module") {
+		return m.enableModule("headers", false)
+	}
+
+	aHosts := m.getApacheHostsWithServerName(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, header := range hostHeaders {
+		if err := header.Validate(); err != nil {
+			return err
+		}
+
+		for _, aHost := range aHosts {
+			if headers.IsSecureOnly(header.Name) && !aHost.Ssl {
+				continue
+			}
+
+			m.removeHostHeader(aHost.AugPath, "Header", header.Name)
+			args := []string{"always", "set", header.Name, headers.Quote(header.Value)}
+
+			if err := m.parser.AddDirective(aHost.AugPath, "Header", args); err != nil {
+				return fmt.Errorf("could not add 'Header' directive to host %s: %v", aHost.ServerName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnsetHeaders removes response headers from hosts with the server name.
+// Returns an error if the header is set in the main server configuration.
+func (m *ApacheManager) UnsetHeaders(serverName string, names []string) error {
+	aHosts := m.getApacheHostsWithServerName(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	globalMatches, err := m.getGlobalDirectiveMatches("Header")
+	if err != nil {
+		return err
+	}
+
+	globalDirectives, err := m.groupDirectiveArgs(globalMatches)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		for _, args := range globalDirectives {
+			if header, ok := parseHeaderDirective(args); ok && strings.EqualFold(header.Name, name) {
+				return fmt.Errorf("header %s is set in the main server configuration", name)
+			}
+		}
+
+		for _, aHost := range aHosts {
+			m.removeHostHeader(aHost.AugPath, "Header", name)
+		}
+	}
+
+	return nil
+}
+
+func (m *ApacheManager) getApacheHostsWithServerName(serverName string) []apacheHost {
+	var aHosts []apacheHost
+
+	for _, aHost := range m.getApacheHosts() {
+		if !aHost.ModMacro && (serverName == "" || aHost.ServerName == serverName) {
+			aHosts = append(aHosts, aHost)
+		}
+	}
+
+	return aHosts
+}
+
+// removeHostHeader removes header directives (Header, RequestHeader) of the header defined directly in the host
+func (m *ApacheManager) removeHostHeader(hostPath, directive, name string) {
+	m.parser.Augeas.Remove(fmt.Sprintf(
+		"%s/directive[self::directive=~regexp('%s', 'i')][arg=~regexp('%s', 'i')]",
+		hostPath,
+		directive,
+		regexp.QuoteMeta(name),
+	))
+}
+
+// parseHeaderDirective parses Header directive arguments: [condition] action header [value]
+func parseHeaderDirective(args []string) (headers.Header, bool) {
+	if len(args) > 0 && slices.Contains([]string{"always", "onsuccess"}, strings.ToLower(args[0])) {
+		args = args[1:]
+	}
+
+	if len(args) < 3 || !slices.Contains(headerSetActions, strings.ToLower(args[0])) {
+		return headers.Header{}, false
+	}
+
+	return headers.Header{Name: args[1], Value: headers.Unquote(args[2])}, true
+}
@@ -0,0 +1,188 @@
+package apache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+)
+
+const backupFileExt = ".back"
+
+var argPathRegex = regexp.MustCompile(`/arg(\[\d+\])?$`)
+
+// Lint analyses parsed apache configuration and returns found problems
+func (m *ApacheManager) Lint() ([]webserver.Finding, error) {
+	var lintHosts []webserver.LintHost
+
+	for _, aHost := range m.getApacheHosts() {
+		if aHost.ModMacro {
+			continue
+		}
+
+		certificates, err := m.getEffectiveDirectiveArgs("SSLCertificateFile", aHost.AugPath)
+		if err != nil {
+			return nil, err
+		}
+
+		_, line := m.getAugPathPosition(aHost.AugPath)
+		lintHosts = append(lintHosts, webserver.LintHost{
+			Host:         aHost.Host,
+			Line:         line,
+			Certificates: certificates,
+		})
+	}
+
+	findings := webserver.LintHosts(lintHosts, m.options.Get(webserverOptions.Root))
+
+	includeFindings, err := m.lintIncludedBackupFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	findings = append(findings, includeFindings...)
+	webserver.SortFindings(findings)
+
+	return findings, nil
+}
+
+// lintIncludedBackupFiles checks that reverter backup files are not loaded by Include globs
+func (m *ApacheManager) lintIncludedBackupFiles() ([]webserver.Finding, error) {
+	var findings []webserver.Finding
+
+	includeMatches, err := m.parser.Augeas.Match("/files//directive[self::directive=~regexp('Include|IncludeOptional', 'i')]")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, includeMatch := range includeMatches {
+		include, err := m.parser.GetArg(includeMatch + "/arg")
+		if err != nil {
+			return nil, err
+		}
+
+		includePath := m.getAbsPath(include)
+
+		if info, err := os.Stat(includePath); err == nil && info.IsDir() {
+			includePath = filepath.Join(includePath, "*")
+		}
+
+		files, err := filepath.Glob(includePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if !strings.HasSuffix(file, backupFileExt) {
+				continue
+			}
+
+			filename, line := m.getAugPathPosition(includeMatch)
+			findings = append(findings, webserver.Finding{
+				Severity: webserver.SeverityError,
+				Code:     webserver.LintBackupFileIncluded,
+				Message:  fmt.Sprintf("backup file '%s' is loaded by 'Include %s'", file, include),
+				File:     filename,
+				Line:     line,
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// getEffectiveDirectiveArgs returns arguments of all the directive occurrences effective for the host
+func (m *ApacheManager) getEffectiveDirectiveArgs(directive, hostPath string) ([]string, error) {
+	directives, err := m.getEffectiveDirectives(directive, hostPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+
+	for _, directiveArgs := range directives {
+		args = append(args, directiveArgs...)
+	}
+
+	return args, nil
+}
+
+// getEffectiveDirectives returns arguments of each directive occurrence defined in the host
+// or in the main server configuration if the host does not define it.
+func (m *ApacheManager) getEffectiveDirectives(directive, hostPath string) ([][]string, error) {
+	matches, err := m.parser.FindDirective(directive, "", hostPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		if matches, err = m.getGlobalDirectiveMatches(directive); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.groupDirectiveArgs(matches)
+}
+
+// getGlobalDirectiveMatches returns directive matches of the main server configuration, virtual hosts are skipped
+func (m *ApacheManager) getGlobalDirectiveMatches(directive string) ([]string, error) {
+	matches, err := m.parser.FindDirective(directive, "", "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var globalMatches []string
+
+	for _, match := range matches {
+		if !strings.Contains(strings.ToLower(match), "/virtualhost") {
+			globalMatches = append(globalMatches, match)
+		}
+	}
+
+	return globalMatches, nil
+}
+
+// groupDirectiveArgs returns arguments of each directive for FindDirective matches
+func (m *ApacheManager) groupDirectiveArgs(matches []string) ([][]string, error) {
+	var directives [][]string
+	var directivePaths []string
+
+	for _, match := range matches {
+		arg, err := m.parser.GetArg(match)
+		if err != nil {
+			return nil, err
+		}
+
+		// matches are arguments of directives: .../directive[2]/arg[1]
+		directivePath := argPathRegex.ReplaceAllString(match, "")
+
+		if len(directivePaths) == 0 || directivePaths[len(directivePaths)-1] != directivePath {
+			directivePaths = append(directivePaths, directivePath)
+			directives = append(directives, nil)
+		}
+
+		directives[len(directives)-1] = append(directives[len(directives)-1], arg)
+	}
+
+	return directives, nil
+}
+
+// getAugPathPosition returns file name and line number of the augeas node
+func (m *ApacheManager) getAugPathPosition(augPath string) (string, int) {
+	span, err := m.parser.Augeas.Span(augPath)
+	if err != nil {
+		return "", 0
+	}
+
+	content, err := os.ReadFile(span.Filename)
+	if err != nil || int(span.SpanStart) > len(content) {
+		return span.Filename, 0
+	}
+
+	return span.Filename, bytes.Count(content[:span.SpanStart], []byte("\n")) + 1
+}
@@ -1,6 +1,7 @@
 package apache
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -15,24 +16,35 @@ import (
 	"github.com/r2dtools/webmng/internal/apache/parser"
 	apacheutils "github.com/r2dtools/webmng/internal/apache/utils"
 	"github.com/r2dtools/webmng/pkg/aug"
+	"github.com/r2dtools/webmng/pkg/certificate"
 	"github.com/r2dtools/webmng/pkg/logger"
 	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/r2dtools/webmng/pkg/utils"
 	"github.com/r2dtools/webmng/pkg/webserver"
 	"github.com/r2dtools/webmng/pkg/webserver/host"
 	"github.com/r2dtools/webmng/pkg/webserver/hostmanager"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
 	"github.com/r2dtools/webmng/pkg/webserver/reverter"
+	"github.com/r2dtools/webmng/pkg/webserver/staging"
 	"github.com/unknwon/com"
 	"golang.org/x/exp/slices"
 )
 
 const (
 	minApacheVersion = "2.4.0"
+	// defaultHttpsPort is the port Apache uses for https if the Listen directive has no protocol
+	defaultHttpsPort = "443"
 )
 
 var serverRootPaths = []string{"/etc/httpd", "/etc/apache2"}
 var enabledHostConfigDirNames = []string{"sites-enabled", "conf.d"}
 
+// openSSLVersionRegexp matches OpenSSL version text compiled into mod_ssl, e.g. "OpenSSL 3.0.2 15 Mar 2022"
+var openSSLVersionRegexp = regexp.MustCompile(`OpenSSL (\d+\.\d+\.\d+[a-z]*)`)
+
 type HostManager interface {
 	Enable(hostConfigPath string) error
 	Disable(hostConfigPath string) error
@@ -47,6 +59,14 @@ type ApacheManager struct {
 	apacheHosts   []apacheHost
 	reverter      reverter.Reverter
 	options       options.Options
+	runner        runner.Runner
+	portChecker   procstat.PortChecker
+	// availableDir is the directory of created ssl virtual hosts configs, empty if not specified
+	availableDir string
+	// changes contains files and symlinks changed besides the configuration, they are deferred in staged mode
+	changes *staging.Changes
+	// service is detected on the first use
+	service webserver.ServiceController
 }
 
 type apacheHost struct {
@@ -73,17 +93,94 @@ func (m *ApacheManager) GetVersion() (string, error) {
 	return m.apacheVersion, nil
 }
 
+// GetOpenSSLVersion returns OpenSSL version mod_ssl is built with. The version is taken from the module library
+// since apache does not report it. Empty version is returned if mod_ssl is not loaded by LoadModule directive
+// or its library could not be read, e.g. in offline mode or alternate root.
+func (m *ApacheManager) GetOpenSSLVersion() (string, error) {
+	modulePath, err := m.parser.GetModulePath("ssl")
+	if err != nil || modulePath == "" {
+		return "", err
+	}
+
+	content, err := os.ReadFile(modulePath)
+	if err != nil {
+		m.logger.Debug(fmt.Sprintf("could not read mod_ssl library: %v", err))
+
+		return "", nil
+	}
+
+	if matches := openSSLVersionRegexp.FindSubmatch(content); matches != nil {
+		return string(matches[1]), nil
+	}
+
+	return "", nil
+}
+
+// Lock acquires the instance lock and loads the configuration again, hosts are detected on the next use
+func (m *ApacheManager) Lock(mode lock.Mode) (*lock.Lock, error) {
+	instanceLock, err := lock.AcquireInstance(m.options, webserver.Apache, m.parser.ServerRoot, m.parser.ConfigRoot, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.parser.Augeas.Load(); err != nil {
+		instanceLock.Release()
+
+		return nil, err
+	}
+
+	m.apacheHosts = nil
+
+	return instanceLock, nil
+}
+
 // Commit applies all current changes
 func (m *ApacheManager) CommitChanges() error {
 	return m.reverter.Commit()
 }
 
 func (m *ApacheManager) RollbackChanges() error {
+	m.changes.Clear()
+
 	return m.reverter.Rollback()
 }
 
+// GetReverter returns the reverter used to roll back changes made outside of the configuration, e.g. certificate symlinks
+func (m *ApacheManager) GetReverter() reverter.Reverter {
+	return m.reverter
+}
+
 func (m *ApacheManager) SaveChanges() error {
-	return m.parser.Save(m.reverter)
+	if !webserverOptions.IsStaged(m.options) {
+		return m.parser.Save(m.reverter)
+	}
+
+	includeDirectives := []string{"Include", "IncludeOptional", "ServerRoot"}
+	err := m.parser.SaveStaged(m.reverter, func(files map[string][]byte) error {
+		return staging.Apply(m.parser.ServerRoot, files, m.changes, includeDirectives, m.testStagedConfiguration)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// deferred changes are left if the configuration is not changed, e.g. only the certificate store symlinks are switched
+	return staging.Apply(m.parser.ServerRoot, nil, m.changes, includeDirectives, m.testStagedConfiguration)
+}
+
+// GetChanges returns files and symlinks changed besides the configuration
+func (m *ApacheManager) GetChanges() *staging.Changes {
+	return m.changes
+}
+
+// testStagedConfiguration tests the staged copy of the configuration, relative paths are resolved against the staged server root
+func (m *ApacheManager) testStagedConfiguration(stage *staging.Stage) error {
+	configFile, err := stage.GetPath(m.parser.ConfigRoot)
+	if err != nil {
+		return err
+	}
+
+	return m.apachectl.TestConfigurationAt(stage.Dir, configFile)
 }
 
 // CheckConfiguration checks if apache configuration is correct
@@ -91,11 +188,6 @@ func (m *ApacheManager) CheckConfiguration() error {
 	return m.apachectl.TestConfiguration()
 }
 
-// RestartWebServer restarts apache web server
-func (m *ApacheManager) Restart() error {
-	return m.apachectl.Restart()
-}
-
 func (m *ApacheManager) EnableHost(host *webserver.Host) error {
 	if host.Enabled {
 		m.logger.Debug(fmt.Sprintf("host '%s' is already enabled. Skip site enabling.", host.FilePath))
@@ -114,20 +206,54 @@ func (m *ApacheManager) EnableHost(host *webserver.Host) error {
 }
 
 func (m *ApacheManager) DeployCertificate(serverName, certPath, certKeyPath, chainPath, fullChainPath string) error {
-	if certPath != "" {
-		certPath = filepath.Clean(certPath)
+	return m.DeployCertificates(serverName, []webserver.Certificate{
+		{
+			CertPath:      certPath,
+			KeyPath:       certKeyPath,
+			ChainPath:     chainPath,
+			FullChainPath: fullChainPath,
+		},
+	})
+}
+
+// DeployCertificates deploys certificates with different key types to hosts with the server name.
+// Host certificates with the same key types as deployed ones are replaced, others are left untouched.
+func (m *ApacheManager) DeployCertificates(serverName string, certificates []webserver.Certificate) error {
+	var cleanCertificates []webserver.Certificate
+
+	for _, cert := range certificates {
+		cleanCertificates = append(cleanCertificates, webserver.Certificate{
+			CertPath:      cleanPath(cert.CertPath),
+			KeyPath:       cleanPath(cert.KeyPath),
+			ChainPath:     cleanPath(cert.ChainPath),
+			FullChainPath: cleanPath(cert.FullChainPath),
+		})
 	}
 
-	if certKeyPath != "" {
-		certKeyPath = filepath.Clean(certKeyPath)
+	keyTypes, err := webserver.GetCertificatesKeyTypes(cleanCertificates)
+	if err != nil {
+		return err
 	}
 
-	if chainPath != "" {
-		chainPath = filepath.Clean(chainPath)
+	var certFiles []webserver.CertificateFiles
+	var chainPaths []string
+
+	for _, cert := range cleanCertificates {
+		certFile, certChainPath, err := m.getCertificateFiles(cert)
+		if err != nil {
+			return err
+		}
+
+		if certChainPath != "" {
+			chainPaths = append(chainPaths, certChainPath)
+		}
+
+		certFiles = append(certFiles, webserver.CertificateFiles{CertPath: certFile, KeyPath: cert.KeyPath})
 	}
 
-	if fullChainPath != "" {
-		fullChainPath = filepath.Clean(fullChainPath)
+	// chains of the deployed certificates are checked before any host is changed
+	if _, err = m.getSharedChainPath(chainPaths); err != nil {
+		return err
 	}
 
 	aHosts, err := m.getApacheHostsByServerNameWithRequiredSSLConfigPart(serverName)
@@ -140,64 +266,60 @@ func (m *ApacheManager) DeployCertificate(serverName, certPath, certKeyPath, cha
 		return fmt.Errorf("could not find suitable hosts with serverName: %s", serverName)
 	}
 
-	if err = m.prepareServerForHTTPS("443", false); err != nil {
+	if err = m.prepareServerForHTTPS(m.getHttpsPort(), false); err != nil {
 		return err
 	}
 
 	for _, aHost := range aHosts {
-		if err = m.cleanSSLApacheHost(aHost); err != nil {
+		hostCertFiles, err := m.getHostCertificateFilesToKeep(aHost, keyTypes)
+		if err != nil {
 			return err
 		}
 
-		if err = m.addDummySSLDirectives(aHost.AugPath); err != nil {
-			return err
-		}
+		hostChainPaths := chainPaths
 
-		augCertPath, err := m.parser.FindDirective("SSLCertificateFile", "", aHost.AugPath, true)
+		// kept certificates could rely on the chain file of the host
+		if len(hostCertFiles) > 0 {
+			hostChainPath, err := m.getHostDirectiveArg(aHost, "SSLCertificateChainFile")
+			if err != nil {
+				return err
+			}
 
-		if err != nil {
-			return fmt.Errorf("error while searching directive 'SSLCertificateFile': %v", err)
+			if hostChainPath != "" {
+				hostChainPaths = append([]string{hostChainPath}, chainPaths...)
+			}
 		}
 
-		augCertKeyPath, err := m.parser.FindDirective("SSLCertificateKeyFile", "", aHost.AugPath, true)
-
+		chainPath, err := m.getSharedChainPath(hostChainPaths)
 		if err != nil {
-			return fmt.Errorf("error while searching directive 'SSLCertificateKeyFile': %v", err)
+			return fmt.Errorf("could not deploy certificates to vhost '%s': %v", serverName, err)
 		}
 
-		res, err := utils.CheckMinVersion(m.apacheVersion, "2.4.8")
-
-		if err != nil {
+		if err = m.cleanSSLApacheHost(aHost); err != nil {
 			return err
 		}
 
-		if !res || (chainPath != "" && fullChainPath == "") {
-			if err = m.parser.Augeas.Set(augCertPath[len(augCertPath)-1], certPath); err != nil {
-				return fmt.Errorf("could not set certificate path for vhost '%s': %v", serverName, err)
-			}
+		if err := m.parser.AddDirective(aHost.AugPath, "SSLEngine", []string{"on"}); err != nil {
+			return fmt.Errorf("could not add 'SSLEngine' directive to vhost '%s': %v", serverName, err)
+		}
 
-			if err = m.parser.Augeas.Set(augCertKeyPath[len(augCertKeyPath)-1], certKeyPath); err != nil {
-				return fmt.Errorf("could not set certificate key path for vhost '%s': %v", serverName, err)
+		for _, files := range append(hostCertFiles, certFiles...) {
+			if err = m.parser.AddDirective(aHost.AugPath, "SSLCertificateFile", []string{files.CertPath}); err != nil {
+				return fmt.Errorf("could not set certificate path for vhost '%s': %v", serverName, err)
 			}
 
-			if chainPath != "" {
-				if err = m.parser.AddDirective(aHost.AugPath, "SSLCertificateChainFile", []string{chainPath}); err != nil {
-					return fmt.Errorf("could not add 'SSLCertificateChainFile' directive to vhost '%s': %v", serverName, err)
-				}
-			} else {
-				return fmt.Errorf("SSL certificate chain path is required for the current Apache version '%s', but is not specified", m.apacheVersion)
-			}
-		} else {
-			if fullChainPath == "" {
-				return errors.New("SSL certificate fullchain path is required, but is not specified")
+			if files.KeyPath == "" {
+				continue
 			}
 
-			if err = m.parser.Augeas.Set(augCertPath[len(augCertPath)-1], fullChainPath); err != nil {
-				return fmt.Errorf("could not set certificate path for vhost '%s': %v", serverName, err)
+			if err = m.parser.AddDirective(aHost.AugPath, "SSLCertificateKeyFile", []string{files.KeyPath}); err != nil {
+				return fmt.Errorf("could not set certificate key path for vhost '%s': %v", serverName, err)
 			}
+		}
 
-			if err = m.parser.Augeas.Set(augCertKeyPath[len(augCertKeyPath)-1], certKeyPath); err != nil {
-				return fmt.Errorf("could not set certificate key path for vhost '%s': %v", serverName, err)
+		if chainPath != "" {
+			if err = m.parser.AddDirective(aHost.AugPath, "SSLCertificateChainFile", []string{chainPath}); err != nil {
+				return fmt.Errorf("could not add 'SSLCertificateChainFile' directive to vhost '%s': %v", serverName, err)
 			}
 		}
 
@@ -211,6 +333,116 @@ func (m *ApacheManager) DeployCertificate(serverName, certPath, certKeyPath, cha
 	return nil
 }
 
+// getCertificateFiles returns values of SSLCertificateFile and SSLCertificateChainFile directives for the certificate.
+// Full chain certificate file is supported since apache 2.4.8.
+func (m *ApacheManager) getCertificateFiles(cert webserver.Certificate) (certPath, chainPath string, err error) {
+	res, err := utils.CheckMinVersion(m.apacheVersion, "2.4.8")
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if !res || (cert.ChainPath != "" && cert.FullChainPath == "") {
+		if cert.ChainPath == "" {
+			return "", "", fmt.Errorf("SSL certificate chain path is required for the current Apache version '%s', but is not specified", m.apacheVersion)
+		}
+
+		return cert.CertPath, cert.ChainPath, nil
+	}
+
+	if cert.FullChainPath == "" {
+		return "", "", errors.New("SSL certificate fullchain path is required, but is not specified")
+	}
+
+	return cert.FullChainPath, "", nil
+}
+
+// getSharedChainPath returns the chain file of the host certificates. SSLCertificateChainFile is shared by all certificates of the host,
+// so certificates issued by different intermediates must be deployed with full chains.
+func (m *ApacheManager) getSharedChainPath(chainPaths []string) (string, error) {
+	if len(chainPaths) == 0 {
+		return "", nil
+	}
+
+	chain, err := os.ReadFile(m.getAbsPath(chainPaths[0]))
+	if err != nil {
+		return "", fmt.Errorf("could not read certificate chain %s: %v", chainPaths[0], err)
+	}
+
+	for _, chainPath := range chainPaths[1:] {
+		if chainPath == chainPaths[0] {
+			continue
+		}
+
+		content, err := os.ReadFile(m.getAbsPath(chainPath))
+		if err != nil {
+			return "", fmt.Errorf("could not read certificate chain %s: %v", chainPath, err)
+		}
+
+		if !bytes.Equal(bytes.TrimSpace(content), bytes.TrimSpace(chain)) {
+			return "", fmt.Errorf("certificate chains %s and %s differ, but only one chain file could be used by the host: specify full chain certificates (apache 2.4.8 or later)", chainPaths[0], chainPath)
+		}
+	}
+
+	return chainPaths[0], nil
+}
+
+// getHostDirectiveArg returns the first argument of the host directive. Empty string is returned if the directive does not exist.
+func (m *ApacheManager) getHostDirectiveArg(aHost apacheHost, directive string) (string, error) {
+	matches, err := m.parser.FindDirective(directive, "", aHost.AugPath, false)
+	if err != nil {
+		return "", fmt.Errorf("error while searching directive '%s': %v", directive, err)
+	}
+
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	return m.parser.GetArg(matches[0])
+}
+
+// getHostCertificateFilesToKeep returns certificate and key pairs of the host which key types differ from the deployed ones.
+// Pairs with unreadable certificates are not kept.
+func (m *ApacheManager) getHostCertificateFilesToKeep(aHost apacheHost, keyTypes []string) ([]webserver.CertificateFiles, error) {
+	certMatches, err := m.parser.FindDirective("SSLCertificateFile", "", aHost.AugPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("error while searching directive 'SSLCertificateFile': %v", err)
+	}
+
+	keyMatches, err := m.parser.FindDirective("SSLCertificateKeyFile", "", aHost.AugPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("error while searching directive 'SSLCertificateKeyFile': %v", err)
+	}
+
+	var filesToKeep []webserver.CertificateFiles
+
+	for i, certMatch := range certMatches {
+		var files webserver.CertificateFiles
+
+		if files.CertPath, err = m.parser.GetArg(certMatch); err != nil {
+			return nil, err
+		}
+
+		if i < len(keyMatches) {
+			if files.KeyPath, err = m.parser.GetArg(keyMatches[i]); err != nil {
+				return nil, err
+			}
+		} else {
+			// the key is stored in the certificate file
+			files.KeyPath = files.CertPath
+		}
+
+		keyType, err := certificate.LoadKeyType(m.getAbsPath(files.CertPath))
+		if err != nil || slices.Contains(keyTypes, keyType) {
+			continue
+		}
+
+		filesToKeep = append(filesToKeep, files)
+	}
+
+	return filesToKeep, nil
+}
+
 func (m *ApacheManager) GetHostsByServerName(serverName string) ([]webserver.Host, error) {
 	aHosts, err := m.getApacheHostsByServerName(serverName)
 
@@ -327,7 +559,9 @@ func (m *ApacheManager) makeSslHosts(hosts []apacheHost) ([]apacheHost, error) {
 			}
 		}
 
-		m.updateSslHostAddresses(sslHostPath)
+		if _, err = m.updateSslHostAddresses(sslHostPath); err != nil {
+			return nil, fmt.Errorf("could not update ssl host addresses: %v", err)
+		}
 
 		if err := m.Save(); err != nil {
 			return nil, err
@@ -442,6 +676,10 @@ func (m *ApacheManager) getSslHostFilePath(noSslHostFilePath string) (string, er
 
 	hostRoot := m.options.Get(apacheoptions.HostRoot)
 
+	if hostRoot == "" {
+		hostRoot = m.availableDir
+	}
+
 	if hostRoot != "" {
 		_, err = os.Stat(hostRoot)
 
@@ -487,7 +725,7 @@ func (m *ApacheManager) updateSslHostAddresses(sslVhostPath string) ([]host.Addr
 		}
 
 		oldAddress := host.CreateHostAddressFromString(addrString)
-		sslAddress := oldAddress.GetAddressWithNewPort("443") // TODO: it should be passed in an external code
+		sslAddress := oldAddress.GetAddressWithNewPort(m.getHttpsPort())
 		err = m.parser.Augeas.Set(sslAddrMatch, sslAddress.ToString())
 
 		if err != nil {
@@ -652,7 +890,7 @@ func (m *ApacheManager) createApacheHost(path string) (apacheHost, error) {
 	}
 
 	for _, addr := range addrs {
-		if addr.Port == "443" {
+		if m.isHttpsPort(addr.Port) {
 			ssl = true
 			break
 		}
@@ -826,22 +1064,6 @@ func (m *ApacheManager) prepareHTTPSModules(temp bool) error {
 	return nil
 }
 
-func (m *ApacheManager) addDummySSLDirectives(hPath string) error {
-	if err := m.parser.AddDirective(hPath, "SSLEngine", []string{"on"}); err != nil {
-		return fmt.Errorf("could not add 'SSLEngine' directive to host %s: %v", hPath, err)
-	}
-
-	if err := m.parser.AddDirective(hPath, "SSLCertificateFile", []string{"insert_cert_file_path"}); err != nil {
-		return fmt.Errorf("could not add 'SSLCertificateFile' directive to host %s: %v", hPath, err)
-	}
-
-	if err := m.parser.AddDirective(hPath, "SSLCertificateKeyFile", []string{"insert_key_file_path"}); err != nil {
-		return fmt.Errorf("could not add 'SSLCertificateKeyFile' directive to host %s: %v", hPath, err)
-	}
-
-	return nil
-}
-
 func (m *ApacheManager) cleanSSLApacheHost(aHost apacheHost) error {
 	if err := m.removeDirectives(aHost.AugPath, []string{"SSLEngine", "SSLCertificateFile", "SSLCertificateKeyFile", "SSLCertificateChainFile"}); err != nil {
 		return err
@@ -871,18 +1093,9 @@ func (m *ApacheManager) removeDirectives(hPath string, directives []string) erro
 // EnsurePortIsListening ensures that the provided port is listening
 // The port will be added to config file it is not listened
 func (m *ApacheManager) ensurePortIsListening(port string, https bool) error {
-	var portService string
 	var listens []string
 	var listenDirs []string
-
-	if https && port != "443" {
-		// https://httpd.apache.org/docs/2.4/bind.html
-		// Listen 192.170.2.1:8443 https
-		// running an https site on port 8443 (if protocol is not specified than 443 is used by default for https)
-		portService = fmt.Sprintf("%s %s", port, "https")
-	} else {
-		portService = port
-	}
+	portService := getListenPortService(port, https)
 
 	listenMatches, err := m.parser.FindDirective("Listen", "", "", true)
 
@@ -925,6 +1138,13 @@ func (m *ApacheManager) ensurePortIsListening(port string, https bool) error {
 		}
 	}
 
+	// the port could be bound by another process, e.g. nginx in front of apache
+	for _, listen := range utils.StrSlicesDifference(listenDirs, listens) {
+		if err := m.portChecker.Check(apacheutils.GetIPFromListen(listen), port); err != nil {
+			return err
+		}
+	}
+
 	if https {
 		return m.addListensForHTTPS(listenDirs, listens, port)
 	}
@@ -952,22 +1172,16 @@ func (m *ApacheManager) addListensForHTTP(listens []string, listensOrigin []stri
 }
 
 func (m *ApacheManager) addListensForHTTPS(listens []string, listensOrigin []string, port string) error {
-	var portService string
 	augListenPath := aug.GetAugPath(m.parser.ConfigListen)
 	newListens := utils.StrSlicesDifference(listens, listensOrigin)
-
-	if port != "443" {
-		portService = fmt.Sprintf("%s %s", port, "https")
-	} else {
-		portService = port
-	}
+	portService := getListenPortService(port, true)
 
 	if com.IsSliceContainsStr(newListens, port) || com.IsSliceContainsStr(newListens, portService) {
 		if err := m.parser.AddDirectiveToIfModSSL(augListenPath, "Listen", strings.Split(portService, " ")); err != nil {
 			return fmt.Errorf("could not add port %s to listen config: %v", port, err)
 		}
 	} else {
-		for _, listen := range listens {
+		for _, listen := range newListens {
 			if err := m.parser.AddDirectiveToIfModSSL(augListenPath, "Listen", strings.Split(listen, " ")); err != nil {
 				return fmt.Errorf("could not add port %s to listen config: %v", port, err)
 			}
@@ -977,6 +1191,28 @@ func (m *ApacheManager) addListensForHTTPS(listens []string, listensOrigin []str
 	return nil
 }
 
+// getHttpsPort returns the port ssl hosts are created on
+func (m *ApacheManager) getHttpsPort() string {
+	return m.options.Get(webserverOptions.HttpsPort)
+}
+
+// isHttpsPort checks if the host address port implies https: the default https port or the configured one
+func (m *ApacheManager) isHttpsPort(port string) bool {
+	return port == defaultHttpsPort || port == m.getHttpsPort()
+}
+
+// getListenPortService returns Listen directive arguments for the port.
+// https://httpd.apache.org/docs/2.4/bind.html
+// Listen 192.170.2.1:8443 https
+// running an https site on port 8443 (if protocol is not specified than 443 is used by default for https)
+func getListenPortService(port string, https bool) string {
+	if https && port != defaultHttpsPort {
+		return fmt.Sprintf("%s %s", port, "https")
+	}
+
+	return port
+}
+
 func (m *ApacheManager) enableModule(module string, temp bool) error {
 	return fmt.Errorf("apache needs to have module %s active. please install the module manually", module)
 }
@@ -984,11 +1220,16 @@ func (m *ApacheManager) enableModule(module string, temp bool) error {
 func GetApacheManager(params map[string]string, logger logger.LoggerInterface) (*ApacheManager, error) {
 	options := apacheoptions.GetOptions(params)
 
-	aCtl, err := apachectl.GetApacheCtl()
+	return getApacheManager(options, runner.GetExecRunner(webserverOptions.GetCmdTimeout(options)), logger)
+}
+
+func getApacheManager(options options.Options, cmdRunner runner.Runner, logger logger.LoggerInterface) (*ApacheManager, error) {
+	aCtl, err := getApacheCtl(options, cmdRunner)
 	if err != nil {
 		return nil, err
 	}
 
+	root := options.Get(webserverOptions.Root)
 	serverRootDirectory, err := getServerRootDirectory(options)
 	if err != nil {
 		return nil, err
@@ -997,15 +1238,17 @@ func GetApacheManager(params map[string]string, logger logger.LoggerInterface) (
 	parser, err := parser.GetParser(
 		aCtl,
 		"Httpd",
+		root,
 		serverRootDirectory,
-		options.Get(apacheoptions.HostRoot),
+		webserverOptions.GetRootedPath(root, options.Get(webserverOptions.ServerConfig)),
+		webserverOptions.GetRootedPath(root, options.Get(apacheoptions.HostRoot)),
 		options.Get(apacheoptions.HostFiles),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	version, err := aCtl.GetVersion()
+	version, err := getApacheVersion(aCtl, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -1019,17 +1262,27 @@ func GetApacheManager(params map[string]string, logger logger.LoggerInterface) (
 		return nil, fmt.Errorf("current apache version '%s' is not supported. Minimal supported version is '%s'", version, minApacheVersion)
 	}
 
-	// Test apache configuration before creating manager
-	if err = aCtl.TestConfiguration(); err != nil {
-		return nil, err
+	// Test apache configuration before creating manager. Configuration check command may be unavailable in offline mode until changes are made.
+	if !aCtl.IsOffline() {
+		if err = aCtl.TestConfiguration(); err != nil {
+			return nil, err
+		}
 	}
 
-	enabledHostConfigDirectory, err := getEnabledHostConfigDirectory(serverRootDirectory)
+	enabledDir := webserverOptions.GetRootedPath(root, options.Get(webserverOptions.EnabledDir))
+	enabledHostConfigDirectory, err := getEnabledHostConfigDirectory(serverRootDirectory, enabledDir)
 	if err != nil {
 		return nil, err
 	}
 
-	hostManager := getHostManager(parser, enabledHostConfigDirectory)
+	availableDir := webserverOptions.GetRootedPath(root, options.Get(webserverOptions.AvailableDir))
+	if availableDir != "" && !com.IsDir(availableDir) {
+		return nil, fmt.Errorf("available hosts configuration directory '%s' does not exist", availableDir)
+	}
+
+	// a2ensite manages the system apache installation only
+	useApacheSite := !aCtl.IsOffline() && root == ""
+	hostManager := getHostManager(parser, enabledHostConfigDirectory, cmdRunner, useApacheSite)
 	manager := ApacheManager{
 		apachectl:     aCtl,
 		hostManager:   hostManager,
@@ -1038,13 +1291,52 @@ func GetApacheManager(params map[string]string, logger logger.LoggerInterface) (
 		apacheVersion: version,
 		options:       options,
 		reverter:      reverter.GetConfigReveter(hostManager, logger),
+		runner:        cmdRunner,
+		availableDir:  availableDir,
+		changes:       staging.GetChanges(webserverOptions.IsStaged(options)),
 	}
+	manager.portChecker = procstat.GetPortChecker(procstat.GetPortCheckMode(options), &manager, logger)
 
 	return &manager, nil
 }
 
+// getApacheVersion returns apache version. Minimal supported version is used if the version is not specified in offline mode.
+func getApacheVersion(aCtl apachectl.ApacheCtl, logger logger.LoggerInterface) (string, error) {
+	version, err := aCtl.GetVersion()
+
+	if err != nil && aCtl.IsOffline() {
+		logger.Debug("%v, minimal supported version %s is used", err, minApacheVersion)
+
+		return minApacheVersion, nil
+	}
+
+	return version, err
+}
+
+// GetInstancePaths returns server root and main config file of the apache instance defined by options, as they are detected by the manager
+func GetInstancePaths(params map[string]string) (string, string, error) {
+	return getInstancePaths(apacheoptions.GetOptions(params))
+}
+
+func getInstancePaths(options options.Options) (string, string, error) {
+	serverRoot, err := getServerRootDirectory(options)
+	if err != nil {
+		return "", "", err
+	}
+
+	root := options.Get(webserverOptions.Root)
+	configRoot, err := parser.GetConfigRoot(serverRoot, webserverOptions.GetRootedPath(root, options.Get(webserverOptions.ServerConfig)))
+	if err != nil {
+		return "", "", err
+	}
+
+	return serverRoot, configRoot, nil
+}
+
+// getServerRootDirectory returns apache root directory resolved against the alternate root
 func getServerRootDirectory(options options.Options) (string, error) {
-	serverRoot := options.Get(apacheoptions.ServerRoot)
+	root := options.Get(webserverOptions.Root)
+	serverRoot := webserverOptions.GetRootedPath(root, options.Get(apacheoptions.ServerRoot))
 
 	if serverRoot != "" {
 		if !com.IsDir(serverRoot) {
@@ -1054,7 +1346,13 @@ func getServerRootDirectory(options options.Options) (string, error) {
 		return serverRoot, nil
 	}
 
-	serverRoot, err := utils.FindFirstExistedDirectory(serverRootPaths)
+	var rootedServerRootPaths []string
+
+	for _, serverRootPath := range serverRootPaths {
+		rootedServerRootPaths = append(rootedServerRootPaths, webserverOptions.GetRootedPath(root, serverRootPath))
+	}
+
+	serverRoot, err := utils.FindFirstExistedDirectory(rootedServerRootPaths)
 	if err != nil {
 		return "", fmt.Errorf("unable to find find server root directory: %v", err)
 	}
@@ -1062,7 +1360,43 @@ func getServerRootDirectory(options options.Options) (string, error) {
 	return serverRoot, nil
 }
 
-func getEnabledHostConfigDirectory(serverRootDir string) (string, error) {
+// GetApacheCtl returns apachectl of the apache instance defined by options. Offline apachectl is returned in offline mode.
+func GetApacheCtl(params map[string]string) (apachectl.ApacheCtl, error) {
+	options := apacheoptions.GetOptions(params)
+
+	return getApacheCtl(options, runner.GetExecRunner(webserverOptions.GetCmdTimeout(options)))
+}
+
+func getApacheCtl(options options.Options, cmdRunner runner.Runner) (apachectl.ApacheCtl, error) {
+	if webserverOptions.IsOffline(options) {
+		return apachectl.GetOfflineApacheCtl(
+			options.Get(webserverOptions.ServerVersion),
+			webserverOptions.GetList(options.Get(webserverOptions.Modules)),
+			webserverOptions.GetList(options.Get(apacheoptions.Defines)),
+			options.Get(webserverOptions.CheckCmd),
+			options.Get(webserverOptions.ReloadCmd),
+			cmdRunner,
+		), nil
+	}
+
+	return apachectl.GetApacheCtl(
+		options.Get(apacheoptions.ApacheCtl),
+		options.Get(apacheoptions.ServerRoot),
+		options.Get(webserverOptions.ServerConfig),
+		options.Get(webserverOptions.ReloadCmd),
+		cmdRunner,
+	)
+}
+
+func getEnabledHostConfigDirectory(serverRootDir, enabledDir string) (string, error) {
+	if enabledDir != "" {
+		if !com.IsDir(enabledDir) {
+			return "", fmt.Errorf("enabled hosts configuration directory '%s' does not exist", enabledDir)
+		}
+
+		return enabledDir, nil
+	}
+
 	for _, dirName := range enabledHostConfigDirNames {
 		enabledHostDir := filepath.Join(serverRootDir, dirName)
 		if com.IsDir(enabledHostDir) {
@@ -1073,10 +1407,11 @@ func getEnabledHostConfigDirectory(serverRootDir string) (string, error) {
 	return "", errors.New("unable to find enabled hosts configuration directory")
 }
 
-func getHostManager(parser *parser.Parser, enabledHostConfigDirectory string) HostManager {
-	aSite, err := apachesite.GetApacheSite()
-	if err == nil {
-		return aSite
+func getHostManager(parser *parser.Parser, enabledHostConfigDirectory string, cmdRunner runner.Runner, useApacheSite bool) HostManager {
+	if useApacheSite {
+		if aSite, err := apachesite.GetApacheSite(cmdRunner); err == nil {
+			return aSite
+		}
 	}
 
 	defaultHostManager, err := hostmanager.GetHostManager(enabledHostConfigDirectory)
@@ -1086,3 +1421,11 @@ func getHostManager(parser *parser.Parser, enabledHostConfigDirectory string) Ho
 
 	return apachehostmanager.GetHostManager(parser)
 }
+
+func cleanPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	return filepath.Clean(path)
+}
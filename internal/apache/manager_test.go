@@ -1,6 +1,7 @@
 package apache
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,10 +9,16 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	apacheoptions "github.com/r2dtools/webmng/internal/apache/options"
+	"github.com/r2dtools/webmng/pkg/certificate"
 	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/r2dtools/webmng/pkg/utils"
+	"github.com/r2dtools/webmng/pkg/webserver"
 	"github.com/r2dtools/webmng/pkg/webserver/host"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
 	"github.com/stretchr/testify/assert"
 	"github.com/unknwon/com"
 )
@@ -174,7 +181,163 @@ func TestDeployCertificate(t *testing.T) {
 	}
 }
 
+func TestApacheManagerWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	writeConfigFile(t, filepath.Join(serverRoot, "apache2.conf"), "LoadModule ssl_module modules/mod_ssl.so\nInclude ports.conf\nIncludeOptional sites-enabled/*.conf\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "ports.conf"), "Listen 80\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com.conf"), "<VirtualHost *:80>\n    ServerName example.com\n    DocumentRoot /var/www/html\n</VirtualHost>\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "modules", "mod_ssl.so"), "\x7fELF\x00mod_ssl/2.4.57\x00OpenSSL 1.1.1n  15 Mar 2022\x00")
+
+	ctl := "/usr/sbin/apache2ctl -d " + serverRoot
+	includes := fmt.Sprintf("Included configuration files:\n  (*) %[1]s/apache2.conf\n    (1) %[1]s/ports.conf\n    (2) %[1]s/sites-enabled/example.com.conf\n", serverRoot)
+	cmdRunner := runner.GetFakeRunner().
+		On(ctl+" -v", runner.FakeResponse{Stdout: "Server version: Apache/2.4.57 (Debian)\n"}).
+		On(ctl+" -t -D DUMP_RUN_CFG", runner.FakeResponse{Stdout: "Define: DUMP_RUN_CFG\n"}).
+		On(ctl+" -t -D DUMP_INCLUDES", runner.FakeResponse{Stdout: includes}).
+		On(ctl+" -t -D DUMP_MODULES", runner.FakeResponse{Stdout: "Loaded Modules:\n core_module (static)\n ssl_module (shared)\n"}).
+		On(ctl+" -t", runner.FakeResponse{Stderr: "Syntax OK"}).
+		On(ctl+" -k restart", runner.FakeResponse{})
+
+	options := apacheoptions.GetOptions(map[string]string{
+		apacheoptions.ServerRoot: serverRoot,
+		apacheoptions.ApacheCtl:  "/usr/sbin/apache2ctl",
+	})
+	webServerManager, err := getApacheManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create apache webserver manager: %v", err)
+	defer webServerManager.parser.Close()
+
+	hosts, err := webServerManager.GetHostsByServerName("example.com")
+	assert.Nilf(t, err, "could not get hosts: %v", err)
+	assert.Equal(t, 1, len(hosts))
+	assert.Equal(t, "/var/www/html", hosts[0].DocRoot)
+
+	version, err := webServerManager.GetVersion()
+	assert.Nilf(t, err, "could not get apache version: %v", err)
+	assert.Equal(t, "2.4.57", version)
+	assert.True(t, webServerManager.parser.ModuleExists("ssl_module"))
+
+	openSSLVersion, err := webServerManager.GetOpenSSLVersion()
+	assert.Nilf(t, err, "could not get OpenSSL version: %v", err)
+	assert.Equal(t, "1.1.1n", openSSLVersion)
+
+	err = os.Remove(filepath.Join(serverRoot, "modules", "mod_ssl.so"))
+	assert.Nilf(t, err, "could not remove mod_ssl library: %v", err)
+	openSSLVersion, err = webServerManager.GetOpenSSLVersion()
+	assert.Nilf(t, err, "unreadable mod_ssl library must not be an error: %v", err)
+	assert.Empty(t, openSSLVersion)
+
+	assert.Nil(t, webServerManager.CheckConfiguration())
+	assert.Nil(t, webServerManager.Restart())
+	assert.Equal(t, ctl+" -k restart", cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-1])
+
+	cmdRunner.On(ctl+" -t", runner.FakeResponse{Stderr: "AH00526: Syntax error", ExitCode: 1})
+	err = webServerManager.CheckConfiguration()
+	assert.NotNil(t, err, "invalid configuration must be reported")
+	assert.Contains(t, err.Error(), "AH00526")
+}
+
+func TestApacheTLSProfileWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	hostPath := filepath.Join(serverRoot, "sites-enabled", "example.com-ssl.conf")
+	writeConfigFile(t, filepath.Join(serverRoot, "apache2.conf"), "LoadModule ssl_module modules/mod_ssl.so\nInclude ports.conf\nIncludeOptional sites-enabled/*.conf\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "ports.conf"), "Listen 443\n")
+	writeConfigFile(t, hostPath, "<VirtualHost *:443>\n    ServerName example.com\n    SSLEngine on\n</VirtualHost>\n")
+
+	ctl := "/usr/sbin/apache2ctl -d " + serverRoot
+	includes := fmt.Sprintf("Included configuration files:\n  (*) %[1]s/apache2.conf\n    (1) %[1]s/ports.conf\n    (2) %[1]s/sites-enabled/example.com-ssl.conf\n", serverRoot)
+	cmdRunner := runner.GetFakeRunner().
+		On(ctl+" -v", runner.FakeResponse{Stdout: "Server version: Apache/2.4.57 (Debian)\n"}).
+		On(ctl+" -t -D DUMP_RUN_CFG", runner.FakeResponse{Stdout: "Define: DUMP_RUN_CFG\n"}).
+		On(ctl+" -t -D DUMP_INCLUDES", runner.FakeResponse{Stdout: includes}).
+		On(ctl+" -t -D DUMP_MODULES", runner.FakeResponse{Stdout: "Loaded Modules:\n core_module (static)\n ssl_module (shared)\n"}).
+		On(ctl+" -t", runner.FakeResponse{Stderr: "Syntax OK"})
+
+	options := apacheoptions.GetOptions(map[string]string{
+		apacheoptions.ServerRoot: serverRoot,
+		apacheoptions.ApacheCtl:  "/usr/sbin/apache2ctl",
+	})
+
+	for _, name := range []string{tlsprofile.Intermediate, tlsprofile.Modern} {
+		webServerManager, err := getApacheManager(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not create apache webserver manager: %v", err)
+		profile, err := tlsprofile.GetProfile(name)
+		assert.Nilf(t, err, "could not get TLS profile: %v", err)
+		err = webServerManager.ApplyTLSProfile("example.com", profile, false)
+		assert.Nilf(t, err, "could not apply %s TLS profile: %v", name, err)
+		err = webServerManager.SaveChanges()
+		assert.Nilf(t, err, "could not save changes: %v", err)
+		webServerManager.parser.Close()
+	}
+
+	content, _ := os.ReadFile(hostPath)
+	assert.NotContains(t, string(content), "SSLCipherSuite", "ciphers of the intermediate profile must be removed")
+	assert.Equal(t, 1, strings.Count(string(content), "SSLProtocol"))
+}
+
+func TestApacheDualCertificatesWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	hostPath := filepath.Join(serverRoot, "sites-enabled", "example.com-ssl.conf")
+	writeConfigFile(t, filepath.Join(serverRoot, "apache2.conf"), "LoadModule ssl_module modules/mod_ssl.so\nInclude ports.conf\nIncludeOptional sites-enabled/*.conf\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "ports.conf"), "Listen 443\n")
+	writeConfigFile(t, hostPath, "<VirtualHost *:443>\n    ServerName example.com\n    SSLEngine on\n</VirtualHost>\n")
+
+	ctl := "/usr/sbin/apache2ctl -d " + serverRoot
+	includes := fmt.Sprintf("Included configuration files:\n  (*) %[1]s/apache2.conf\n    (1) %[1]s/ports.conf\n    (2) %[1]s/sites-enabled/example.com-ssl.conf\n", serverRoot)
+	cmdRunner := runner.GetFakeRunner().
+		On(ctl+" -v", runner.FakeResponse{Stdout: "Server version: Apache/2.4.57 (Debian)\n"}).
+		On(ctl+" -t -D DUMP_RUN_CFG", runner.FakeResponse{Stdout: "Define: DUMP_RUN_CFG\n"}).
+		On(ctl+" -t -D DUMP_INCLUDES", runner.FakeResponse{Stdout: includes}).
+		On(ctl+" -t -D DUMP_MODULES", runner.FakeResponse{Stdout: "Loaded Modules:\n core_module (static)\n ssl_module (shared)\n"}).
+		On(ctl+" -t", runner.FakeResponse{Stderr: "Syntax OK"})
+
+	options := apacheoptions.GetOptions(map[string]string{
+		apacheoptions.ServerRoot: serverRoot,
+		apacheoptions.ApacheCtl:  "/usr/sbin/apache2ctl",
+	})
+
+	// certificates are deployed with separate chain files, so the host relies on SSLCertificateChainFile
+	issuer := generateIssuer(t, "Intermediate")
+	rsaCert := writeIssuedCertificate(t, certificate.KeyTypeRSA, issuer)
+	ecdsaCert := writeIssuedCertificate(t, certificate.KeyTypeECDSA, issuer)
+	newEcdsaCert := writeIssuedCertificate(t, certificate.KeyTypeECDSA, issuer)
+
+	for _, certificates := range [][]webserver.Certificate{{rsaCert, ecdsaCert}, {newEcdsaCert}} {
+		webServerManager, err := getApacheManager(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not create apache webserver manager: %v", err)
+		err = webServerManager.DeployCertificates("example.com", certificates)
+		assert.Nilf(t, err, "could not deploy certificates: %v", err)
+		err = webServerManager.SaveChanges()
+		assert.Nilf(t, err, "could not save changes: %v", err)
+		webServerManager.parser.Close()
+	}
+
+	content, _ := os.ReadFile(hostPath)
+	assert.Contains(t, string(content), "SSLCertificateFile "+rsaCert.CertPath, "certificate of other key type must be kept")
+	assert.Contains(t, string(content), "SSLCertificateKeyFile "+rsaCert.KeyPath)
+	assert.Contains(t, string(content), "SSLCertificateFile "+newEcdsaCert.CertPath)
+	assert.NotContains(t, string(content), ecdsaCert.CertPath, "certificate of the same key type must be replaced")
+	assert.Contains(t, string(content), "SSLCertificateChainFile", "chain of the kept certificate must be kept")
+
+	otherCert := writeIssuedCertificate(t, certificate.KeyTypeECDSA, generateIssuer(t, "Other Intermediate"))
+	webServerManager, err := getApacheManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create apache webserver manager: %v", err)
+	defer webServerManager.parser.Close()
+	err = webServerManager.DeployCertificates("example.com", []webserver.Certificate{otherCert})
+	assert.NotNil(t, err, "chain of other intermediate must not replace the chain of the kept certificate")
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	assert.Nilf(t, err, "could not create config directory: %v", err)
+	err = os.WriteFile(path, []byte(content), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+}
+
 func getWebServerManager(t *testing.T) *ApacheManager {
+	if testing.Short() {
+		t.Skip("apache is required")
+	}
+
 	webServerManager, err := GetApacheManager(nil, logger.NilLogger{})
 	assert.Nil(t, err, fmt.Sprintf("could not create apache webserver manager: %v", err))
 
@@ -242,3 +405,26 @@ func getSitesAvailablePath() string {
 
 	return "/etc/apache2/sites-available"
 }
+
+func generateIssuer(t *testing.T, name string) *certificate.Issuer {
+	key, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate issuer key: %v", err)
+	issuer, err := certificate.GenerateCA(name, key, 24*time.Hour)
+	assert.Nilf(t, err, "could not generate issuer: %v", err)
+
+	return &certificate.Issuer{Certificate: issuer, Key: key}
+}
+
+// writeIssuedCertificate writes the certificate of the key type with a separate chain file, full chain is not used
+func writeIssuedCertificate(t *testing.T, keyType string, issuer *certificate.Issuer) webserver.Certificate {
+	key, err := certificate.GenerateKey(keyType)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+	leaf, err := certificate.GenerateCertificate([]string{"example.com"}, key, 24*time.Hour, issuer)
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+
+	cert, err := webserver.WriteCertificate(t.TempDir(), key, []*x509.Certificate{leaf, issuer.Certificate})
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+	cert.FullChainPath = ""
+
+	return cert
+}
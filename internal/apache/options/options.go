@@ -14,6 +14,8 @@ const (
 	ApacheCtl = "ctl"
 	// SslVhostlExt postfix for config files of created SSL virtual hosts
 	SslVhostlExt = "ssl_vhost_ext"
+	// Defines is a comma separated list of apache defines used in offline mode: NAME=value,NAME
+	Defines = "defines"
 )
 
 func GetOptions(params map[string]string) options.Options {
@@ -31,6 +33,7 @@ func GetDefaults() map[string]string {
 	defaults[HostFiles] = "*"
 	defaults[ApacheCtl] = ""
 	defaults[SslVhostlExt] = "-ssl.conf"
+	defaults[Defines] = ""
 
 	wsOptions := webserverOptions.GetDefaults()
 
@@ -13,6 +13,7 @@ import (
 	"github.com/r2dtools/webmng/internal/apache/utils"
 	"github.com/r2dtools/webmng/pkg/aug"
 	commonutils "github.com/r2dtools/webmng/pkg/utils"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
 	"github.com/r2dtools/webmng/pkg/webserver/reverter"
 	"github.com/unknwon/com"
 	"honnef.co/go/augeas"
@@ -37,7 +38,9 @@ type Parser struct {
 	ConfigListen,
 	ConfigRoot,
 	lensModule,
-	hostRoot string
+	hostRoot,
+	// root is an alternate root directory absolute paths of the configuration are resolved against
+	root string
 	existingPaths map[string][]string
 	apachectl     apachectl.ApacheCtl
 	variables     map[string]string
@@ -139,9 +142,34 @@ func (p *Parser) AddDirective(augConfPath string, directive string, args []strin
 	return nil
 }
 
+// InsertDirective inserts directive before the directive node given by augPath
+func (p *Parser) InsertDirective(augPath string, directive string, args []string) error {
+	if err := p.Augeas.Insert(augPath, "directive", true); err != nil {
+		return fmt.Errorf("could not insert directive %s: %v", directive, err)
+	}
+
+	// the inserted node takes position of the node it is inserted before
+	nPath := augPath
+	if !strings.HasSuffix(nPath, "]") {
+		nPath += "[1]"
+	}
+
+	if err := p.Augeas.Set(nPath, directive); err != nil {
+		return err
+	}
+
+	for i, arg := range args {
+		if err := p.Augeas.Set(fmt.Sprintf("%s/arg[%d]", nPath, i+1), arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // AddDirectiveToIfModSSL adds directive to the end of the file given by augConfPath within IfModule ssl block
 func (p *Parser) AddDirectiveToIfModSSL(augConfPath string, directive string, args []string) error {
-	ifModPath, err := p.getIfModule(augConfPath, "mod_ssl.c", false)
+	ifModPath, err := p.getIfModSSL(augConfPath)
 
 	if err != nil {
 		return err
@@ -172,6 +200,40 @@ func (p *Parser) AddDirectiveToIfModSSL(augConfPath string, directive string, ar
 	return nil
 }
 
+// GetSection returns the path to <section arg> within augConfPath. The section is created at the end if it does not exist and create is true.
+func (p *Parser) GetSection(augConfPath, section, arg string, create bool) (string, error) {
+	sectionPath := fmt.Sprintf("%s/*[label()=~regexp('%s', 'i')][arg='%s' or arg='\"%s\"']", augConfPath, section, arg, arg)
+	matches, err := p.Augeas.Match(sectionPath)
+
+	if err != nil {
+		return "", fmt.Errorf("could not get %s section: %v", section, err)
+	}
+
+	if len(matches) > 0 {
+		return matches[0], nil
+	}
+
+	if !create {
+		return "", nil
+	}
+
+	if err = p.Augeas.Set(fmt.Sprintf("%s/%s[last() + 1]", augConfPath, section), ""); err != nil {
+		return "", fmt.Errorf("could not create %s section: %v", section, err)
+	}
+
+	if err = p.Augeas.Set(fmt.Sprintf("%s/%s[last()]/arg", augConfPath, section), arg); err != nil {
+		return "", fmt.Errorf("could not set %s section argument %s: %v", section, arg, err)
+	}
+
+	matches, err = p.Augeas.Match(sectionPath)
+
+	if err != nil || len(matches) == 0 {
+		return "", fmt.Errorf("could not get created %s section: %v", section, err)
+	}
+
+	return matches[0], nil
+}
+
 // AddInclude adds Include directive for a configuration file
 func (p *Parser) AddInclude(mainConfigPath string, inclPath string) error {
 	matches, err := p.FindDirective("Include", inclPath, "", true)
@@ -236,6 +298,13 @@ func (p *Parser) UpdateRuntimeVariables() error {
 		return err
 	}
 
+	// apache is not run in offline mode, so Define directives are taken from the configuration
+	if p.apachectl.IsOffline() {
+		if err := p.updateConfigDefines(); err != nil {
+			return err
+		}
+	}
+
 	if err := p.updateModules(); err != nil {
 		return err
 	}
@@ -309,6 +378,68 @@ func (p *Parser) Save(reverter reverter.Reverter) error {
 	return nil
 }
 
+// SaveStaged saves changes via apply instead of writing them to the live files.
+// Augeas writes changed files next to the live ones with .augnew extension, their contents are passed to apply and the .augnew files are removed.
+// The changes are discarded if apply fails.
+func (p *Parser) SaveStaged(reverter reverter.Reverter, apply func(files map[string][]byte) error) error {
+	unsavedFiles, err := p.getUnsavedFiles()
+
+	if err != nil {
+		return err
+	}
+
+	if len(unsavedFiles) == 0 {
+		return nil
+	}
+
+	if reverter != nil {
+		if err = reverter.BackupFiles(unsavedFiles); err != nil {
+			return err
+		}
+	}
+
+	saveMethod, err := p.Augeas.Get("/augeas/save")
+	if err != nil {
+		return err
+	}
+
+	// See https://github.com/hercules-team/augeas/wiki/Change-how-files-are-saved
+	if err = p.Augeas.Set("/augeas/save", "newfile"); err != nil {
+		return err
+	}
+
+	err = p.Augeas.Save()
+	p.Augeas.Set("/augeas/save", saveMethod)
+	files := make(map[string][]byte)
+
+	for _, unsavedFile := range unsavedFiles {
+		newFile := unsavedFile + ".augnew"
+		content, readErr := os.ReadFile(newFile)
+
+		if readErr == nil {
+			files[unsavedFile] = content
+			os.Remove(newFile)
+		} else if err == nil && !os.IsNotExist(readErr) {
+			err = readErr
+		}
+	}
+
+	if err == nil {
+		err = apply(files)
+	}
+
+	// files are reloaded from the disk: applied changes are read from the live files, failed ones are discarded
+	for _, unsavedFile := range unsavedFiles {
+		p.Augeas.Remove(fmt.Sprintf("/files/%s", unsavedFile))
+	}
+
+	if loadErr := p.Augeas.Load(); err == nil {
+		err = loadErr
+	}
+
+	return err
+}
+
 // getIfModule returns the path to <IfModule mod> and creates one if it does not exist
 func (p *Parser) getIfModule(augConfPath string, mod string, begining bool) (string, error) {
 	ifMods, err := p.Augeas.Match(fmt.Sprintf("%s/IfModule/*[self::arg='%s']", augConfPath, mod))
@@ -326,6 +457,24 @@ func (p *Parser) getIfModule(augConfPath string, mod string, begining bool) (str
 	return path, nil
 }
 
+// getIfModSSL returns the path to the existing SSL IfModule block, e.g. <IfModule ssl_module> of Debian ports.conf.
+// <IfModule mod_ssl.c> is created if there is no such block.
+func (p *Parser) getIfModSSL(augConfPath string) (string, error) {
+	ifMods, err := p.Augeas.Match(fmt.Sprintf("%s/IfModule/*[self::arg='mod_ssl.c' or self::arg='ssl_module']", augConfPath))
+
+	if err != nil {
+		return "", fmt.Errorf("could not get IfModule directive: %v", err)
+	}
+
+	if len(ifMods) == 0 {
+		return p.createIfModule(augConfPath, "mod_ssl.c", false)
+	}
+
+	path, _, _ := xstrings.LastPartition(ifMods[0], "arg")
+
+	return path, nil
+}
+
 // CreateIfModule creates a new <IfMod mod> and returns its path
 func (p *Parser) createIfModule(augConfPath string, mod string, begining bool) (string, error) {
 	var argPath, retPath string
@@ -491,11 +640,78 @@ func (p *Parser) updateDefines() error {
 		return fmt.Errorf("could not parse defines: %v", err)
 	}
 
+	if p.apachectl.IsOffline() {
+		// in offline mode variables exported by apachectl from envvars file are not available
+		envVars := p.getEnvVars()
+
+		for name, value := range variables {
+			envVars[name] = value
+		}
+
+		variables = envVars
+	}
+
 	p.variables = variables
 
 	return nil
 }
 
+// getEnvVars returns variables exported by envvars file in the server root
+func (p *Parser) getEnvVars() map[string]string {
+	content, err := os.ReadFile(filepath.Join(p.ServerRoot, "envvars"))
+
+	if err != nil {
+		return make(map[string]string)
+	}
+
+	return utils.ParseEnvVars(string(content))
+}
+
+// updateConfigDefines adds variables defined by Define directives of the configuration
+func (p *Parser) updateConfigDefines() error {
+	matches, err := p.FindDirective("Define", "", "", false)
+
+	if err != nil {
+		return fmt.Errorf("could not parse defines: %v", err)
+	}
+
+	var directives []string
+	args := make(map[string][]string)
+
+	for _, match := range matches {
+		directive := path.Dir(match)
+
+		if _, ok := args[directive]; !ok {
+			directives = append(directives, directive)
+		}
+
+		arg, err := p.GetArg(match)
+
+		if err != nil {
+			// arguments referencing unknown variables are treated as empty
+			arg = ""
+		}
+
+		args[directive] = append(args[directive], arg)
+	}
+
+	for _, directive := range directives {
+		name := args[directive][0]
+
+		if _, ok := p.variables[name]; ok || name == "" {
+			continue
+		}
+
+		if len(args[directive]) > 1 {
+			p.variables[name] = args[directive][1]
+		} else {
+			p.variables[name] = ""
+		}
+	}
+
+	return nil
+}
+
 // updateIncludes gets includes from httpd process, and add them to DOM if needed
 func (p *Parser) updateIncludes() error {
 	// FindDirective iterates over configuration for Include and IncludeOptional
@@ -528,9 +744,53 @@ func (p *Parser) updateModules() error {
 		p.addModule(strings.TrimSpace(module))
 	}
 
+	// apache is not run in offline mode, so LoadModule directives are taken from the configuration
+	if p.apachectl.IsOffline() {
+		return p.updateConfigModules()
+	}
+
+	return nil
+}
+
+// updateConfigModules adds modules loaded by LoadModule directives of the configuration
+func (p *Parser) updateConfigModules() error {
+	matches, err := p.FindDirective("LoadModule", "", "", false)
+
+	if err != nil {
+		return fmt.Errorf("could not parse loaded modules: %v", err)
+	}
+
+	for _, match := range matches {
+		// the first argument is the module name, e.g. LoadModule ssl_module /usr/lib/apache2/modules/mod_ssl.so
+		if arg, err := p.GetArg(match); err == nil && strings.HasSuffix(arg, "_module") {
+			p.addModule(strings.TrimSuffix(arg, "_module"))
+		}
+	}
+
 	return nil
 }
 
+// GetModulePath returns the absolute path of the module library loaded by LoadModule directive, e.g. ssl for mod_ssl.
+// Empty path is returned if the module is not loaded by the configuration.
+func (p *Parser) GetModulePath(name string) (string, error) {
+	matches, err := p.FindDirective("LoadModule", fmt.Sprintf("^%s_module$", name), "", false)
+	if err != nil {
+		return "", fmt.Errorf("could not parse loaded modules: %v", err)
+	}
+
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	// the module library is the second argument: LoadModule ssl_module /usr/lib/apache2/modules/mod_ssl.so
+	path, err := p.GetArg(matches[0][:strings.LastIndex(matches[0], "/")] + "/arg[2]")
+	if err != nil {
+		return "", err
+	}
+
+	return p.convertPathFromServerRootToAbs(path), nil
+}
+
 func (p *Parser) addModule(name string) {
 	modKey := fmt.Sprintf("%s_module", name)
 
@@ -656,7 +916,7 @@ func (p *Parser) convertPathFromServerRootToAbs(path string) string {
 	path = strings.Trim(path, "'\"")
 
 	if strings.HasPrefix(path, "/") {
-		path = filepath.Clean(path)
+		path = webserverOptions.GetRootedPath(p.root, filepath.Clean(path))
 	} else {
 		path = filepath.Clean(filepath.Join(p.ServerRoot, path))
 	}
@@ -748,7 +1008,9 @@ func (p *Parser) addTransform(fPath string) error {
 	return nil
 }
 
-func GetParser(apachectl apachectl.ApacheCtl, lensModule, serverRoot, hostRoot, hostFiles string) (*Parser, error) {
+// GetParser returns apache parser. Root config file is detected in serverRoot if configFile is empty.
+// Absolute paths in the configuration are resolved against root if it is not empty. serverRoot, configFile and hostRoot must be already resolved.
+func GetParser(apachectl apachectl.ApacheCtl, lensModule, root, serverRoot, configFile, hostRoot, hostFiles string) (*Parser, error) {
 	var err error
 
 	if hostRoot != "" {
@@ -760,7 +1022,7 @@ func GetParser(apachectl apachectl.ApacheCtl, lensModule, serverRoot, hostRoot,
 	}
 
 	// try to detect apache root config file path (ex. /etc/apache2/apache2.conf), ports.conf file path
-	configRoot, err := commonutils.FindAnyFilesInDirectory(serverRoot, configFiles)
+	configRoot, err := GetConfigRoot(serverRoot, configFile)
 
 	if err != nil {
 		return nil, err
@@ -779,6 +1041,7 @@ func GetParser(apachectl apachectl.ApacheCtl, lensModule, serverRoot, hostRoot,
 		ConfigListen:  configListen,
 		ConfigRoot:    configRoot,
 		hostRoot:      hostRoot,
+		root:          root,
 		existingPaths: make(map[string][]string),
 		LoadedPaths:   make(map[string][]string),
 		lensModule:    lensModule,
@@ -815,6 +1078,23 @@ func GetParser(apachectl apachectl.ApacheCtl, lensModule, serverRoot, hostRoot,
 	return &parser, nil
 }
 
+// GetConfigRoot returns main config file, it is detected in serverRoot if configFile is empty
+func GetConfigRoot(serverRoot, configFile string) (string, error) {
+	if configFile == "" {
+		return commonutils.FindAnyFilesInDirectory(serverRoot, configFiles)
+	}
+
+	if !filepath.IsAbs(configFile) {
+		configFile = filepath.Join(serverRoot, configFile)
+	}
+
+	if !com.IsFile(configFile) {
+		return "", fmt.Errorf("apache config file '%s' does not exist", configFile)
+	}
+
+	return configFile, nil
+}
+
 func getConfigListen(serverRoot, configRoot string) string {
 	configPorts := filepath.Join(serverRoot, "ports.conf")
 
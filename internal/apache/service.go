@@ -0,0 +1,153 @@
+package apache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	apacheutils "github.com/r2dtools/webmng/internal/apache/utils"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/service"
+	"github.com/unknwon/com"
+)
+
+// serviceUnits are systemd units of apache on Debian and RHEL based systems
+var serviceUnits = []string{"apache2.service", "httpd.service"}
+
+// defaultPidFiles are apache pid files used if PidFile directive is not set. Relative paths are resolved against the server root.
+var defaultPidFiles = []string{"run/httpd.pid", "logs/httpd.pid", "/run/apache2/apache2.pid"}
+
+// Reload gracefully restarts apache, current connections are not aborted
+func (m *ApacheManager) Reload() error {
+	if m.apachectl.IsOffline() {
+		return m.apachectl.Reload()
+	}
+
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Reload()
+}
+
+// Restart restarts apache web server
+func (m *ApacheManager) Restart() error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Restart()
+}
+
+func (m *ApacheManager) Start() error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Start()
+}
+
+// Stop stops apache. Graceful stop waits for the current requests to be served.
+func (m *ApacheManager) Stop(graceful bool) error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Stop(graceful)
+}
+
+func (m *ApacheManager) Status() (webserver.ServiceStatus, error) {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return webserver.ServiceStatus{}, err
+	}
+
+	return controller.Status()
+}
+
+// getServiceController returns systemd or apachectl controller of apache
+func (m *ApacheManager) getServiceController() (webserver.ServiceController, error) {
+	if m.service != nil {
+		return m.service, nil
+	}
+
+	if m.apachectl.IsOffline() {
+		return nil, errors.New("apache process could not be controlled in offline mode")
+	}
+
+	units := serviceUnits
+
+	if unit := m.options.Get(webserverOptions.ServiceUnit); unit != "" {
+		units = []string{unit}
+	}
+
+	// WINCH is apache graceful-stop signal
+	ctl := service.GetCtlController(m.apachectl, m.getPidFile())
+	controller, err := service.GetController(m.runner, m.options.Get(webserverOptions.ServiceBackend), units, "WINCH", ctl)
+	if err != nil {
+		return nil, err
+	}
+
+	// reload command is run by apachectl
+	if m.options.Get(webserverOptions.ReloadCmd) != "" {
+		controller = service.WithReload(controller, m.apachectl.Reload)
+	}
+
+	m.service = controller
+
+	return controller, nil
+}
+
+// getPidFile returns apache pid file from options, PidFile directive or the default locations
+func (m *ApacheManager) getPidFile() string {
+	if pidFile := m.options.Get(webserverOptions.PidFile); pidFile != "" {
+		return pidFile
+	}
+
+	if matches, err := m.parser.FindDirective("PidFile", "", "", true); err == nil && len(matches) > 0 {
+		pidFile, err := m.parser.GetArg(matches[0])
+
+		// Debian sets PidFile from APACHE_PID_FILE environment variable exported by envvars file
+		if err != nil {
+			rawPidFile, _ := m.parser.Augeas.Get(matches[0])
+			envVars := m.getEnvVars()
+			pidFile = os.Expand(rawPidFile, func(name string) string {
+				return envVars[name]
+			})
+		}
+
+		if pidFile != "" {
+			return m.getServerRootPath(pidFile)
+		}
+	}
+
+	for _, pidFile := range defaultPidFiles {
+		if pidFile = m.getServerRootPath(pidFile); com.IsFile(pidFile) {
+			return pidFile
+		}
+	}
+
+	return m.getServerRootPath(defaultPidFiles[0])
+}
+
+func (m *ApacheManager) getEnvVars() map[string]string {
+	content, err := os.ReadFile(filepath.Join(m.parser.ServerRoot, "envvars"))
+	if err != nil {
+		return nil
+	}
+
+	return apacheutils.ParseEnvVars(string(content))
+}
+
+func (m *ApacheManager) getServerRootPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(m.parser.ServerRoot, path)
+}
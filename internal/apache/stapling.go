@@ -0,0 +1,74 @@
+package apache
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+)
+
+// EnableStapling enables OCSP stapling for ssl hosts with the server name.
+// Stapling is not enabled if a host certificate has no OCSP responder URL.
+// Apache uses configured certificate chain and the system resolver, so the config is not used.
+func (m *ApacheManager) EnableStapling(serverName string, config webserver.StaplingConfig) error {
+	aHosts := m.getSslApacheHosts(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, aHost := range aHosts {
+		tlsConfig, err := m.getHostTLSConfig(aHost)
+		if err != nil {
+			return err
+		}
+
+		if len(tlsConfig.Certificates) == 0 {
+			return fmt.Errorf("host %s has no certificate", aHost.ServerName)
+		}
+
+		for _, files := range tlsConfig.Certificates {
+			if err := certificate.CheckOCSPServer(files.CertPath); err != nil {
+				return fmt.Errorf("could not enable OCSP stapling: %v", err)
+			}
+		}
+	}
+
+	if err := m.ensureStaplingCache(); err != nil {
+		return err
+	}
+
+	return m.setHostsStapling(aHosts, true)
+}
+
+// DisableStapling disables OCSP stapling for ssl hosts with the server name
+func (m *ApacheManager) DisableStapling(serverName string) error {
+	aHosts := m.getSslApacheHosts(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	return m.setHostsStapling(aHosts, false)
+}
+
+func (m *ApacheManager) setHostsStapling(aHosts []apacheHost, enabled bool) error {
+	value := "off"
+
+	if enabled {
+		value = "on"
+	}
+
+	directive := tlsprofile.Directive{Name: "SSLUseStapling", Values: []string{value}}
+
+	for _, aHost := range aHosts {
+		m.removeHostDirective(aHost.AugPath, directive)
+
+		if err := m.parser.AddDirective(aHost.AugPath, directive.Name, directive.Values); err != nil {
+			return fmt.Errorf("could not add '%s' directive to host %s: %v", directive.Name, aHost.ServerName, err)
+		}
+	}
+
+	return nil
+}
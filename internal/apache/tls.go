@@ -0,0 +1,145 @@
+package apache
+
+import (
+	"path/filepath"
+	"strings"
+
+	apacheutils "github.com/r2dtools/webmng/internal/apache/utils"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+)
+
+var defaultSSLProtocol = []string{"all", "-SSLv3"}
+
+// GetTLSConfigs returns effective TLS settings of ssl hosts. All ssl hosts are used if serverName is empty.
+func (m *ApacheManager) GetTLSConfigs(serverName string) ([]webserver.TLSConfig, error) {
+	var configs []webserver.TLSConfig
+
+	for _, aHost := range m.getSslApacheHosts(serverName) {
+		config, err := m.getHostTLSConfig(aHost)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
+
+func (m *ApacheManager) getHostTLSConfig(aHost apacheHost) (webserver.TLSConfig, error) {
+	config := webserver.TLSConfig{
+		WebServer: webserver.Apache,
+		Host:      aHost.Host,
+	}
+
+	protocolArgs, err := m.getLastEffectiveDirective("SSLProtocol", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	if len(protocolArgs) == 0 {
+		protocolArgs = defaultSSLProtocol
+	}
+
+	config.Protocols = apacheutils.ParseSSLProtocol(protocolArgs)
+
+	cipherArgs, err := m.getLastEffectiveDirective("SSLCipherSuite", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	// SSLCipherSuite [protocol] cipher-spec
+	if len(cipherArgs) > 0 {
+		config.Ciphers = cipherArgs[len(cipherArgs)-1]
+	}
+
+	if config.PreferServerCiphers, err = m.isEffectiveDirectiveOn("SSLHonorCipherOrder", aHost.AugPath, false); err != nil {
+		return config, err
+	}
+
+	if config.OcspStapling, err = m.isEffectiveDirectiveOn("SSLUseStapling", aHost.AugPath, false); err != nil {
+		return config, err
+	}
+
+	if config.SessionTickets, err = m.isEffectiveDirectiveOn("SSLSessionTickets", aHost.AugPath, true); err != nil {
+		return config, err
+	}
+
+	confCmds, err := m.getEffectiveDirectives("SSLOpenSSLConfCmd", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	for _, confCmd := range confCmds {
+		if len(confCmd) > 1 && strings.EqualFold(confCmd[0], "DHParameters") {
+			config.DHParamPath = m.getAbsPath(confCmd[1])
+		}
+	}
+
+	headers, err := m.getEffectiveDirectives("Header", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	for _, header := range headers {
+		for i, arg := range header {
+			if strings.EqualFold(arg, "Strict-Transport-Security") && i+1 < len(header) {
+				config.HstsHeader = header[i+1]
+			}
+		}
+	}
+
+	certificates, err := m.getEffectiveDirectiveArgs("SSLCertificateFile", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	keys, err := m.getEffectiveDirectiveArgs("SSLCertificateKeyFile", aHost.AugPath)
+	if err != nil {
+		return config, err
+	}
+
+	for i, certificate := range certificates {
+		// the key could be combined with the certificate in the same file
+		files := webserver.CertificateFiles{
+			CertPath: m.getAbsPath(certificate),
+			KeyPath:  m.getAbsPath(certificate),
+		}
+
+		if i < len(keys) {
+			files.KeyPath = m.getAbsPath(keys[i])
+		}
+
+		config.Certificates = append(config.Certificates, files)
+	}
+
+	return config, nil
+}
+
+func (m *ApacheManager) getLastEffectiveDirective(directive, hostPath string) ([]string, error) {
+	directives, err := m.getEffectiveDirectives(directive, hostPath)
+	if err != nil || len(directives) == 0 {
+		return nil, err
+	}
+
+	return directives[len(directives)-1], nil
+}
+
+func (m *ApacheManager) isEffectiveDirectiveOn(directive, hostPath string, def bool) (bool, error) {
+	args, err := m.getLastEffectiveDirective(directive, hostPath)
+	if err != nil || len(args) == 0 {
+		return def, err
+	}
+
+	return strings.EqualFold(args[0], "on"), nil
+}
+
+// getAbsPath returns absolute path. Relative paths are resolved against the server root.
+func (m *ApacheManager) getAbsPath(path string) string {
+	if filepath.IsAbs(path) {
+		return webserverOptions.GetRootedPath(m.options.Get(webserverOptions.Root), filepath.Clean(path))
+	}
+
+	return filepath.Join(m.parser.ServerRoot, path)
+}
@@ -0,0 +1,132 @@
+package apache
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/r2dtools/webmng/pkg/aug"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/unknwon/com"
+	"golang.org/x/exp/slices"
+)
+
+const staplingCache = "shmcb:/var/run/ocsp(128000)"
+
+// ApplyTLSProfile writes directives of the TLS profile to ssl hosts. All ssl hosts are used if serverName is empty.
+// If snippet is true, directives are written to the shared file which is included by the hosts.
+func (m *ApacheManager) ApplyTLSProfile(serverName string, profile tlsprofile.Profile, snippet bool) error {
+	aHosts := m.getSslApacheHosts(serverName)
+
+	if len(aHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	directives := profile.GetDirectives(webserver.Apache, tlsprofile.PolicySettings, "")
+
+	if profile.OcspStapling && !slices.Contains(profile.UnsupportedSettings, tlsprofile.SettingOcspStapling) {
+		if err := m.ensureStaplingCache(); err != nil {
+			return err
+		}
+	}
+
+	var snippetPath string
+
+	if snippet {
+		var err error
+		if snippetPath, err = m.writeTLSSnippet(profile, directives); err != nil {
+			return err
+		}
+	}
+
+	for _, aHost := range aHosts {
+		// directives of the previously applied profile are replaced
+		m.parser.Augeas.Remove(fmt.Sprintf(
+			"%s/directive[self::directive=~regexp('Include|IncludeOptional', 'i')][arg[1]=~regexp('.*/%s[^/]*')]",
+			aHost.AugPath,
+			tlsprofile.SnippetFilePrefix,
+		))
+
+		for _, directive := range tlsprofile.GetPolicyDirectives(webserver.Apache) {
+			m.removeHostDirective(aHost.AugPath, directive)
+		}
+
+		if snippet {
+			if err := m.parser.AddDirective(aHost.AugPath, "Include", []string{snippetPath}); err != nil {
+				return fmt.Errorf("could not add 'Include' directive to host %s: %v", aHost.ServerName, err)
+			}
+
+			continue
+		}
+
+		for _, directive := range directives {
+			if err := m.parser.AddDirective(aHost.AugPath, directive.Name, directive.Values); err != nil {
+				return fmt.Errorf("could not add '%s' directive to host %s: %v", directive.Name, aHost.ServerName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *ApacheManager) getSslApacheHosts(serverName string) []apacheHost {
+	var sslHosts []apacheHost
+
+	for _, aHost := range m.getApacheHosts() {
+		if aHost.Ssl && !aHost.ModMacro && (serverName == "" || aHost.ServerName == serverName) {
+			sslHosts = append(sslHosts, aHost)
+		}
+	}
+
+	return sslHosts
+}
+
+// removeHostDirective removes the directive defined directly in the host.
+// SSLOpenSSLConfCmd directives are distinguished by the command name.
+func (m *ApacheManager) removeHostDirective(hostPath string, directive tlsprofile.Directive) {
+	path := fmt.Sprintf("%s/directive[self::directive=~regexp('%s', 'i')]", hostPath, directive.Name)
+
+	if directive.Name == "SSLOpenSSLConfCmd" && len(directive.Values) > 0 {
+		path += fmt.Sprintf("[arg[1]=~regexp('%s', 'i')]", directive.Values[0])
+	}
+
+	m.parser.Augeas.Remove(path)
+}
+
+// ensureStaplingCache adds SSLStaplingCache to the main configuration since OCSP stapling does not work without it
+func (m *ApacheManager) ensureStaplingCache() error {
+	matches, err := m.parser.FindDirective("SSLStaplingCache", "", "", true)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) > 0 {
+		return nil
+	}
+
+	if err := m.parser.AddDirectiveToIfModSSL(aug.GetAugPath(m.parser.ConfigRoot), "SSLStaplingCache", []string{staplingCache}); err != nil {
+		return fmt.Errorf("could not add 'SSLStaplingCache' directive: %v", err)
+	}
+
+	return nil
+}
+
+func (m *ApacheManager) writeTLSSnippet(profile tlsprofile.Profile, directives []tlsprofile.Directive) (string, error) {
+	snippetPath := filepath.Join(m.parser.ServerRoot, profile.GetSnippetFileName())
+
+	if com.IsFile(snippetPath) {
+		if err := m.reverter.BackupFile(snippetPath); err != nil {
+			return "", err
+		}
+	} else {
+		m.reverter.AddFileToDeletion(snippetPath)
+	}
+
+	content := profile.GetSnippetContent(webserver.Apache, directives)
+
+	if err := m.changes.WriteFile(snippetPath, []byte(content)); err != nil {
+		return "", fmt.Errorf("could not write TLS snippet %s: %v", snippetPath, err)
+	}
+
+	return snippetPath, nil
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
 
@@ -65,7 +66,11 @@ func IsPortListened(listens []string, port string) bool {
 	}
 
 	for _, listen := range listens {
-		// listen can be 1.1.1.1:443 https
+		// listen can be 1.1.1.1:443 https or 8443 https
+		if strings.Split(listen, " ")[0] == port {
+			return true
+		}
+
 		lParts := strings.Split(listen, ":")
 
 		if len(lParts) > 1 {
@@ -205,3 +210,59 @@ func RemoveClosingHostTag(lines []string) {
 		}
 	}
 }
+
+// ParseSSLProtocol returns enabled protocols for SSLProtocol directive arguments, e.g. "all -SSLv3 -TLSv1"
+func ParseSSLProtocol(args []string) []string {
+	allProtocols := []string{"SSLv3", "TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+	enabled := make(map[string]bool)
+
+	for _, arg := range args {
+		protocol := strings.TrimLeft(arg, "+-")
+		enable := !strings.HasPrefix(arg, "-")
+		protocols := []string{protocol}
+
+		if strings.EqualFold(protocol, "all") {
+			protocols = allProtocols
+		}
+
+		for _, p := range protocols {
+			for _, known := range allProtocols {
+				if strings.EqualFold(p, known) {
+					enabled[known] = enable
+				}
+			}
+		}
+	}
+
+	var result []string
+
+	for _, protocol := range allProtocols {
+		if enabled[protocol] {
+			result = append(result, protocol)
+		}
+	}
+
+	return result
+}
+
+var envVarRegexp = regexp.MustCompile(`^export\s+([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// ParseEnvVars returns variables exported at the top level of apache envvars file, e.g. "export APACHE_LOG_DIR=/var/log/apache2$SUFFIX".
+// Conditional assignments are skipped. References to exported variables are expanded, others are replaced with empty string.
+func ParseEnvVars(content string) map[string]string {
+	variables := make(map[string]string)
+
+	for _, line := range strings.Split(content, "\n") {
+		matches := envVarRegexp.FindStringSubmatch(strings.TrimRight(line, " \t\r"))
+		if matches == nil {
+			continue
+		}
+
+		value := strings.Trim(strings.TrimSpace(matches[2]), "'\"")
+		variables[matches[1]] = os.Expand(value, func(name string) string {
+			return variables[name]
+		})
+	}
+
+	return variables
+}
@@ -24,11 +24,12 @@ func TestIsPortListened(t *testing.T) {
 		listened bool
 	}
 
-	listens := []string{"80", "1.1.1.1:443", "[2001:db8::a00:20ff:fea7:ccea]:8443"}
+	listens := []string{"80", "1.1.1.1:443", "[2001:db8::a00:20ff:fea7:ccea]:8443", "9443 https"}
 	items := []testData{
 		{"80", true},
 		{"443", true},
 		{"8443", true},
+		{"9443", true},
 		{"8080", false},
 	}
 
@@ -303,3 +304,50 @@ func TestRemoveClosingVhostTag(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSSLProtocol(t *testing.T) {
+	type testData struct {
+		args      []string
+		protocols string
+	}
+
+	items := []testData{
+		{[]string{"all", "-SSLv3"}, "TLSv1 TLSv1.1 TLSv1.2 TLSv1.3"},
+		{[]string{"-all", "+TLSv1.2", "+TLSv1.3"}, "TLSv1.2 TLSv1.3"},
+		{[]string{"TLSv1.2"}, "TLSv1.2"},
+		{[]string{"all", "-SSLv3", "-TLSv1", "-TLSv1.1"}, "TLSv1.2 TLSv1.3"},
+	}
+
+	for _, item := range items {
+		protocols := strings.Join(ParseSSLProtocol(item.args), " ")
+
+		if protocols != item.protocols {
+			t.Errorf("expected protocols %s, got %s", item.protocols, protocols)
+		}
+	}
+}
+
+func TestParseEnvVars(t *testing.T) {
+	content := `# envvars - default environment variables for apache2ctl
+unset HOME
+if [ "${APACHE_CONFDIR##/etc/apache2-}" != "${APACHE_CONFDIR}" ] ; then
+	SUFFIX="-${APACHE_CONFDIR##/etc/apache2-}"
+fi
+export APACHE_RUN_USER=www-data
+export APACHE_PID_FILE=/var/run/apache2$SUFFIX/apache2.pid
+export APACHE_LOG_DIR="/var/log/apache2${SUFFIX}"
+`
+	variables := ParseEnvVars(content)
+	expected := map[string]string{
+		"SUFFIX":          "",
+		"APACHE_RUN_USER": "www-data",
+		"APACHE_PID_FILE": "/var/run/apache2/apache2.pid",
+		"APACHE_LOG_DIR":  "/var/log/apache2",
+	}
+
+	for name, value := range expected {
+		if variables[name] != value {
+			t.Errorf("expected %s=%s, got %s", name, value, variables[name])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package nginx
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+// acmeChallengeRewrite is a pattern of the rewrite directive that stops processing of server level rewrite module
+// directives (return, rewrite, if) for challenge requests, so they reach the challenge location.
+var acmeChallengeRewrite = "^" + regexp.QuoteMeta(webserver.AcmeChallengePath)
+
+// EnableAcmeWebroot serves ACME HTTP-01 challenges of hosts with the server name from the webroot directory.
+// The "^~" location takes precedence over regular expression locations, redirects of the server level are skipped.
+func (m *NginxManager) EnableAcmeWebroot(serverName, webroot string) error {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, nHost := range nHosts {
+		if err := m.disableHostAcmeWebroot(&nHost); err != nil {
+			return err
+		}
+
+		locationDirectives := []*parser.NginxDirective{{Name: "root", Values: []string{m.parser.GetAbsPath(webroot)}}}
+
+		if err := m.parser.SetServerLocation(&nHost, []string{"^~", webserver.AcmeChallengePath}, locationDirectives); err != nil {
+			return err
+		}
+
+		rewrite := &parser.NginxDirective{
+			Name:          "rewrite",
+			Values:        []string{acmeChallengeRewrite, "$uri", "break"},
+			NewLineBefore: true,
+		}
+
+		if err := m.parser.AddServerDirectives(&nHost, []*parser.NginxDirective{rewrite}, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAcmeWebroot returns the webroot ACME HTTP-01 challenges of hosts with the server name are served from.
+// Empty webroot is returned if hosts have no challenge location.
+func (m *NginxManager) GetAcmeWebroot(serverName string) (string, error) {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, nHost := range nHosts {
+		location, err := m.parser.FindServerLocation(&nHost, webserver.AcmeChallengePath)
+		if err != nil {
+			return "", err
+		}
+
+		if location == nil {
+			continue
+		}
+
+		for _, entry := range location.GetEntries() {
+			if entry.Directive == nil {
+				continue
+			}
+
+			path := m.parser.GetAbsPath(entry.Directive.GetFirstValueStr())
+
+			switch entry.GetIdentifier() {
+			case "root":
+				return path, nil
+			case "alias":
+				challengeDir := webserver.GetAcmeChallengeDir("/")
+
+				if path = strings.TrimSuffix(path, "/"); strings.HasSuffix(path, challengeDir) {
+					return filepath.Join("/", strings.TrimSuffix(path, challengeDir)), nil
+				}
+			}
+		}
+
+		return "", fmt.Errorf("could not detect webroot of ACME challenge location of host '%s'", nHost.ServerName)
+	}
+
+	return "", nil
+}
+
+// DisableAcmeWebroot removes ACME HTTP-01 challenge location from hosts with the server name
+func (m *NginxManager) DisableAcmeWebroot(serverName string) error {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, nHost := range nHosts {
+		if err := m.disableHostAcmeWebroot(&nHost); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NginxManager) disableHostAcmeWebroot(nHost *parser.NginxHost) error {
+	if err := m.parser.RemoveServerLocation(nHost, webserver.AcmeChallengePath); err != nil {
+		return err
+	}
+
+	return m.parser.RemoveServerDirectives(nHost, func(directive *rawparser.Directive) bool {
+		return directive.Identifier == "rewrite" && directive.GetFirstValueStr() == acmeChallengeRewrite
+	})
+}
@@ -0,0 +1,116 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"golang.org/x/exp/slices"
+)
+
+const clientDNVariable = "$ssl_client_s_dn"
+
+var clientAuthDirectives = []string{"ssl_client_certificate", "ssl_verify_client", "ssl_verify_depth"}
+
+// EnableClientAuth enables client certificate authentication for ssl hosts with the server name.
+// nginx verifies client certificates at the server level only, so the path is not supported.
+func (m *NginxManager) EnableClientAuth(serverName string, config webserver.ClientAuthConfig) error {
+	if config.Path != "" {
+		return errors.New("nginx supports client certificate authentication for the whole host only")
+	}
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	directives := []*parser.NginxDirective{
+		{
+			Name:          "ssl_client_certificate",
+			Values:        []string{m.parser.GetAbsPath(config.CACertPath)},
+			NewLineBefore: true,
+		},
+		{
+			Name:          "ssl_verify_client",
+			Values:        []string{config.Verify},
+			NewLineBefore: true,
+		},
+	}
+
+	if config.Depth > 0 {
+		directives = append(directives, &parser.NginxDirective{
+			Name:          "ssl_verify_depth",
+			Values:        []string{strconv.Itoa(config.Depth)},
+			NewLineBefore: true,
+		})
+	}
+
+	directives[len(directives)-1].NewLineAfter = true
+
+	for _, nHost := range nHosts {
+		if err := m.parser.RemoveServerDirectives(&nHost, isClientAuthDirective); err != nil {
+			return err
+		}
+
+		if err := m.parser.AddServerDirectives(&nHost, directives, false); err != nil {
+			return err
+		}
+
+		if config.ForwardHeader == "" {
+			continue
+		}
+
+		if err := m.parser.RemoveHostProxyHeaders(&nHost, clientDNVariable); err != nil {
+			return err
+		}
+
+		if err := m.parser.SetHostProxyHeader(&nHost, config.ForwardHeader, clientDNVariable); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DisableClientAuth disables client certificate authentication for ssl hosts with the server name
+func (m *NginxManager) DisableClientAuth(serverName, path string) error {
+	if path != "" {
+		return errors.New("nginx supports client certificate authentication for the whole host only")
+	}
+
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, nHost := range nHosts {
+		if err := m.parser.RemoveServerDirectives(&nHost, isClientAuthDirective); err != nil {
+			return err
+		}
+
+		if err := m.parser.RemoveHostProxyHeaders(&nHost, clientDNVariable); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isClientAuthDirective(directive *rawparser.Directive) bool {
+	return slices.Contains(clientAuthDirectives, directive.Identifier)
+}
@@ -0,0 +1,115 @@
+package nginx
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+)
+
+// GetHeaders returns response headers effective for hosts. All hosts are used if serverName is empty.
+func (m *NginxManager) GetHeaders(serverName string) ([]webserver.HostHeaders, error) {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	var hostsHeaders []webserver.HostHeaders
+
+	for _, nHost := range nHosts {
+		directives, err := m.parser.GetHostDirectives(&nHost, "add_header")
+		if err != nil {
+			return nil, err
+		}
+
+		hostHeaders := webserver.HostHeaders{Host: nHost.Host}
+
+		for _, directive := range directives {
+			values := directive.GetExpressions()
+
+			if len(values) < 2 {
+				continue
+			}
+
+			hostHeaders.Headers = append(hostHeaders.Headers, headers.Header{
+				Name:  values[0],
+				Value: headers.Unquote(values[1]),
+			})
+		}
+
+		hostsHeaders = append(hostsHeaders, hostHeaders)
+	}
+
+	return hostsHeaders, nil
+}
+
+// SetHeaders sets response headers for hosts with the server name. Headers are sent for all response codes.
+func (m *NginxManager) SetHeaders(serverName string, hostHeaders []headers.Header) error {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, header := range hostHeaders {
+		if err := header.Validate(); err != nil {
+			return err
+		}
+
+		for _, nHost := range nHosts {
+			if headers.IsSecureOnly(header.Name) && !nHost.Ssl {
+				continue
+			}
+
+			if err := m.parser.SetHostHeader(&nHost, header.Name, []string{headers.Quote(header.Value), "always"}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnsetHeaders removes response headers from hosts with the server name
+func (m *NginxManager) UnsetHeaders(serverName string, names []string) error {
+	nHosts, err := m.getNginxHostsWithServerName(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find hosts with server name '%s'", serverName)
+	}
+
+	for _, name := range names {
+		for _, nHost := range nHosts {
+			if err := m.parser.RemoveHostHeader(&nHost, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getNginxHostsWithServerName returns all hosts with the server name. All hosts are returned if serverName is empty.
+func (m *NginxManager) getNginxHostsWithServerName(serverName string) ([]parser.NginxHost, error) {
+	nHosts, err := m.parser.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []parser.NginxHost
+
+	for _, nHost := range nHosts {
+		if serverName == "" || nHost.ServerName == serverName {
+			result = append(result, nHost)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package nginx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/nginxcli"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	FeatureHttp2      = "http2"
+	FeatureHttp3      = "http3"
+	FeatureSslPreread = "ssl_preread"
+)
+
+// featureModules contains nginx modules required by the features
+var featureModules = map[string][]string{
+	FeatureHttp2:      {"http_v2_module"},
+	FeatureHttp3:      {"http_v3_module"},
+	FeatureSslPreread: {"stream_module", "stream_ssl_preread_module"},
+}
+
+// Info describes nginx build, its paths and modules available for the configuration
+type Info struct {
+	nginxcli.BuildInfo
+	ServerRoot    string          `json:"serverRoot"`
+	ConfigFile    string          `json:"configFile"`
+	LoadedModules []string        `json:"loadedModules"`
+	Features      map[string]bool `json:"features"`
+}
+
+// GetInfo returns nginx build configuration, paths and available features
+func (m *NginxManager) GetInfo() (Info, error) {
+	if m.buildInfo == nil {
+		buildInfo, err := m.nginxCli.GetBuildInfo()
+		if err != nil {
+			return Info{}, fmt.Errorf("could not get nginx build configuration: %v", err)
+		}
+
+		m.buildInfo = &buildInfo
+	}
+
+	info := Info{
+		BuildInfo:     *m.buildInfo,
+		ServerRoot:    m.parser.GetServerRoot(),
+		ConfigFile:    m.parser.GetConfigRoot(),
+		LoadedModules: m.getLoadedModules(),
+		Features:      make(map[string]bool),
+	}
+
+	for feature := range featureModules {
+		info.Features[feature] = m.IsFeatureAvailable(feature)
+	}
+
+	return info, nil
+}
+
+// IsModuleAvailable checks if the module is built into nginx statically or loaded by load_module directive.
+// All modules are considered available if nginx build configuration is unknown.
+func (m *NginxManager) IsModuleAvailable(module string) bool {
+	if m.buildInfo == nil {
+		return true
+	}
+
+	return m.buildInfo.HasModule(module) || slices.Contains(m.getLoadedModules(), module)
+}
+
+// IsFeatureAvailable checks if all modules required by the feature are available
+func (m *NginxManager) IsFeatureAvailable(feature string) bool {
+	for _, module := range featureModules[feature] {
+		if !m.IsModuleAvailable(module) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *NginxManager) getLoadedModules() []string {
+	var modules []string
+
+	for _, path := range m.parser.GetLoadModules() {
+		modules = append(modules, nginxcli.GetModuleNameFromPath(path))
+	}
+
+	sort.Strings(modules)
+
+	return modules
+}
+
+// lintMissingModules checks that directives do not use features of modules that nginx is built without
+func (m *NginxManager) lintMissingModules() []webserver.Finding {
+	var findings []webserver.Finding
+
+	if m.buildInfo == nil {
+		return nil
+	}
+
+	for _, directive := range m.parser.FindDirectives("listen") {
+		for _, value := range directive.GetExpressions() {
+			feature := ""
+
+			switch value {
+			case "http2":
+				feature = FeatureHttp2
+			case "quic":
+				feature = FeatureHttp3
+			}
+
+			if feature == "" || m.IsFeatureAvailable(feature) {
+				continue
+			}
+
+			findings = append(findings, webserver.Finding{
+				Severity: webserver.SeverityError,
+				Code:     webserver.LintMissingModule,
+				Message:  fmt.Sprintf("'listen %s %s' requires %s that is not available in nginx", directive.GetFirstValueStr(), value, strings.Join(featureModules[feature], ", ")),
+				File:     directive.Pos.Filename,
+				Line:     directive.Pos.Line,
+			})
+		}
+	}
+
+	for _, feature := range []string{FeatureHttp2, FeatureHttp3, FeatureSslPreread} {
+		if m.IsFeatureAvailable(feature) {
+			continue
+		}
+
+		for _, directive := range m.parser.FindDirectives(feature) {
+			if directive.GetFirstValueStr() != "on" {
+				continue
+			}
+
+			findings = append(findings, webserver.Finding{
+				Severity: webserver.SeverityError,
+				Code:     webserver.LintMissingModule,
+				Message:  fmt.Sprintf("'%s on' requires %s that is not available in nginx", feature, strings.Join(featureModules[feature], ", ")),
+				File:     directive.Pos.Filename,
+				Line:     directive.Pos.Line,
+			})
+		}
+	}
+
+	return findings
+}
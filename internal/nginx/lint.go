@@ -0,0 +1,143 @@
+package nginx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"golang.org/x/exp/slices"
+)
+
+const backupFileExt = ".back"
+
+// Lint analyses parsed nginx configuration and returns found problems
+func (m *NginxManager) Lint() ([]webserver.Finding, error) {
+	nHosts, err := m.parser.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var lintHosts []webserver.LintHost
+	var findings []webserver.Finding
+
+	for _, nHost := range nHosts {
+		lintHost, err := m.getLintHost(&nHost)
+		if err != nil {
+			return nil, err
+		}
+
+		lintHosts = append(lintHosts, lintHost)
+
+		sslFindings, err := m.lintSslDirective(&nHost, lintHost.Line)
+		if err != nil {
+			return nil, err
+		}
+
+		findings = append(findings, sslFindings...)
+	}
+
+	findings = append(findings, webserver.LintHosts(lintHosts, m.options.Get(webserverOptions.Root))...)
+
+	includeFindings, err := m.lintIncludedBackupFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	findings = append(findings, includeFindings...)
+	findings = append(findings, m.lintMissingModules()...)
+	webserver.SortFindings(findings)
+
+	return findings, nil
+}
+
+func (m *NginxManager) getLintHost(nHost *parser.NginxHost) (webserver.LintHost, error) {
+	certDirectives, err := m.parser.GetHostDirectives(nHost, "ssl_certificate")
+	if err != nil {
+		return webserver.LintHost{}, err
+	}
+
+	var certificates []string
+
+	for _, certDirective := range certDirectives {
+		certificates = append(certificates, certDirective.GetFirstValueStr())
+	}
+
+	return webserver.LintHost{
+		Host:         nHost.Host,
+		Line:         m.parser.GetHostLine(nHost),
+		Certificates: certificates,
+	}, nil
+}
+
+// lintSslDirective checks that deprecated "ssl on" directive does not conflict with "listen ... ssl"
+func (m *NginxManager) lintSslDirective(nHost *parser.NginxHost, line int) ([]webserver.Finding, error) {
+	var findings []webserver.Finding
+
+	sslDirectives, err := m.parser.GetHostDirectives(nHost, "ssl")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sslDirectives) == 0 || sslDirectives[len(sslDirectives)-1].GetFirstValueStr() != "on" {
+		return nil, nil
+	}
+
+	listenDirectives, err := m.parser.GetHostDirectives(nHost, "listen")
+	if err != nil {
+		return nil, err
+	}
+
+	sslDirective := sslDirectives[len(sslDirectives)-1]
+
+	for _, listenDirective := range listenDirectives {
+		finding := webserver.Finding{
+			Code:       webserver.LintSslDirectiveConflict,
+			File:       sslDirective.Pos.Filename,
+			Line:       sslDirective.Pos.Line,
+			ServerName: nHost.ServerName,
+		}
+		listen := listenDirective.GetFirstValueStr()
+
+		if slices.Contains(listenDirective.GetExpressions(), "ssl") {
+			finding.Severity = webserver.SeverityWarning
+			finding.Message = fmt.Sprintf("deprecated 'ssl on' is used together with 'listen %s ssl', remove 'ssl on'", listen)
+		} else {
+			finding.Severity = webserver.SeverityError
+			finding.Message = fmt.Sprintf("'ssl on' forces ssl for non-ssl 'listen %s'", listen)
+		}
+
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// lintIncludedBackupFiles checks that reverter backup files are not loaded by include globs
+func (m *NginxManager) lintIncludedBackupFiles() ([]webserver.Finding, error) {
+	var findings []webserver.Finding
+
+	for _, include := range m.parser.GetIncludes() {
+		files, err := m.parser.GetIncludedFiles(include)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if !strings.HasSuffix(file, backupFileExt) {
+				continue
+			}
+
+			findings = append(findings, webserver.Finding{
+				Severity: webserver.SeverityError,
+				Code:     webserver.LintBackupFileIncluded,
+				Message:  fmt.Sprintf("backup file '%s' is loaded by 'include %s'", file, include.GetFirstValueStr()),
+				File:     include.Pos.Filename,
+				Line:     include.Pos.Line,
+			})
+		}
+	}
+
+	return findings, nil
+}
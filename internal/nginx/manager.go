@@ -9,12 +9,18 @@ import (
 	"github.com/r2dtools/webmng/internal/nginx/nginxcli"
 	nginxoptions "github.com/r2dtools/webmng/internal/nginx/options"
 	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+	"github.com/r2dtools/webmng/pkg/certificate"
 	"github.com/r2dtools/webmng/pkg/logger"
 	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/r2dtools/webmng/pkg/webserver"
 	"github.com/r2dtools/webmng/pkg/webserver/hostmanager"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
 	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
 	"github.com/r2dtools/webmng/pkg/webserver/reverter"
+	"github.com/r2dtools/webmng/pkg/webserver/staging"
 	"github.com/unknwon/com"
 	"golang.org/x/exp/slices"
 )
@@ -33,6 +39,14 @@ type NginxManager struct {
 	logger   logger.LoggerInterface
 	options  options.Options
 	reverter reverter.Reverter
+	// buildInfo is nil if nginx build configuration could not be detected
+	buildInfo   *nginxcli.BuildInfo
+	runner      runner.Runner
+	portChecker procstat.PortChecker
+	// changes contains files and symlinks changed besides the configuration, they are deferred in staged mode
+	changes *staging.Changes
+	// service is detected on the first use
+	service webserver.ServiceController
 }
 
 func (m *NginxManager) GetHosts() ([]webserver.Host, error) {
@@ -57,21 +71,46 @@ func (m *NginxManager) GetVersion() (string, error) {
 	return m.nginxCli.GetVersion()
 }
 
+// GetOpenSSLVersion returns OpenSSL version from nginx build configuration
+func (m *NginxManager) GetOpenSSLVersion() (string, error) {
+	if m.buildInfo == nil {
+		return "", nil
+	}
+
+	return m.buildInfo.OpenSSLVersion, nil
+}
+
 func (m *NginxManager) CheckConfiguration() error {
 	return m.nginxCli.TestConfiguration()
 }
 
-func (m *NginxManager) Restart() error {
-	return m.nginxCli.Restart()
+func (m *NginxManager) DeployCertificate(serverName, certPath, certKeyPath, chainPath, fullChainPath string) error {
+	return m.DeployCertificates(serverName, []webserver.Certificate{
+		{
+			CertPath:      certPath,
+			KeyPath:       certKeyPath,
+			ChainPath:     chainPath,
+			FullChainPath: fullChainPath,
+		},
+	})
 }
 
-func (m *NginxManager) DeployCertificate(serverName, certPath, certKeyPath, chainPath, fullChainPath string) error {
-	if fullChainPath == "" {
-		return errors.New("nginx requires fullchain-path to deploy a certificate")
+// DeployCertificates deploys certificates with different key types to hosts with the server name.
+// Host certificates with the same key types as deployed ones are replaced, others are left untouched.
+func (m *NginxManager) DeployCertificates(serverName string, certificates []webserver.Certificate) error {
+	for _, cert := range certificates {
+		if cert.FullChainPath == "" {
+			return errors.New("nginx requires fullchain-path to deploy a certificate")
+		}
+
+		if cert.KeyPath == "" {
+			return errors.New("nginx requires cert key path to deploy a certificate")
+		}
 	}
 
-	if certKeyPath == "" {
-		return errors.New("nginx requires cert key path to deploy a certificate")
+	keyTypes, err := webserver.GetCertificatesKeyTypes(certificates)
+	if err != nil {
+		return err
 	}
 
 	hosts, err := m.getNginxHostsByServerName(serverName)
@@ -85,12 +124,12 @@ func (m *NginxManager) DeployCertificate(serverName, certPath, certKeyPath, chai
 
 	for _, host := range hosts {
 		if !host.Ssl {
-			if err := m.makeSslHost(&host, certKeyPath, fullChainPath); err != nil {
+			if err := m.makeSslHost(&host); err != nil {
 				return err
 			}
 		}
 
-		if err = m.deployCertificateToHost(&host, certKeyPath, fullChainPath); err != nil {
+		if err = m.deployCertificatesToHost(&host, certificates, keyTypes); err != nil {
 			return err
 		}
 	}
@@ -98,12 +137,15 @@ func (m *NginxManager) DeployCertificate(serverName, certPath, certKeyPath, chai
 	return nil
 }
 
-func (m *NginxManager) makeSslHost(host *parser.NginxHost, certKeyPath, fullChainPath string) error {
+func (m *NginxManager) makeSslHost(host *parser.NginxHost) error {
 	httpsPort := m.options.Get(webserverOptions.HttpsPort)
 
-	ipv6Info, err := m.getIpv6Info(httpsPort)
-	var ipv4Block, ipv6Block *parser.NginxDirective
+	// the https port could be bound by another process, e.g. apache behind nginx
+	if err := m.portChecker.Check("", httpsPort); err != nil {
+		return err
+	}
 
+	ipv6Info, err := m.getIpv6Info(httpsPort)
 	if err != nil {
 		return err
 	}
@@ -120,8 +162,10 @@ func (m *NginxManager) makeSslHost(host *parser.NginxHost, certKeyPath, fullChai
 		m.parser.AddServerDirectives(host, listenBlock, true)
 	}
 
+	var sslBlock []*parser.NginxDirective
+
 	if host.IsIpv6Enabled() {
-		ipv6Block = &parser.NginxDirective{
+		ipv6Block := &parser.NginxDirective{
 			Name:          "listen",
 			Values:        []string{fmt.Sprintf("[::]:%s", httpsPort), "ssl"},
 			NewLineBefore: true,
@@ -131,32 +175,24 @@ func (m *NginxManager) makeSslHost(host *parser.NginxHost, certKeyPath, fullChai
 		if !ipv6Info.isIpv6OnlyPresent {
 			ipv6Block.AddValues("ipv6only=on")
 		}
+
+		sslBlock = append(sslBlock, ipv6Block)
 	}
 
 	if host.IsIpv4Enabled() {
-		ipv4Block = &parser.NginxDirective{
+		sslBlock = append(sslBlock, &parser.NginxDirective{
 			Name:          "listen",
 			Values:        []string{httpsPort, " ", "ssl"},
 			NewLineBefore: true,
-		}
+		})
 	}
 
-	sslBlock := []*parser.NginxDirective{
-		ipv6Block,
-		ipv4Block,
-		{
-			Name:          "ssl_certificate_key",
-			Values:        []string{certKeyPath},
-			NewLineBefore: true,
-		},
-		{
-			Name:          "ssl_certificate",
-			Values:        []string{fullChainPath},
-			NewLineBefore: true,
-			NewLineAfter:  true,
-		},
+	if len(sslBlock) == 0 {
+		return nil
 	}
 
+	sslBlock[len(sslBlock)-1].NewLineAfter = true
+
 	if err := m.parser.AddServerDirectives(host, sslBlock, false); err != nil {
 		return err
 	}
@@ -166,43 +202,146 @@ func (m *NginxManager) makeSslHost(host *parser.NginxHost, certKeyPath, fullChai
 	return nil
 }
 
-func (m *NginxManager) deployCertificateToHost(host *parser.NginxHost, certKeyPath, fullChainPath string) error {
-	certDirectives := []*parser.NginxDirective{
-		{
-			Name:          "ssl_certificate_key",
-			Values:        []string{certKeyPath},
-			NewLineBefore: true,
-		},
-		{
-			Name:          "ssl_certificate",
-			Values:        []string{fullChainPath},
-			NewLineBefore: true,
-			NewLineAfter:  true,
-		},
+// deployCertificatesToHost replaces certificate and key pairs of the host which have the same key types as deployed certificates.
+// Pairs with unreadable certificates are replaced as well.
+func (m *NginxManager) deployCertificatesToHost(host *parser.NginxHost, certificates []webserver.Certificate, keyTypes []string) error {
+	certDirectives, err := m.parser.GetServerDirectives(host, "ssl_certificate")
+	if err != nil {
+		return err
+	}
+
+	keyDirectives, err := m.parser.GetServerDirectives(host, "ssl_certificate_key")
+	if err != nil {
+		return err
 	}
 
-	return m.parser.UpdateOrAddServerDirectives(host, certDirectives, false)
+	var directivesToRemove []*rawparser.Directive
+
+	for i, certDirective := range certDirectives {
+		keyType, err := certificate.LoadKeyType(m.parser.GetAbsPath(certDirective.GetFirstValueStr()))
+		if err == nil && !slices.Contains(keyTypes, keyType) {
+			continue
+		}
+
+		directivesToRemove = append(directivesToRemove, certDirective)
+
+		if i < len(keyDirectives) {
+			directivesToRemove = append(directivesToRemove, keyDirectives[i])
+		}
+	}
+
+	// keys without certificates
+	if len(keyDirectives) > len(certDirectives) {
+		directivesToRemove = append(directivesToRemove, keyDirectives[len(certDirectives):]...)
+	}
+
+	err = m.parser.RemoveServerDirectives(host, func(directive *rawparser.Directive) bool {
+		return slices.Contains(directivesToRemove, directive)
+	})
+	if err != nil {
+		return err
+	}
+
+	var certBlock []*parser.NginxDirective
+
+	for _, cert := range certificates {
+		certBlock = append(certBlock,
+			&parser.NginxDirective{
+				Name:          "ssl_certificate_key",
+				Values:        []string{cert.KeyPath},
+				NewLineBefore: true,
+			},
+			&parser.NginxDirective{
+				Name:          "ssl_certificate",
+				Values:        []string{cert.FullChainPath},
+				NewLineBefore: true,
+			},
+		)
+	}
+
+	certBlock[len(certBlock)-1].NewLineAfter = true
+
+	return m.parser.AddServerDirectives(host, certBlock, false)
 }
 
 func (m *NginxManager) EnableHost(host *webserver.Host) error {
 	return nil
 }
 
+// Lock acquires the instance lock and parses the configuration again
+func (m *NginxManager) Lock(mode lock.Mode) (*lock.Lock, error) {
+	instanceLock, err := lock.AcquireInstance(m.options, webserver.Nginx, m.parser.GetServerRoot(), m.parser.GetConfigRoot(), mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.parser.Parse(); err != nil {
+		instanceLock.Release()
+
+		return nil, err
+	}
+
+	return instanceLock, nil
+}
+
 func (m *NginxManager) CommitChanges() error {
 	return m.reverter.Commit()
 }
 
 func (m *NginxManager) RollbackChanges() error {
+	m.changes.Clear()
+
 	return m.reverter.Rollback()
 }
 
+// GetChanges returns files and symlinks changed besides the configuration
+func (m *NginxManager) GetChanges() *staging.Changes {
+	return m.changes
+}
+
+// GetReverter returns the reverter used to roll back changes made outside of the configuration, e.g. certificate symlinks
+func (m *NginxManager) GetReverter() reverter.Reverter {
+	return m.reverter
+}
+
 func (m *NginxManager) SaveChanges() error {
 	changedFiles := m.parser.GetChangedFiles()
 	if err := m.reverter.BackupFiles(changedFiles); err != nil {
 		return err
 	}
 
-	return m.parser.Dump()
+	if !webserverOptions.IsStaged(m.options) {
+		return m.parser.Dump()
+	}
+
+	contents, err := m.parser.DumpContents()
+	if err != nil {
+		return err
+	}
+
+	// the stapling chain file could be deferred too, so the staged test must read its staged copy
+	return staging.Apply(m.parser.GetServerRoot(), contents, m.changes, []string{"include", "ssl_trusted_certificate"}, m.testStagedConfiguration)
+}
+
+// testStagedConfiguration tests the staged copy of the configuration.
+// Prefix is moved to the stage only if it is inside of the server root, otherwise prefix relative paths, e.g. logs, would not exist.
+func (m *NginxManager) testStagedConfiguration(stage *staging.Stage) error {
+	configFile, err := stage.GetPath(m.parser.GetConfigRoot())
+	if err != nil {
+		return err
+	}
+
+	prefix := m.options.Get(nginxoptions.Prefix)
+
+	if prefix == "" && m.buildInfo != nil {
+		prefix = m.buildInfo.Prefix
+	}
+
+	if stagedPrefix, err := stage.GetPath(prefix); prefix != "" && err == nil {
+		prefix = stagedPrefix
+	}
+
+	return m.nginxCli.TestConfigurationAt(prefix, configFile)
 }
 
 func (m *NginxManager) getNginxHostsByServerName(serverName string) ([]parser.NginxHost, error) {
@@ -274,18 +413,24 @@ func (m *NginxManager) getIpv6Info(port string) (ipv6Info, error) {
 func GetNginxManager(params map[string]string, logger logger.LoggerInterface) (*NginxManager, error) {
 	options := nginxoptions.GetOptions(params)
 
-	nginxCli, err := nginxcli.GetNginxCli()
+	return getNginxManager(options, runner.GetExecRunner(webserverOptions.GetCmdTimeout(options)), logger)
+}
+
+func getNginxManager(options options.Options, cmdRunner runner.Runner, logger logger.LoggerInterface) (*NginxManager, error) {
+	nginxCli, err := getNginxCli(options, cmdRunner)
 	if err != nil {
 		return nil, err
 	}
 
-	serverRootDirectory := options.Get(nginxoptions.ServerRoot)
-	parser, err := parser.GetParser(serverRootDirectory, logger)
+	root := options.Get(webserverOptions.Root)
+	serverRootDirectory, configFile, buildInfo := getServerPaths(nginxCli, options, logger)
+	parser, err := parser.GetParser(root, serverRootDirectory, configFile, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	enabledHostConfigDirectory, err := getEnabledHostConfigDirectory(serverRootDirectory)
+	enabledDir := webserverOptions.GetRootedPath(root, options.Get(webserverOptions.EnabledDir))
+	enabledHostConfigDirectory, err := getEnabledHostConfigDirectory(serverRootDirectory, enabledDir)
 	if err != nil {
 		return nil, err
 	}
@@ -295,18 +440,119 @@ func GetNginxManager(params map[string]string, logger logger.LoggerInterface) (*
 		return nil, err
 	}
 
+	// nginx hosts configs are not created, the directory is checked so a misconfigured instance is reported early
+	availableDir := webserverOptions.GetRootedPath(root, options.Get(webserverOptions.AvailableDir))
+	if availableDir != "" && !com.IsDir(availableDir) {
+		return nil, fmt.Errorf("available hosts configuration directory '%s' does not exist", availableDir)
+	}
+
 	manager := NginxManager{
-		nginxCli: nginxCli,
-		parser:   parser,
-		logger:   logger,
-		options:  options,
-		reverter: reverter.GetConfigReveter(defaultHostManager, logger),
+		nginxCli:  nginxCli,
+		parser:    parser,
+		logger:    logger,
+		options:   options,
+		reverter:  reverter.GetConfigReveter(defaultHostManager, logger),
+		buildInfo: buildInfo,
+		runner:    cmdRunner,
+		changes:   staging.GetChanges(webserverOptions.IsStaged(options)),
 	}
+	manager.portChecker = procstat.GetPortChecker(procstat.GetPortCheckMode(options), &manager, logger)
 
 	return &manager, nil
 }
 
-func getEnabledHostConfigDirectory(serverRootDir string) (string, error) {
+// getServerPaths returns nginx root directory and main config file resolved against the alternate root.
+// Paths not specified by options are detected from nginx build configuration.
+func getServerPaths(nginxCli nginxcli.NginxCli, options options.Options, logger logger.LoggerInterface) (string, string, *nginxcli.BuildInfo) {
+	root := options.Get(webserverOptions.Root)
+	serverRoot := webserverOptions.GetRootedPath(root, options.Get(nginxoptions.ServerRoot))
+	configFile := webserverOptions.GetRootedPath(root, options.Get(webserverOptions.ServerConfig))
+	defaultServerRoot := webserverOptions.GetRootedPath(root, nginxoptions.DefaultServerRoot)
+	buildInfo, err := nginxCli.GetBuildInfo()
+
+	if err != nil {
+		logger.Debug("could not get nginx build configuration: %v", err)
+
+		if serverRoot == "" {
+			serverRoot = defaultServerRoot
+		}
+
+		return serverRoot, configFile, nil
+	}
+
+	if serverRoot == "" && configFile == "" {
+		confPath := buildInfo.GetConfPath(options.Get(nginxoptions.Prefix))
+
+		if confPath = webserverOptions.GetRootedPath(root, confPath); com.IsFile(confPath) {
+			configFile = confPath
+		}
+	}
+
+	if serverRoot == "" {
+		if filepath.IsAbs(configFile) {
+			serverRoot = filepath.Dir(configFile)
+		} else {
+			serverRoot = defaultServerRoot
+		}
+	}
+
+	return serverRoot, configFile, &buildInfo
+}
+
+// GetInstancePaths returns server root and main config file of the nginx instance defined by options, as they are detected by the manager
+func GetInstancePaths(params map[string]string) (string, string, error) {
+	options := nginxoptions.GetOptions(params)
+
+	return getInstancePaths(options, runner.GetExecRunner(webserverOptions.GetCmdTimeout(options)), logger.NilLogger{})
+}
+
+func getInstancePaths(options options.Options, cmdRunner runner.Runner, logger logger.LoggerInterface) (string, string, error) {
+	nginxCli, err := getNginxCli(options, cmdRunner)
+	if err != nil {
+		return "", "", err
+	}
+
+	serverRoot, configFile, _ := getServerPaths(nginxCli, options, logger)
+
+	return parser.GetPaths(serverRoot, configFile)
+}
+
+// GetNginxCli returns cli of the nginx instance defined by options. Offline cli is returned in offline mode.
+func GetNginxCli(params map[string]string) (nginxcli.NginxCli, error) {
+	options := nginxoptions.GetOptions(params)
+
+	return getNginxCli(options, runner.GetExecRunner(webserverOptions.GetCmdTimeout(options)))
+}
+
+func getNginxCli(options options.Options, cmdRunner runner.Runner) (nginxcli.NginxCli, error) {
+	if webserverOptions.IsOffline(options) {
+		return nginxcli.GetOfflineNginxCli(
+			options.Get(webserverOptions.ServerVersion),
+			webserverOptions.GetList(options.Get(webserverOptions.Modules)),
+			options.Get(webserverOptions.CheckCmd),
+			options.Get(webserverOptions.ReloadCmd),
+			cmdRunner,
+		), nil
+	}
+
+	return nginxcli.GetNginxCli(
+		options.Get(nginxoptions.Bin),
+		options.Get(nginxoptions.Prefix),
+		options.Get(webserverOptions.ServerConfig),
+		options.Get(webserverOptions.ReloadCmd),
+		cmdRunner,
+	)
+}
+
+func getEnabledHostConfigDirectory(serverRootDir, enabledDir string) (string, error) {
+	if enabledDir != "" {
+		if !com.IsDir(enabledDir) {
+			return "", fmt.Errorf("enabled hosts configuration directory '%s' does not exist", enabledDir)
+		}
+
+		return enabledDir, nil
+	}
+
 	for _, dirName := range enabledHostConfigDirNames {
 		enabledHostDir := filepath.Join(serverRootDir, dirName)
 		if com.IsDir(enabledHostDir) {
@@ -0,0 +1,371 @@
+package nginx
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	nginxoptions "github.com/r2dtools/webmng/internal/nginx/options"
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/runner"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/stretchr/testify/assert"
+	"github.com/unknwon/com"
+)
+
+const nginxBuildInfo = `nginx version: nginx/1.24.0
+built with OpenSSL 3.0.2 15 Mar 2022
+configure arguments: --prefix=/usr/share/nginx --conf-path=/etc/nginx/nginx.conf --with-http_ssl_module
+`
+
+func TestNginxManagerWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), "server {\n    listen 443 ssl http2;\n    server_name example.com;\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().
+		On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo}).
+		On("/usr/sbin/nginx -v", runner.FakeResponse{Stderr: "nginx version: nginx/1.24.0"}).
+		On("/usr/sbin/nginx -t", runner.FakeResponse{Stderr: "syntax is ok"}).
+		On("/usr/sbin/nginx -s reload", runner.FakeResponse{Stderr: "signal process started"})
+
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+	})
+	manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+
+	hosts, err := manager.GetHostsByServerName("example.com")
+	assert.Nilf(t, err, "could not get hosts: %v", err)
+	assert.Equal(t, 1, len(hosts))
+
+	version, err := manager.GetVersion()
+	assert.Nilf(t, err, "could not get nginx version: %v", err)
+	assert.Equal(t, "1.24.0", version)
+	assert.False(t, manager.IsFeatureAvailable(FeatureHttp2), "nginx is built without http_v2_module")
+
+	openSSLVersion, err := manager.GetOpenSSLVersion()
+	assert.Nilf(t, err, "could not get OpenSSL version: %v", err)
+	assert.Equal(t, "3.0.2", openSSLVersion)
+
+	findings, err := manager.Lint()
+	assert.Nilf(t, err, "could not lint configuration: %v", err)
+	codes := make(map[string]bool)
+
+	for _, finding := range findings {
+		codes[finding.Code] = true
+	}
+
+	assert.True(t, codes["missing-module"], "missing http_v2_module must be reported")
+
+	assert.Nil(t, manager.CheckConfiguration())
+	assert.Nil(t, manager.Reload())
+	assert.Equal(t, []string{"/usr/sbin/nginx -V", "/usr/sbin/nginx -v", "/usr/sbin/nginx -t", "/usr/sbin/nginx -s reload"}, cmdRunner.GetCalls())
+
+	cmdRunner.On("/usr/sbin/nginx -t", runner.FakeResponse{Stderr: "unknown directive", ExitCode: 1})
+	cmdRunner.On("/usr/sbin/nginx -t", runner.FakeResponse{Stderr: "timed out", Err: runner.ErrTimeout})
+	err = manager.CheckConfiguration()
+	assert.NotNil(t, err, "invalid configuration must be reported")
+	assert.Contains(t, err.Error(), "unknown directive")
+	assert.ErrorIs(t, manager.CheckConfiguration(), runner.ErrTimeout)
+
+	options = nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot:    serverRoot,
+		webserverOptions.Offline:   "true",
+		webserverOptions.ReloadCmd: "systemctl reload nginx",
+	})
+	cmdRunner = runner.GetFakeRunner().On("systemctl reload nginx", runner.FakeResponse{})
+	manager, err = getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create offline nginx manager: %v", err)
+	assert.Nil(t, manager.CheckConfiguration())
+	assert.Nil(t, manager.Reload())
+	assert.Equal(t, []string{"systemctl reload nginx"}, cmdRunner.GetCalls(), "nginx binary must not be called in offline mode")
+	assert.NotNil(t, manager.Stop(false), "nginx process must not be controlled in offline mode")
+
+	options.Params[webserverOptions.AvailableDir] = filepath.Join(serverRoot, "sites-available")
+	_, err = getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.NotNil(t, err, "missing available hosts directory must be reported")
+}
+
+func TestNginxServiceWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "pid nginx.pid;\nevents {}\nhttp {\n    include sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), "server {\n    server_name example.com;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.pid"), strconv.Itoa(os.Getpid()))
+
+	cmdRunner := runner.GetFakeRunner().
+		On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: "configure arguments: --prefix=" + serverRoot}).
+		On("/usr/sbin/nginx -s quit", runner.FakeResponse{})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+	})
+	manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+
+	status, err := manager.Status()
+	assert.Nilf(t, err, "could not get nginx status: %v", err)
+	assert.Equal(t, webserver.ServiceStatus{Running: true, State: "running", Backend: webserver.ServiceBackendCtl, Pid: int32(os.Getpid())}, status, "pid directive must be used without systemd")
+	assert.Nil(t, manager.Stop(true))
+	assert.Equal(t, "/usr/sbin/nginx -s quit", cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-1])
+
+	cmdRunner.AddPath("systemctl", "/usr/bin/systemctl").
+		On("systemctl show --property=LoadState nginx.service", runner.FakeResponse{Stdout: "LoadState=loaded\n"}).
+		On("systemctl reload nginx.service", runner.FakeResponse{}).
+		On("systemctl kill --kill-who=main --signal=QUIT nginx.service", runner.FakeResponse{})
+	manager, err = getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+	assert.Nil(t, manager.Reload())
+	assert.Nil(t, manager.Stop(true))
+	assert.Equal(t, []string{"systemctl show --property=LoadState nginx.service", "systemctl reload nginx.service", "systemctl kill --kill-who=main --signal=QUIT nginx.service"}, cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-3:])
+}
+
+func TestNginxStagedApplyWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	hostConfig := "server {\n    listen 80;\n    server_name example.com;\n}\n"
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), hostConfig)
+
+	stagedTest := `^/usr/sbin/nginx -t -c /\S+/webmng-stage-\d+/nginx.conf -p /usr/share/nginx$`
+	cmdRunner := runner.GetFakeRunner().
+		On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo}).
+		OnMatch(stagedTest, runner.FakeResponse{Stderr: "unknown directive", ExitCode: 1})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+		webserverOptions.Staged: "true",
+	})
+	manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+
+	err = manager.SetHeaders("example.com", []headers.Header{{Name: "X-Frame-Options", Value: "DENY"}})
+	assert.Nilf(t, err, "could not set headers: %v", err)
+	err = manager.SaveChanges()
+	assert.NotNil(t, err, "invalid staged configuration must be reported")
+	assert.Contains(t, err.Error(), "unknown directive")
+	content, _ := os.ReadFile(filepath.Join(serverRoot, "sites-enabled", "example.com"))
+	assert.Equal(t, hostConfig, string(content), "live config must not be changed if staged configuration is invalid")
+
+	cmdRunner.OnMatch(stagedTest, runner.FakeResponse{Stderr: "syntax is ok"})
+	err = manager.SaveChanges()
+	assert.Nilf(t, err, "could not save changes: %v", err)
+	content, _ = os.ReadFile(filepath.Join(serverRoot, "sites-enabled", "example.com"))
+	assert.Contains(t, string(content), "add_header X-Frame-Options")
+
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.org"), "server {\n    listen 443 ssl;\n    server_name example.org;\n}\n")
+	manager, err = getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+	profile, err := tlsprofile.GetProfile("modern")
+	assert.Nilf(t, err, "could not get TLS profile: %v", err)
+	err = manager.ApplyTLSProfile("example.org", profile, true)
+	assert.Nilf(t, err, "could not apply TLS profile: %v", err)
+	snippetPath := filepath.Join(serverRoot, profile.GetSnippetFileName())
+	assert.False(t, com.IsFile(snippetPath), "TLS snippet must not be written before the staged test")
+
+	cmdRunner.OnMatch(stagedTest, runner.FakeResponse{Stderr: "unknown directive", ExitCode: 1})
+	assert.NotNil(t, manager.SaveChanges(), "invalid staged configuration must be reported")
+	assert.False(t, com.IsFile(snippetPath), "TLS snippet must not be written if staged configuration is invalid")
+
+	cmdRunner.OnMatch(stagedTest, runner.FakeResponse{Stderr: "syntax is ok"})
+	err = manager.SaveChanges()
+	assert.Nilf(t, err, "could not save changes: %v", err)
+	assert.True(t, com.IsFile(snippetPath), "TLS snippet must be written with the staged configuration")
+}
+
+func TestNginxTLSProfileWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	hostPath := filepath.Join(serverRoot, "sites-enabled", "example.com")
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, hostPath, "server {\n    listen 443 ssl;\n    server_name example.com;\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+	})
+
+	for _, name := range []string{tlsprofile.Intermediate, tlsprofile.Modern} {
+		manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not create nginx manager: %v", err)
+		profile, err := tlsprofile.GetProfile(name)
+		assert.Nilf(t, err, "could not get TLS profile: %v", err)
+		err = manager.ApplyTLSProfile("example.com", profile, false)
+		assert.Nilf(t, err, "could not apply %s TLS profile: %v", name, err)
+		err = manager.SaveChanges()
+		assert.Nilf(t, err, "could not save changes: %v", err)
+	}
+
+	content, _ := os.ReadFile(hostPath)
+	assert.NotContains(t, string(content), "ssl_ciphers", "ciphers of the intermediate profile must be removed")
+	assert.Contains(t, string(content), "ssl_protocols TLSv1.3;")
+	assert.Equal(t, 1, strings.Count(string(content), "ssl_protocols"))
+}
+
+func TestNginxLockWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), "server {\n    listen 80;\n    server_name example.com;\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot:   serverRoot,
+		nginxoptions.Bin:          "/usr/sbin/nginx",
+		webserverOptions.LockFile: filepath.Join(serverRoot, "webmng.lock"),
+		webserverOptions.LockWait: "false",
+	})
+	manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+
+	// configuration changed by the previous lock holder must be read again
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.org"), "server {\n    listen 80;\n    server_name example.org;\n}\n")
+	instanceLock, err := manager.Lock(lock.Exclusive)
+	assert.Nilf(t, err, "could not lock nginx instance: %v", err)
+	hosts, err := manager.GetHostsByServerName("example.org")
+	assert.Nilf(t, err, "could not get hosts: %v", err)
+	assert.Len(t, hosts, 1)
+
+	otherManager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+	_, err = otherManager.Lock(lock.Shared)
+	var heldErr lock.HeldError
+	assert.True(t, errors.As(err, &heldErr), "locked instance must be reported")
+	assert.Equal(t, os.Getpid(), heldErr.Pid)
+
+	assert.Nil(t, instanceLock.Release())
+	otherLock, err := otherManager.Lock(lock.Shared)
+	assert.Nilf(t, err, "could not lock released nginx instance: %v", err)
+	assert.Nil(t, otherLock.Release())
+}
+
+func TestNginxInstancePathsWithFakeRunner(t *testing.T) {
+	root := t.TempDir()
+	serverRoot := filepath.Join(root, "etc", "nginx")
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), "server {\n    listen 80;\n    server_name example.com;\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo})
+	detectedOptions := nginxoptions.GetOptions(map[string]string{
+		webserverOptions.Root: root,
+		nginxoptions.Bin:      "/usr/sbin/nginx",
+	})
+	detectedServerRoot, detectedConfigFile, err := getInstancePaths(detectedOptions, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not get nginx instance paths: %v", err)
+	assert.Equal(t, filepath.Join(serverRoot, "nginx.conf"), detectedConfigFile)
+	lockPath := lock.GetPath(detectedOptions, webserver.Nginx, detectedServerRoot, detectedConfigFile)
+
+	// the instance lock must not depend on how the instance paths are specified
+	for _, params := range []map[string]string{
+		{nginxoptions.ServerRoot: "/etc/nginx"},
+		{nginxoptions.ServerRoot: "/etc/nginx/"},
+		{webserverOptions.ServerConfig: "/etc/nginx/../nginx/nginx.conf"},
+	} {
+		params[webserverOptions.Root] = root
+		params[nginxoptions.Bin] = "/usr/sbin/nginx"
+		options := nginxoptions.GetOptions(params)
+
+		instanceServerRoot, instanceConfigFile, err := getInstancePaths(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not get nginx instance paths: %v", err)
+		assert.Equal(t, lockPath, lock.GetPath(options, webserver.Nginx, instanceServerRoot, instanceConfigFile), "%v must have the same lock file", params)
+
+		manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not create nginx manager: %v", err)
+		assert.Equal(t, lockPath, lock.GetPath(options, webserver.Nginx, manager.parser.GetServerRoot(), manager.parser.GetConfigRoot()), "manager and cli must lock the same file")
+	}
+}
+
+func TestNginxAcmeWebrootWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.com"), "server {\n    listen 80;\n    server_name example.com;\n}\n")
+	writeConfigFile(t, filepath.Join(serverRoot, "sites-enabled", "example.org"), "server {\n    listen 80;\n    server_name example.org;\n    location /.well-known/acme-challenge/ {\n        alias /srv/acme/.well-known/acme-challenge/;\n    }\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+	})
+	manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+	assert.Nilf(t, err, "could not create nginx manager: %v", err)
+
+	webroot, err := manager.GetAcmeWebroot("example.com")
+	assert.Nilf(t, err, "could not get ACME webroot: %v", err)
+	assert.Empty(t, webroot, "host has no challenge location")
+
+	err = manager.EnableAcmeWebroot("example.com", "/var/www/acme")
+	assert.Nilf(t, err, "could not enable ACME webroot: %v", err)
+	webroot, err = manager.GetAcmeWebroot("example.com")
+	assert.Nilf(t, err, "could not get ACME webroot: %v", err)
+	assert.Equal(t, "/var/www/acme", webroot)
+
+	webroot, err = manager.GetAcmeWebroot("example.org")
+	assert.Nilf(t, err, "could not get ACME webroot: %v", err)
+	assert.Equal(t, "/srv/acme", webroot, "webroot must be detected from the challenge alias")
+}
+
+func TestNginxDualCertificatesWithFakeRunner(t *testing.T) {
+	serverRoot := t.TempDir()
+	hostPath := filepath.Join(serverRoot, "sites-enabled", "example.com")
+	writeConfigFile(t, filepath.Join(serverRoot, "nginx.conf"), "events {}\nhttp {\n    include "+serverRoot+"/sites-enabled/*;\n}\n")
+	writeConfigFile(t, hostPath, "server {\n    listen 443 ssl;\n    server_name example.com;\n}\n")
+
+	cmdRunner := runner.GetFakeRunner().On("/usr/sbin/nginx -V", runner.FakeResponse{Stderr: nginxBuildInfo})
+	options := nginxoptions.GetOptions(map[string]string{
+		nginxoptions.ServerRoot: serverRoot,
+		nginxoptions.Bin:        "/usr/sbin/nginx",
+	})
+	rsaCert := writeIssuedCertificate(t, certificate.KeyTypeRSA, "RSA Intermediate")
+	ecdsaCert := writeIssuedCertificate(t, certificate.KeyTypeECDSA, "ECDSA Intermediate")
+	newEcdsaCert := writeIssuedCertificate(t, certificate.KeyTypeECDSA, "ECDSA Intermediate")
+
+	for _, certificates := range [][]webserver.Certificate{{rsaCert, ecdsaCert}, {newEcdsaCert}} {
+		manager, err := getNginxManager(options, cmdRunner, logger.NilLogger{})
+		assert.Nilf(t, err, "could not create nginx manager: %v", err)
+		err = manager.DeployCertificates("example.com", certificates)
+		assert.Nilf(t, err, "could not deploy certificates: %v", err)
+		err = manager.SaveChanges()
+		assert.Nilf(t, err, "could not save changes: %v", err)
+	}
+
+	content, _ := os.ReadFile(hostPath)
+	assert.Contains(t, string(content), "ssl_certificate "+rsaCert.FullChainPath+";", "certificate of other key type must be kept")
+	assert.Contains(t, string(content), "ssl_certificate_key "+rsaCert.KeyPath+";")
+	assert.Contains(t, string(content), "ssl_certificate "+newEcdsaCert.FullChainPath+";")
+	assert.NotContains(t, string(content), ecdsaCert.FullChainPath, "certificate of the same key type must be replaced")
+	assert.Equal(t, 2, strings.Count(string(content), "ssl_certificate "))
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	assert.Nilf(t, err, "could not create config directory: %v", err)
+	err = os.WriteFile(path, []byte(content), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+}
+
+// writeIssuedCertificate writes the certificate of the key type issued by its own intermediate CA
+func writeIssuedCertificate(t *testing.T, keyType, issuerName string) webserver.Certificate {
+	issuerKey, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate issuer key: %v", err)
+	issuer, err := certificate.GenerateCA(issuerName, issuerKey, 24*time.Hour)
+	assert.Nilf(t, err, "could not generate issuer: %v", err)
+
+	key, err := certificate.GenerateKey(keyType)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+	leaf, err := certificate.GenerateCertificate([]string{"example.com"}, key, 24*time.Hour, &certificate.Issuer{Certificate: issuer, Key: issuerKey})
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+
+	cert, err := webserver.WriteCertificate(t.TempDir(), key, []*x509.Certificate{leaf, issuer})
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+
+	return cert
+}
@@ -0,0 +1,186 @@
+package nginxcli
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	versionRegexp  = regexp.MustCompile(`nginx version: nginx/(\d+\.\d+\.\d+)`)
+	compilerRegexp = regexp.MustCompile(`(?m)^built by (.+)$`)
+	openSSLRegexp  = regexp.MustCompile(`(?m)^built with OpenSSL (\S+)`)
+)
+
+// BuildInfo is nginx build configuration reported by "nginx -V"
+type BuildInfo struct {
+	Version        string   `json:"version"`
+	Compiler       string   `json:"compiler"`
+	OpenSSLVersion string   `json:"opensslVersion"`
+	Prefix         string   `json:"prefix"`
+	ConfPath       string   `json:"confPath"`
+	PidPath        string   `json:"pidPath"`
+	Modules        []string `json:"modules"`
+	DynamicModules []string `json:"dynamicModules"`
+	AddedModules   []string `json:"addedModules"`
+	ConfigureArgs  []string `json:"configureArgs"`
+}
+
+// HasModule checks if the module is built into nginx statically
+func (i BuildInfo) HasModule(module string) bool {
+	return slices.Contains(i.Modules, module)
+}
+
+// GetConfPath returns absolute path of nginx config file. Relative conf path is resolved against the prefix.
+// Build prefix is used if prefix is empty.
+func (i BuildInfo) GetConfPath(prefix string) string {
+	return i.getAbsPath(i.ConfPath, prefix)
+}
+
+// GetPidPath returns absolute path of nginx pid file. Relative pid path is resolved against the prefix.
+func (i BuildInfo) GetPidPath(prefix string) string {
+	return i.getAbsPath(i.PidPath, prefix)
+}
+
+func (i BuildInfo) getAbsPath(path, prefix string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	if prefix == "" {
+		prefix = i.Prefix
+	}
+
+	return filepath.Join(prefix, path)
+}
+
+// GetBuildInfo returns nginx build configuration
+func (n NginxCli) GetBuildInfo() (BuildInfo, error) {
+	if n.offline {
+		if len(n.modules) == 0 {
+			return BuildInfo{}, errors.New("nginx modules are not specified in offline mode")
+		}
+
+		return BuildInfo{Version: n.version, Modules: n.modules}, nil
+	}
+
+	output, err := n.execCmd([]string{"-V"})
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	return ParseBuildInfo(string(output)), nil
+}
+
+// ParseBuildInfo parses "nginx -V" output
+func ParseBuildInfo(output string) BuildInfo {
+	var info BuildInfo
+
+	if matches := versionRegexp.FindStringSubmatch(output); matches != nil {
+		info.Version = matches[1]
+	}
+
+	if matches := compilerRegexp.FindStringSubmatch(output); matches != nil {
+		info.Compiler = strings.TrimSpace(matches[1])
+	}
+
+	if matches := openSSLRegexp.FindStringSubmatch(output); matches != nil {
+		info.OpenSSLVersion = matches[1]
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if args, ok := strings.CutPrefix(strings.TrimSpace(line), "configure arguments:"); ok {
+			info.ConfigureArgs = splitConfigureArgs(args)
+		}
+	}
+
+	for _, arg := range info.ConfigureArgs {
+		name, value, _ := strings.Cut(arg, "=")
+
+		switch {
+		case name == "--prefix":
+			info.Prefix = value
+		case name == "--conf-path":
+			info.ConfPath = value
+		case name == "--pid-path":
+			info.PidPath = value
+		case name == "--add-module" || name == "--add-dynamic-module":
+			info.AddedModules = append(info.AddedModules, value)
+		case strings.HasPrefix(name, "--with-"):
+			module := getModuleName(strings.TrimPrefix(name, "--with-"))
+
+			if module == "" {
+				continue
+			}
+
+			if value == "dynamic" {
+				info.DynamicModules = append(info.DynamicModules, module)
+			} else {
+				info.Modules = append(info.Modules, module)
+			}
+		}
+	}
+
+	return info
+}
+
+// GetModuleNameFromPath returns module name of load_module path, e.g. modules/ngx_stream_module.so -> stream_module
+func GetModuleNameFromPath(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".so")
+
+	return strings.TrimPrefix(name, "ngx_")
+}
+
+// getModuleName returns module name of --with-* option. --with-stream and --with-mail enable stream_module and mail_module.
+// Empty name is returned for options that are not modules, e.g. --with-cc-opt.
+func getModuleName(option string) string {
+	if option == "stream" || option == "mail" {
+		return option + "_module"
+	}
+
+	if strings.HasSuffix(option, "_module") {
+		return option
+	}
+
+	return ""
+}
+
+// splitConfigureArgs splits configure arguments taking into account quoted values: --with-cc-opt='-g -O2'
+func splitConfigureArgs(args string) []string {
+	var result []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, char := range args {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == ' ' || char == '\t':
+			if inArg {
+				result = append(result, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		result = append(result, arg.String())
+	}
+
+	return result
+}
@@ -0,0 +1,63 @@
+package nginxcli
+
+import (
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+const buildInfoOutput = `nginx version: nginx/1.25.3
+built by gcc 12.2.0 (Debian 12.2.0-14)
+built with OpenSSL 1.1.1f  31 Mar 2020
+TLS SNI support enabled
+configure arguments: --with-cc-opt='-g -O2 -fstack-protector-strong' --prefix=/usr/share/nginx --conf-path=/etc/nginx/nginx.conf --pid-path=/run/nginx.pid --with-compat --with-http_ssl_module --with-http_v2_module --with-stream=dynamic --with-stream_ssl_preread_module --add-dynamic-module=/build/nginx/debian/modules/http-geoip2
+`
+
+func TestParseBuildInfo(t *testing.T) {
+	info := ParseBuildInfo(buildInfoOutput)
+
+	assert.Equal(t, "1.25.3", info.Version)
+	assert.Equal(t, "gcc 12.2.0 (Debian 12.2.0-14)", info.Compiler)
+	assert.Equal(t, "1.1.1f", info.OpenSSLVersion)
+	assert.Equal(t, "/usr/share/nginx", info.Prefix)
+	assert.Equal(t, "/etc/nginx/nginx.conf", info.ConfPath)
+	assert.Equal(t, "/run/nginx.pid", info.PidPath)
+	assert.Equal(t, []string{"http_ssl_module", "http_v2_module", "stream_ssl_preread_module"}, info.Modules)
+	assert.Equal(t, []string{"stream_module"}, info.DynamicModules)
+	assert.Equal(t, []string{"/build/nginx/debian/modules/http-geoip2"}, info.AddedModules)
+	assert.Equal(t, "--with-cc-opt=-g -O2 -fstack-protector-strong", info.ConfigureArgs[0])
+	assert.True(t, info.HasModule("http_v2_module"))
+	assert.False(t, info.HasModule("http_v3_module"))
+}
+
+func TestGetConfPath(t *testing.T) {
+	info := BuildInfo{Prefix: "/usr/local/nginx", ConfPath: "conf/nginx.conf"}
+	assert.Equal(t, "/usr/local/nginx/conf/nginx.conf", info.GetConfPath(""))
+	assert.Equal(t, "/opt/site1/conf/nginx.conf", info.GetConfPath("/opt/site1"))
+
+	info = ParseBuildInfo(buildInfoOutput)
+	assert.Equal(t, "/etc/nginx/nginx.conf", info.GetConfPath("/opt/site1"))
+}
+
+func TestGetModuleNameFromPath(t *testing.T) {
+	assert.Equal(t, "stream_module", GetModuleNameFromPath("modules/ngx_stream_module.so"))
+	assert.Equal(t, "http_geoip2_module", GetModuleNameFromPath("/usr/lib/nginx/modules/ngx_http_geoip2_module.so"))
+}
+
+func TestOfflineBuildInfo(t *testing.T) {
+	cli := GetOfflineNginxCli("1.24.0", []string{"http_ssl_module", "http_v2_module"}, "", "", runner.GetFakeRunner())
+	info, err := cli.GetBuildInfo()
+	assert.Nilf(t, err, "could not get offline build info: %v", err)
+	assert.Equal(t, "1.24.0", info.Version)
+	assert.True(t, info.HasModule("http_v2_module"))
+	assert.Nil(t, cli.TestConfiguration())
+	assert.Nil(t, cli.Reload())
+
+	cli = GetOfflineNginxCli("", nil, "nginx-check", "", runner.GetFakeRunner())
+	_, err = cli.GetBuildInfo()
+	assert.NotNil(t, err, "build info must not be available without modules")
+	_, err = cli.GetVersion()
+	assert.NotNil(t, err, "version must not be available")
+	assert.NotNil(t, cli.TestConfiguration(), "check command must be run")
+}
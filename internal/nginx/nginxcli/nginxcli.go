@@ -1,22 +1,44 @@
 package nginxcli
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os/exec"
 	"regexp"
 	"strings"
+
+	"github.com/r2dtools/webmng/pkg/runner"
 )
 
 const (
 	nginxCmd = "nginx"
 )
 
+var errOffline = errors.New("nginx process could not be controlled in offline mode")
+
 type NginxCli struct {
+	runner  runner.Runner
 	binPath string
+	// args are passed to every nginx call: -p prefix, -c config file
+	args      []string
+	reloadCmd []string
+	// offline cli does not call nginx binary: version and modules are taken from options,
+	// configuration check and reload are delegated to user commands or skipped
+	offline  bool
+	version  string
+	modules  []string
+	checkCmd []string
 }
 
-func (n NginxCli) Restart() error {
+// Reload gracefully reloads nginx configuration
+func (n NginxCli) Reload() error {
+	if len(n.reloadCmd) > 0 {
+		return n.runCmd(n.reloadCmd)
+	}
+
+	if n.offline {
+		return nil
+	}
+
 	if _, err := n.execCmd([]string{"-s", "reload"}); err != nil {
 		return err
 	}
@@ -24,7 +46,43 @@ func (n NginxCli) Restart() error {
 	return nil
 }
 
+// Start starts nginx master process
+func (n NginxCli) Start() error {
+	if n.offline {
+		return errOffline
+	}
+
+	_, err := n.execCmd(nil)
+
+	return err
+}
+
+// Stop stops nginx. Graceful stop ("nginx -s quit") waits for the worker processes to serve current requests.
+func (n NginxCli) Stop(graceful bool) error {
+	if n.offline {
+		return errOffline
+	}
+
+	signal := "stop"
+
+	if graceful {
+		signal = "quit"
+	}
+
+	_, err := n.execCmd([]string{"-s", signal})
+
+	return err
+}
+
 func (n NginxCli) TestConfiguration() error {
+	if n.offline {
+		if len(n.checkCmd) > 0 {
+			return n.runCmd(n.checkCmd)
+		}
+
+		return nil
+	}
+
 	if _, err := n.execCmd([]string{"-t"}); err != nil {
 		return err
 	}
@@ -32,7 +90,33 @@ func (n NginxCli) TestConfiguration() error {
 	return nil
 }
 
+// TestConfigurationAt tests configuration of the other tree, e.g. staged copy of the configuration.
+// Prefix is not passed if it is empty.
+func (n NginxCli) TestConfigurationAt(prefix, configFile string) error {
+	if n.offline {
+		return errOffline
+	}
+
+	args := []string{"-t", "-c", configFile}
+
+	if prefix != "" {
+		args = append(args, "-p", prefix)
+	}
+
+	_, err := n.runner.Run(context.Background(), runner.Command{Name: n.binPath, Args: args})
+
+	return err
+}
+
 func (n NginxCli) GetVersion() (string, error) {
+	if n.offline {
+		if n.version == "" {
+			return "", errors.New("nginx version is not specified in offline mode")
+		}
+
+		return n.version, nil
+	}
+
 	params := []string{"-v"}
 	result, err := n.parseCmdOutput(params, `nginx/(\d+\.\d+\.\d+)`, 1)
 	if err != nil {
@@ -64,29 +148,66 @@ func (n NginxCli) parseCmdOutput(params []string, regexpStr string, captureGroup
 }
 
 func (n NginxCli) execCmd(params []string) ([]byte, error) {
-	cmd := exec.Command(n.binPath, params...)
-	output, err := cmd.CombinedOutput()
+	args := append(append([]string{}, n.args...), params...)
+	result, err := n.runner.Run(context.Background(), runner.Command{Name: n.binPath, Args: args})
 	if err != nil {
-		return nil, fmt.Errorf("%v: %s", err, output)
+		return nil, err
 	}
 
-	return output, nil
+	return result.Output(), nil
 }
 
-func GetNginxCli() (NginxCli, error) {
-	binPath, err := detectNginxCmd()
-	if err != nil {
-		return NginxCli{}, err
+// IsOffline checks if the cli works without nginx binary
+func (n NginxCli) IsOffline() bool {
+	return n.offline
+}
+
+func (n NginxCli) runCmd(command []string) error {
+	_, err := n.runner.Run(context.Background(), runner.Command{Name: command[0], Args: command[1:]})
+
+	return err
+}
+
+// GetNginxCli returns cli for the nginx instance running commands via cmdRunner. The binary is detected if binPath is empty.
+// If reloadCmd is not empty it is used to reload nginx instead of "nginx -s reload".
+func GetNginxCli(binPath, prefix, configFile, reloadCmd string, cmdRunner runner.Runner) (NginxCli, error) {
+	var err error
+
+	if binPath == "" {
+		if binPath, err = detectNginxCmd(cmdRunner); err != nil {
+			return NginxCli{}, err
+		}
+	}
+
+	cli := NginxCli{runner: cmdRunner, binPath: binPath, reloadCmd: strings.Fields(reloadCmd)}
+
+	if prefix != "" {
+		cli.args = append(cli.args, "-p", prefix)
+	}
+
+	if configFile != "" {
+		cli.args = append(cli.args, "-c", configFile)
 	}
 
-	return NginxCli{binPath: binPath}, nil
+	return cli, nil
 }
 
-func detectNginxCmd() (string, error) {
-	cmd := exec.Command("which", nginxCmd)
+// GetOfflineNginxCli returns cli that does not require nginx binary. The version and the modules are used instead of nginx build configuration.
+// checkCmd and reloadCmd are run instead of configuration check and reload if they are not empty.
+func GetOfflineNginxCli(version string, modules []string, checkCmd, reloadCmd string, cmdRunner runner.Runner) NginxCli {
+	return NginxCli{
+		runner:    cmdRunner,
+		offline:   true,
+		version:   version,
+		modules:   modules,
+		checkCmd:  strings.Fields(checkCmd),
+		reloadCmd: strings.Fields(reloadCmd),
+	}
+}
 
-	if output, err := cmd.Output(); err == nil {
-		return strings.TrimSpace(string(output)), nil
+func detectNginxCmd(cmdRunner runner.Runner) (string, error) {
+	if binPath, err := cmdRunner.LookPath(nginxCmd); err == nil {
+		return binPath, nil
 	}
 
 	return "", errors.New("could not find nginx binary")
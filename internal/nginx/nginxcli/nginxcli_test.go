@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/r2dtools/webmng/pkg/runner"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,10 +14,10 @@ func TestConfiguration(t *testing.T) {
 	assert.Nilf(t, err, "error while testing nginx configuration: %v", err)
 }
 
-func TestRestart(t *testing.T) {
+func TestReload(t *testing.T) {
 	cli := getNginxCli(t)
-	err := cli.Restart()
-	assert.Nilf(t, err, "error while restarting nginx: %v", err)
+	err := cli.Reload()
+	assert.Nilf(t, err, "error while reloading nginx: %v", err)
 }
 
 func TestGetVersion(t *testing.T) {
@@ -29,8 +30,49 @@ func TestGetVersion(t *testing.T) {
 	assert.Equal(t, true, reg.MatchString(version), "invalid version: %s", version)
 }
 
+func TestNginxCliWithFakeRunner(t *testing.T) {
+	cmdRunner := runner.GetFakeRunner().
+		AddPath("nginx", "/usr/sbin/nginx").
+		On("/usr/sbin/nginx -c /etc/nginx/nginx.conf -v", runner.FakeResponse{Stderr: "nginx version: nginx/1.25.3\n"}).
+		On("/usr/sbin/nginx -c /etc/nginx/nginx.conf -t", runner.FakeResponse{Stderr: "unknown directive \"foo\"", ExitCode: 1}).
+		On("/usr/sbin/nginx -c /etc/nginx/nginx.conf -s reload", runner.FakeResponse{})
+
+	cli, err := GetNginxCli("", "", "/etc/nginx/nginx.conf", "", cmdRunner)
+	assert.Nilf(t, err, "could not create nginx cli: %v", err)
+
+	version, err := cli.GetVersion()
+	assert.Nilf(t, err, "could not get nginx version: %v", err)
+	assert.Equal(t, "1.25.3", version)
+
+	err = cli.TestConfiguration()
+	assert.NotNil(t, err, "invalid configuration must be reported")
+	assert.Contains(t, err.Error(), "unknown directive")
+
+	err = cli.Reload()
+	assert.Nilf(t, err, "could not reload nginx: %v", err)
+
+	cli, err = GetNginxCli("/usr/sbin/nginx", "", "", "systemctl reload nginx", cmdRunner)
+	assert.Nilf(t, err, "could not create nginx cli: %v", err)
+	assert.NotNil(t, cli.Reload(), "reload command must be used")
+	assert.Equal(t, "systemctl reload nginx", cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-1])
+	cmdRunner.On("/usr/sbin/nginx -s quit", runner.FakeResponse{})
+	assert.Nil(t, cli.Stop(true))
+	assert.Equal(t, "/usr/sbin/nginx -s quit", cmdRunner.GetCalls()[len(cmdRunner.GetCalls())-1])
+
+	cmdRunner.On("/usr/sbin/nginx -t -c /tmp/stage/nginx.conf -p /tmp/stage/", runner.FakeResponse{})
+	err = cli.TestConfigurationAt("/tmp/stage/", "/tmp/stage/nginx.conf")
+	assert.Nilf(t, err, "staged configuration must be valid: %v", err)
+
+	_, err = GetNginxCli("", "", "", "", runner.GetFakeRunner())
+	assert.NotNil(t, err, "missing nginx binary must be reported")
+}
+
 func getNginxCli(t *testing.T) NginxCli {
-	cli, err := GetNginxCli()
+	if testing.Short() {
+		t.Skip("nginx is required")
+	}
+
+	cli, err := GetNginxCli("", "", "", "", runner.GetExecRunner(0))
 	assert.Nilf(t, err, "could not create nginx cli: %v", err)
 
 	return cli
@@ -4,8 +4,14 @@ import "github.com/r2dtools/webmng/pkg/options"
 import webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
 
 const (
-	// Nginx root directory
+	// Nginx root directory. It is detected from nginx build configuration if not specified.
 	ServerRoot = "server_root"
+	// DefaultServerRoot is used if nginx root directory could not be detected
+	DefaultServerRoot = "/etc/nginx"
+	// Bin is a path to nginx binary
+	Bin = "bin"
+	// Prefix is nginx prefix path passed with -p
+	Prefix = "prefix"
 )
 
 func GetOptions(params map[string]string) options.Options {
@@ -18,7 +24,9 @@ func GetOptions(params map[string]string) options.Options {
 // GetDefaults returns Nginx manager default options
 func GetDefaults() map[string]string {
 	defaults := make(map[string]string)
-	defaults[ServerRoot] = "/etc/nginx"
+	defaults[ServerRoot] = ""
+	defaults[Bin] = ""
+	defaults[Prefix] = ""
 
 	wsOptions := webserverOptions.GetDefaults()
 
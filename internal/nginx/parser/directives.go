@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+)
+
+// maxIncludeDepth limits nested includes expansion to protect against include loops
+const maxIncludeDepth = 10
+
+// GetHostDirectives returns directives effective for the host.
+// Directives of the server block (including files included into it) take precedence over the http context.
+func (p *Parser) GetHostDirectives(host *NginxHost, name string) ([]*rawparser.Directive, error) {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return nil, err
+	}
+
+	directives := findDirectives(p.expandIncludes(sBlock.block.GetEntries(), 0), name)
+
+	if len(directives) > 0 {
+		return directives, nil
+	}
+
+	httpBlock := p.getHttpBlock()
+	if httpBlock == nil {
+		return directives, nil
+	}
+
+	return findDirectives(p.expandIncludes(httpBlock.GetEntries(), 0), name), nil
+}
+
+// GetServerDirectives returns directives defined directly in the host server block
+func (p *Parser) GetServerDirectives(host *NginxHost, name string) ([]*rawparser.Directive, error) {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return findDirectives(sBlock.block.GetEntries(), name), nil
+}
+
+// GetHostLine returns line number of the host server block
+func (p *Parser) GetHostLine(host *NginxHost) int {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return 0
+	}
+
+	return sBlock.block.Pos.Line
+}
+
+// GetIncludes returns all include directives of the parsed configuration files
+func (p *Parser) GetIncludes() []*rawparser.Directive {
+	var includes []*rawparser.Directive
+
+	for _, config := range p.parsedFiles {
+		includes = append(includes, findDirectivesRecursively(config.Entries, includeDirective)...)
+	}
+
+	return includes
+}
+
+// FindDirectives returns directives with the name from all parsed configuration files
+func (p *Parser) FindDirectives(name string) []*rawparser.Directive {
+	var directives []*rawparser.Directive
+
+	for _, config := range p.parsedFiles {
+		directives = append(directives, findDirectivesRecursively(config.Entries, name)...)
+	}
+
+	return directives
+}
+
+// GetLoadModules returns paths of dynamic modules loaded by load_module directives of the main context
+func (p *Parser) GetLoadModules() []string {
+	config, ok := p.parsedFiles[p.configRoot]
+	if !ok {
+		return nil
+	}
+
+	var paths []string
+
+	for _, directive := range findDirectives(p.expandIncludes(config.Entries, 0), "load_module") {
+		paths = append(paths, directive.GetFirstValueStr())
+	}
+
+	return paths
+}
+
+// GetPidFile returns value of pid directive of the main context. Empty string is returned if the directive is not set.
+func (p *Parser) GetPidFile() string {
+	config, ok := p.parsedFiles[p.configRoot]
+	if !ok {
+		return ""
+	}
+
+	for _, directive := range findDirectives(p.expandIncludes(config.Entries, 0), "pid") {
+		return directive.GetFirstValueStr()
+	}
+
+	return ""
+}
+
+// GetServerRoot returns nginx root directory
+func (p *Parser) GetServerRoot() string {
+	return p.serverRoot
+}
+
+// GetConfigRoot returns path of nginx main config file
+func (p *Parser) GetConfigRoot() string {
+	return p.configRoot
+}
+
+// GetIncludedFiles returns files matched by the include directive pattern
+func (p *Parser) GetIncludedFiles(include *rawparser.Directive) ([]string, error) {
+	return filepath.Glob(p.GetAbsPath(include.GetFirstValueStr()))
+}
+
+// expandIncludes replaces include directives with the entries of the included files
+func (p *Parser) expandIncludes(entries []*rawparser.Entry, depth int) []*rawparser.Entry {
+	var result []*rawparser.Entry
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		if depth >= maxIncludeDepth || strings.ToLower(entry.GetIdentifier()) != includeDirective || entry.Directive == nil {
+			result = append(result, entry)
+			continue
+		}
+
+		files, err := p.GetIncludedFiles(entry.Directive)
+		if err != nil {
+			p.logger.Warning("invalid include pattern %s: %v", entry.Directive.GetFirstValueStr(), err)
+			continue
+		}
+
+		for _, file := range files {
+			config, ok := p.parsedFiles[file]
+			if !ok {
+				continue
+			}
+
+			result = append(result, p.expandIncludes(config.Entries, depth+1)...)
+		}
+	}
+
+	return result
+}
+
+func (p *Parser) getHttpBlock() *rawparser.BlockDirective {
+	config, ok := p.parsedFiles[p.configRoot]
+	if !ok {
+		return nil
+	}
+
+	for _, entry := range config.Entries {
+		if entry != nil && entry.BlockDirective != nil && strings.ToLower(entry.GetIdentifier()) == "http" {
+			return entry.BlockDirective
+		}
+	}
+
+	return nil
+}
+
+func findDirectives(entries []*rawparser.Entry, name string) []*rawparser.Directive {
+	var directives []*rawparser.Directive
+
+	for _, entry := range entries {
+		if entry != nil && entry.Directive != nil && strings.ToLower(entry.GetIdentifier()) == name {
+			directives = append(directives, entry.Directive)
+		}
+	}
+
+	return directives
+}
+
+func findDirectivesRecursively(entries []*rawparser.Entry, name string) []*rawparser.Directive {
+	directives := findDirectives(entries, name)
+
+	for _, entry := range entries {
+		if entry != nil && entry.BlockDirective != nil {
+			directives = append(directives, findDirectivesRecursively(entry.BlockDirective.GetEntries(), name)...)
+		}
+	}
+
+	return directives
+}
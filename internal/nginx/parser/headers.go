@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+)
+
+const (
+	addHeaderDirective      = "add_header"
+	proxySetHeaderDirective = "proxy_set_header"
+)
+
+// SetHostHeader replaces add_header directive of the response header in the host server block.
+// nginx does not inherit add_header into blocks defining their own add_header directives,
+// so the header is also set in nested blocks (locations) which define their own headers
+// and headers of the http context are copied into the server block if it has no headers.
+func (p *Parser) SetHostHeader(host *NginxHost, name string, values []string) error {
+	return p.setHostHeaderDirective(host, addHeaderDirective, name, values)
+}
+
+// RemoveHostHeader removes add_header directives of the response header from the host server block and its nested blocks.
+// Returns an error if the header is inherited from the http context.
+func (p *Parser) RemoveHostHeader(host *NginxHost, name string) error {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	p.removeHeaderDirectives(sBlock.block, addHeaderDirective, func(values []string) bool {
+		return strings.EqualFold(values[0], name)
+	})
+
+	if p.hasOwnDirectives(sBlock.block, addHeaderDirective) {
+		return nil
+	}
+
+	if httpBlock := p.getHttpBlock(); httpBlock != nil {
+		for _, directive := range findDirectives(p.expandIncludes(httpBlock.GetEntries(), 0), addHeaderDirective) {
+			if strings.EqualFold(directive.GetFirstValueStr(), name) {
+				return fmt.Errorf("header %s is inherited from the http context", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetHostProxyHeader replaces proxy_set_header directive of the request header passed to upstreams.
+// proxy_set_header is inherited the same way as add_header, see SetHostHeader.
+func (p *Parser) SetHostProxyHeader(host *NginxHost, name, value string) error {
+	return p.setHostHeaderDirective(host, proxySetHeaderDirective, name, []string{value})
+}
+
+// RemoveHostProxyHeaders removes proxy_set_header directives with the value from the host server block and its nested blocks
+func (p *Parser) RemoveHostProxyHeaders(host *NginxHost, value string) error {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	p.removeHeaderDirectives(sBlock.block, proxySetHeaderDirective, func(values []string) bool {
+		return len(values) > 1 && strings.Trim(values[1], `"'`) == value
+	})
+
+	return nil
+}
+
+func (p *Parser) setHostHeaderDirective(host *NginxHost, directiveName, name string, values []string) error {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	directive := &NginxDirective{
+		Name:         directiveName,
+		Values:       append([]string{name}, values...),
+		NewLineAfter: true,
+	}
+
+	if !p.hasOwnDirectives(sBlock.block, directiveName) {
+		if err := p.copyHttpDirectives(sBlock.block, directiveName, name); err != nil {
+			return err
+		}
+	}
+
+	isSameHeader := func(values []string) bool {
+		return strings.EqualFold(values[0], name)
+	}
+
+	for _, block := range append([]*rawparser.BlockDirective{sBlock.block}, p.getNestedBlocksWithDirectives(sBlock.block, directiveName)...) {
+		removeBlockDirectives(block, getHeaderFilter(directiveName, isSameHeader))
+
+		if err := p.addBlockDirectives(block, []*NginxDirective{directive}, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeHeaderDirectives removes header directives accepted by the filter from the block and its nested blocks
+func (p *Parser) removeHeaderDirectives(block *rawparser.BlockDirective, directiveName string, filter func(values []string) bool) {
+	for _, nestedBlock := range append([]*rawparser.BlockDirective{block}, p.getNestedBlocks(block)...) {
+		if removeBlockDirectives(nestedBlock, getHeaderFilter(directiveName, filter)) {
+			p.changedFiles[nestedBlock.Pos.Filename] = true
+		}
+	}
+}
+
+func (p *Parser) hasOwnDirectives(block *rawparser.BlockDirective, directiveName string) bool {
+	return len(findDirectives(p.expandIncludes(block.GetEntries(), 0), directiveName)) > 0
+}
+
+// copyHttpDirectives copies directives of the http context except the skipped header into the block
+func (p *Parser) copyHttpDirectives(block *rawparser.BlockDirective, directiveName, skipName string) error {
+	httpBlock := p.getHttpBlock()
+	if httpBlock == nil {
+		return nil
+	}
+
+	var directives []*NginxDirective
+
+	for _, directive := range findDirectives(p.expandIncludes(httpBlock.GetEntries(), 0), directiveName) {
+		if strings.EqualFold(directive.GetFirstValueStr(), skipName) {
+			continue
+		}
+
+		directives = append(directives, &NginxDirective{
+			Name:         directiveName,
+			Values:       directive.GetExpressions(),
+			NewLineAfter: true,
+		})
+	}
+
+	if len(directives) == 0 {
+		return nil
+	}
+
+	return p.addBlockDirectives(block, directives, false)
+}
+
+func (p *Parser) getNestedBlocks(block *rawparser.BlockDirective) []*rawparser.BlockDirective {
+	var blocks []*rawparser.BlockDirective
+
+	for _, entry := range block.GetEntries() {
+		if entry != nil && entry.BlockDirective != nil {
+			blocks = append(blocks, entry.BlockDirective)
+			blocks = append(blocks, p.getNestedBlocks(entry.BlockDirective)...)
+		}
+	}
+
+	return blocks
+}
+
+func (p *Parser) getNestedBlocksWithDirectives(block *rawparser.BlockDirective, directiveName string) []*rawparser.BlockDirective {
+	var blocks []*rawparser.BlockDirective
+
+	for _, nestedBlock := range p.getNestedBlocks(block) {
+		if p.hasOwnDirectives(nestedBlock, directiveName) {
+			blocks = append(blocks, nestedBlock)
+		}
+	}
+
+	return blocks
+}
+
+func getHeaderFilter(directiveName string, filter func(values []string) bool) func(directive *rawparser.Directive) bool {
+	return func(directive *rawparser.Directive) bool {
+		values := directive.GetExpressions()
+
+		return directive.Identifier == directiveName && len(values) > 0 && filter(values)
+	}
+}
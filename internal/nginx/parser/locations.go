@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+)
+
+const locationDirective = "location"
+
+// SetServerLocation adds the location block to the top of the host server block replacing the existing one with the same uri.
+// The location must not be defined in files included into the server block.
+func (p *Parser) SetServerLocation(host *NginxHost, parameters []string, directives []*NginxDirective) error {
+	if len(parameters) == 0 {
+		return fmt.Errorf("location uri is not specified")
+	}
+
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	uri := parameters[len(parameters)-1]
+
+	for _, entry := range p.expandIncludes(sBlock.block.GetEntries(), 0) {
+		if isLocationEntry(entry, uri) && entry.BlockDirective.Pos.Filename != sBlock.block.Pos.Filename {
+			return fmt.Errorf("location %s is already defined in %s", uri, entry.BlockDirective.Pos.Filename)
+		}
+	}
+
+	removeBlockLocations(sBlock.block, uri)
+
+	var locationParameters []*rawparser.Value
+
+	for _, parameter := range parameters {
+		locationParameters = append(locationParameters, &rawparser.Value{Expression: parameter})
+	}
+
+	location := &rawparser.BlockDirective{
+		// the position is used to track changed files
+		Pos:        sBlock.block.Pos,
+		Identifier: locationDirective,
+		Parameters: locationParameters,
+		Content:    &rawparser.BlockContent{},
+	}
+
+	for _, directive := range directives {
+		directive.NewLineBefore = true
+	}
+
+	if len(directives) > 0 {
+		directives[len(directives)-1].NewLineAfter = true
+	}
+
+	if err := p.addBlockDirectives(location, directives, false); err != nil {
+		return err
+	}
+
+	entry := &rawparser.Entry{
+		StartNewLines:  []string{"\n"},
+		BlockDirective: location,
+		EndNewLines:    []string{"\n"},
+	}
+	sBlock.block.Content.Entries = append([]*rawparser.Entry{entry}, sBlock.block.Content.Entries...)
+	p.changedFiles[sBlock.block.Pos.Filename] = true
+
+	return nil
+}
+
+// FindServerLocation returns the location block with the uri of the host server block including files included into it.
+// Nil is returned if the location is not defined.
+func (p *Parser) FindServerLocation(host *NginxHost, uri string) (*rawparser.BlockDirective, error) {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range p.expandIncludes(sBlock.block.GetEntries(), 0) {
+		if isLocationEntry(entry, uri) {
+			return entry.BlockDirective, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RemoveServerLocation removes location blocks with the uri defined directly in the host server block
+func (p *Parser) RemoveServerLocation(host *NginxHost, uri string) error {
+	sBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	if removeBlockLocations(sBlock.block, uri) {
+		p.changedFiles[sBlock.block.Pos.Filename] = true
+	}
+
+	return nil
+}
+
+func removeBlockLocations(block *rawparser.BlockDirective, uri string) bool {
+	if block.Content == nil {
+		return false
+	}
+
+	entries := block.Content.Entries[:0]
+	removed := false
+
+	for _, entry := range block.Content.Entries {
+		if isLocationEntry(entry, uri) {
+			removed = true
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	block.Content.Entries = entries
+
+	return removed
+}
+
+// isLocationEntry checks that the entry is a location block with the uri regardless of its modifier
+func isLocationEntry(entry *rawparser.Entry, uri string) bool {
+	if entry == nil || entry.BlockDirective == nil || entry.GetIdentifier() != locationDirective {
+		return false
+	}
+
+	parameters := entry.BlockDirective.GetParametersExpressions()
+
+	return len(parameters) > 0 && parameters[len(parameters)-1] == uri
+}
@@ -15,12 +15,14 @@ import (
 	"github.com/r2dtools/webmng/pkg/utils"
 	"github.com/r2dtools/webmng/pkg/webserver"
 	"github.com/r2dtools/webmng/pkg/webserver/host"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/unknwon/com"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
 
 var includeDirective = "include"
-var repeatableDirectives = []string{"server_name", "listen", includeDirective, "rewrite", "add_header"}
+var repeatableDirectives = []string{"server_name", "listen", includeDirective, "rewrite", "add_header", "proxy_set_header", "ssl_certificate", "ssl_certificate_key"}
 
 var errInvalidDirective = errors.New("entry is not a directive")
 
@@ -29,6 +31,8 @@ type Parser struct {
 	dumper      dumper.RawDumper
 	parsedFiles map[string]*rawparser.Config
 	logger      logger.LoggerInterface
+	// root is an alternate root directory absolute paths of the configuration are resolved against
+	root,
 	serverRoot,
 	configRoot string
 	changedFiles map[string]bool
@@ -131,6 +135,24 @@ func (p *Parser) GetChangedFiles() []string {
 }
 
 func (p *Parser) Dump() error {
+	contents, err := p.DumpContents()
+	if err != nil {
+		return err
+	}
+
+	for changedFile, content := range contents {
+		if err = os.WriteFile(changedFile, content, 0644); err != nil {
+			return fmt.Errorf("failed to write config %s: %v", changedFile, err)
+		}
+	}
+
+	return nil
+}
+
+// DumpContents returns contents of the changed files without writing them
+func (p *Parser) DumpContents() (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+
 	for changedFile := range p.changedFiles {
 		config, ok := p.parsedFiles[changedFile]
 
@@ -140,15 +162,13 @@ func (p *Parser) Dump() error {
 
 		content, err := p.dumper.Dump(config)
 		if err != nil {
-			return fmt.Errorf("failed to dump config %s: %v", changedFile, err)
+			return nil, fmt.Errorf("failed to dump config %s: %v", changedFile, err)
 		}
 
-		if err = os.WriteFile(changedFile, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write config %s: %v", changedFile, err)
-		}
+		contents[changedFile] = []byte(content)
 	}
 
-	return nil
+	return contents, nil
 }
 
 func (p *Parser) AddServerDirectives(host *NginxHost, directives []*NginxDirective, insertAtTop bool) error {
@@ -169,6 +189,44 @@ func (p *Parser) UpdateOrAddServerDirectives(host *NginxHost, directives []*Ngin
 	return p.updateOrAddBlockDirectives(serverBlock.block, directives, insertAtTop)
 }
 
+// RemoveServerDirectives removes directives accepted by the filter from the server block of the host.
+// Directives of nested blocks and included files are not affected.
+func (p *Parser) RemoveServerDirectives(host *NginxHost, filter func(directive *rawparser.Directive) bool) error {
+	serverBlock, err := p.getHostServerBlock(host)
+	if err != nil {
+		return err
+	}
+
+	if removeBlockDirectives(serverBlock.block, filter) {
+		p.changedFiles[serverBlock.block.Pos.Filename] = true
+	}
+
+	return nil
+}
+
+// removeBlockDirectives removes directives accepted by the filter from the block. Returns true if any directive is removed.
+func removeBlockDirectives(block *rawparser.BlockDirective, filter func(directive *rawparser.Directive) bool) bool {
+	if block.Content == nil {
+		return false
+	}
+
+	entries := block.Content.Entries[:0]
+	removed := false
+
+	for _, entry := range block.Content.Entries {
+		if entry != nil && entry.Directive != nil && filter(entry.Directive) {
+			removed = true
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	block.Content.Entries = entries
+
+	return removed
+}
+
 func (p *Parser) addBlockDirectives(block *rawparser.BlockDirective, directives []*NginxDirective, insertAtTop bool) error {
 	for _, directive := range directives {
 		if err := p.addBlockDirective(block, directive, insertAtTop); err != nil {
@@ -264,7 +322,7 @@ func (p *Parser) updateOrAddBlockDirective(blockDirective *rawparser.BlockDirect
 }
 
 func (p *Parser) parseRecursively(configFilePath string) error {
-	configFilePathAbs := p.getAbsPath(configFilePath)
+	configFilePathAbs := p.GetAbsPath(configFilePath)
 	trees, err := p.parseFilesByPath(configFilePathAbs, false)
 	if err != nil {
 		return err
@@ -359,9 +417,10 @@ func (p *Parser) parseFilesByPath(filePath string, override bool) ([]*rawparser.
 	return trees, nil
 }
 
-func (p *Parser) getAbsPath(path string) string {
+// GetAbsPath returns absolute path. Relative paths are resolved against the server root, absolute paths against the alternate root.
+func (p *Parser) GetAbsPath(path string) string {
 	if filepath.IsAbs(path) {
-		return filepath.Clean(path)
+		return webserverOptions.GetRootedPath(p.root, filepath.Clean(path))
 	}
 
 	return filepath.Clean(filepath.Join(p.serverRoot, path))
@@ -431,13 +490,10 @@ func (p *Parser) getHostServerBlock(host *NginxHost) (serverBlock, error) {
 	return sBlock, nil
 }
 
-func GetParser(serverRoot string, logger logger.LoggerInterface) (*Parser, error) {
-	serverRoot, err := filepath.Abs(serverRoot)
-	if err != nil {
-		return nil, err
-	}
-
-	configRoot, err := utils.FindAnyFilesInDirectory(serverRoot, []string{"nginx.conf"})
+// GetParser returns nginx parser. Main config file is detected in serverRoot if configFile is empty.
+// Absolute paths in the configuration are resolved against root if it is not empty. serverRoot and configFile must be already resolved.
+func GetParser(root, serverRoot, configFile string, logger logger.LoggerInterface) (*Parser, error) {
+	serverRoot, configRoot, err := GetPaths(serverRoot, configFile)
 	if err != nil {
 		return nil, err
 	}
@@ -451,6 +507,7 @@ func GetParser(serverRoot string, logger logger.LoggerInterface) (*Parser, error
 		rawParser:  rawParser,
 		dumper:     dumper.RawDumper{},
 		logger:     logger,
+		root:       root,
 		serverRoot: serverRoot,
 		configRoot: configRoot,
 	}
@@ -461,3 +518,34 @@ func GetParser(serverRoot string, logger logger.LoggerInterface) (*Parser, error
 
 	return &parser, nil
 }
+
+// GetPaths returns absolute server root and main config file as they are used by the parser
+func GetPaths(serverRoot, configFile string) (string, string, error) {
+	serverRoot, err := filepath.Abs(serverRoot)
+	if err != nil {
+		return "", "", err
+	}
+
+	configRoot, err := getConfigRoot(serverRoot, configFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	return serverRoot, configRoot, nil
+}
+
+func getConfigRoot(serverRoot, configFile string) (string, error) {
+	if configFile == "" {
+		return utils.FindAnyFilesInDirectory(serverRoot, []string{"nginx.conf"})
+	}
+
+	if !filepath.IsAbs(configFile) {
+		configFile = filepath.Join(serverRoot, configFile)
+	}
+
+	if !com.IsFile(configFile) {
+		return "", fmt.Errorf("nginx config file '%s' does not exist", configFile)
+	}
+
+	return configFile, nil
+}
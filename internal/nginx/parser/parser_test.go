@@ -29,8 +29,11 @@ func TestGetHosts(t *testing.T) {
 }
 
 func getNginxParser(t *testing.T) *Parser {
-	options := nginxoptions.GetOptions(nil)
-	parser, err := GetParser(options.Get(nginxoptions.ServerRoot), logger.NilLogger{})
+	if testing.Short() {
+		t.Skip("nginx configuration is required")
+	}
+
+	parser, err := GetParser("", nginxoptions.DefaultServerRoot, "", logger.NilLogger{})
 	assert.Nil(t, err, fmt.Sprintf("could not create nginx parser: %v", err))
 
 	return parser
@@ -0,0 +1,127 @@
+package nginx
+
+import (
+	"errors"
+	"path/filepath"
+
+	nginxoptions "github.com/r2dtools/webmng/internal/nginx/options"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/r2dtools/webmng/pkg/webserver/service"
+)
+
+const defaultPidFile = "/run/nginx.pid"
+
+// serviceUnits are systemd units of nginx
+var serviceUnits = []string{"nginx.service"}
+
+// Reload gracefully reloads nginx configuration
+func (m *NginxManager) Reload() error {
+	if m.nginxCli.IsOffline() {
+		return m.nginxCli.Reload()
+	}
+
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Reload()
+}
+
+func (m *NginxManager) Restart() error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Restart()
+}
+
+func (m *NginxManager) Start() error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Start()
+}
+
+// Stop stops nginx. Graceful stop waits for the worker processes to serve current requests.
+func (m *NginxManager) Stop(graceful bool) error {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return err
+	}
+
+	return controller.Stop(graceful)
+}
+
+func (m *NginxManager) Status() (webserver.ServiceStatus, error) {
+	controller, err := m.getServiceController()
+	if err != nil {
+		return webserver.ServiceStatus{}, err
+	}
+
+	return controller.Status()
+}
+
+// getServiceController returns systemd or ctl controller of nginx
+func (m *NginxManager) getServiceController() (webserver.ServiceController, error) {
+	if m.service != nil {
+		return m.service, nil
+	}
+
+	if m.nginxCli.IsOffline() {
+		return nil, errors.New("nginx process could not be controlled in offline mode")
+	}
+
+	units := serviceUnits
+
+	if unit := m.options.Get(webserverOptions.ServiceUnit); unit != "" {
+		units = []string{unit}
+	}
+
+	// QUIT is nginx graceful shutdown signal
+	ctl := service.GetCtlController(m.nginxCli, m.getPidFile())
+	controller, err := service.GetController(m.runner, m.options.Get(webserverOptions.ServiceBackend), units, "QUIT", ctl)
+	if err != nil {
+		return nil, err
+	}
+
+	// reload command is run by nginx cli
+	if m.options.Get(webserverOptions.ReloadCmd) != "" {
+		controller = service.WithReload(controller, m.nginxCli.Reload)
+	}
+
+	m.service = controller
+
+	return controller, nil
+}
+
+// getPidFile returns nginx pid file from options, pid directive or nginx build configuration
+func (m *NginxManager) getPidFile() string {
+	if pidFile := m.options.Get(webserverOptions.PidFile); pidFile != "" {
+		return pidFile
+	}
+
+	prefix := m.options.Get(nginxoptions.Prefix)
+
+	if prefix == "" && m.buildInfo != nil {
+		prefix = m.buildInfo.Prefix
+	}
+
+	if pidFile := m.parser.GetPidFile(); pidFile != "" {
+		if !filepath.IsAbs(pidFile) && prefix != "" {
+			pidFile = filepath.Join(prefix, pidFile)
+		}
+
+		return pidFile
+	}
+
+	if m.buildInfo != nil && m.buildInfo.PidPath != "" {
+		return m.buildInfo.GetPidPath(prefix)
+	}
+
+	return defaultPidFile
+}
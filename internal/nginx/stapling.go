@@ -0,0 +1,240 @@
+package nginx
+
+import (
+	"bufio"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/unknwon/com"
+	"golang.org/x/exp/slices"
+)
+
+const staplingChainFilePrefix = "webmng-stapling-"
+
+var resolvConfPath = "/etc/resolv.conf"
+
+// EnableStapling enables OCSP stapling for ssl hosts with the server name.
+// Stapling is not enabled if a host certificate has no OCSP responder URL.
+func (m *NginxManager) EnableStapling(serverName string, config webserver.StaplingConfig) error {
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, nHost := range nHosts {
+		tlsConfig, err := m.getHostTLSConfig(&nHost)
+		if err != nil {
+			return err
+		}
+
+		if len(tlsConfig.Certificates) == 0 {
+			return fmt.Errorf("host %s has no certificate", nHost.ServerName)
+		}
+
+		for _, files := range tlsConfig.Certificates {
+			if err := certificate.CheckOCSPServer(files.CertPath); err != nil {
+				return fmt.Errorf("could not enable OCSP stapling: %v", err)
+			}
+		}
+
+		trustedPath, err := m.getStaplingChainPath(nHost.ServerName, tlsConfig.Certificates, config.ChainPath)
+		if err != nil {
+			return err
+		}
+
+		resolvers, err := m.getStaplingResolvers(&nHost, config.Resolvers)
+		if err != nil {
+			return err
+		}
+
+		directives := []*parser.NginxDirective{
+			{
+				Name:          "ssl_stapling",
+				Values:        []string{"on"},
+				NewLineBefore: true,
+			},
+			{
+				Name:          "ssl_stapling_verify",
+				Values:        []string{"on"},
+				NewLineBefore: true,
+			},
+			{
+				Name:          "ssl_trusted_certificate",
+				Values:        []string{trustedPath},
+				NewLineBefore: true,
+			},
+		}
+
+		if len(resolvers) > 0 {
+			directives = append(directives, &parser.NginxDirective{
+				Name:          "resolver",
+				Values:        resolvers,
+				NewLineBefore: true,
+			})
+		}
+
+		directives[len(directives)-1].NewLineAfter = true
+
+		if err := m.parser.UpdateOrAddServerDirectives(&nHost, directives, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DisableStapling disables OCSP stapling for ssl hosts with the server name
+func (m *NginxManager) DisableStapling(serverName string) error {
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	for _, nHost := range nHosts {
+		err := m.parser.RemoveServerDirectives(&nHost, func(directive *rawparser.Directive) bool {
+			return directive.Identifier == "ssl_stapling_verify"
+		})
+		if err != nil {
+			return err
+		}
+
+		directives := []*parser.NginxDirective{
+			{
+				Name:          "ssl_stapling",
+				Values:        []string{"off"},
+				NewLineBefore: true,
+				NewLineAfter:  true,
+			},
+		}
+
+		if err := m.parser.UpdateOrAddServerDirectives(&nHost, directives, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getStaplingChainPath returns path of the issuer certificates used by ssl_stapling_verify.
+// If the chain path is not specified, certificates following the leaf ones are written to a separate file.
+func (m *NginxManager) getStaplingChainPath(serverName string, hostCertificates []webserver.CertificateFiles, chainPath string) (string, error) {
+	if chainPath != "" {
+		chainPath = m.parser.GetAbsPath(chainPath)
+
+		if _, err := certificate.LoadCertificates(chainPath); err != nil {
+			return "", fmt.Errorf("invalid certificate chain %s: %v", chainPath, err)
+		}
+
+		return chainPath, nil
+	}
+
+	var chain []*x509.Certificate
+
+	// RSA and ECDSA certificates could be issued by different intermediates
+	for _, files := range hostCertificates {
+		certificates, err := certificate.LoadCertificates(files.CertPath)
+		if err != nil {
+			return "", fmt.Errorf("could not load certificate %s: %v", files.CertPath, err)
+		}
+
+		if len(certificates) < 2 {
+			return "", fmt.Errorf("certificate %s does not contain issuer certificates, specify the certificate chain", files.CertPath)
+		}
+
+		for _, issuer := range certificates[1:] {
+			if !slices.ContainsFunc(chain, func(c *x509.Certificate) bool { return c.Equal(issuer) }) {
+				chain = append(chain, issuer)
+			}
+		}
+	}
+
+	chainPath = m.parser.GetAbsPath(staplingChainFilePrefix + serverName + ".pem")
+
+	if com.IsFile(chainPath) {
+		if err := m.reverter.BackupFile(chainPath); err != nil {
+			return "", err
+		}
+	} else {
+		m.reverter.AddFileToDeletion(chainPath)
+	}
+
+	if err := m.changes.WriteFile(chainPath, certificate.EncodeCertificates(chain)); err != nil {
+		return "", fmt.Errorf("could not write certificate chain %s: %v", chainPath, err)
+	}
+
+	return chainPath, nil
+}
+
+// getStaplingResolvers returns resolvers for the resolver directive.
+// Resolvers are not changed if they are not specified and the host already has an effective resolver.
+// Otherwise the system name servers are used.
+func (m *NginxManager) getStaplingResolvers(nHost *parser.NginxHost, resolvers []string) ([]string, error) {
+	if len(resolvers) == 0 {
+		directives, err := m.parser.GetHostDirectives(nHost, "resolver")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(directives) > 0 {
+			return nil, nil
+		}
+
+		if resolvers, err = getSystemResolvers(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("could not detect DNS resolvers for OCSP stapling, specify them explicitly")
+	}
+
+	var values []string
+
+	for _, resolver := range resolvers {
+		// nginx requires IPv6 addresses to be enclosed in square brackets
+		if ip := net.ParseIP(resolver); ip != nil && ip.To4() == nil {
+			resolver = "[" + resolver + "]"
+		}
+
+		values = append(values, resolver)
+	}
+
+	return values, nil
+}
+
+func getSystemResolvers() ([]string, error) {
+	file, err := os.Open(filepath.Clean(resolvConfPath))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var resolvers []string
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) > 1 && fields[0] == "nameserver" {
+			resolvers = append(resolvers, fields[1])
+		}
+	}
+
+	return resolvers, scanner.Err()
+}
@@ -0,0 +1,142 @@
+package nginx
+
+import (
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+var defaultSslProtocols = []string{"TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+
+// GetTLSConfigs returns effective TLS settings of ssl hosts. All ssl hosts are used if serverName is empty.
+func (m *NginxManager) GetTLSConfigs(serverName string) ([]webserver.TLSConfig, error) {
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []webserver.TLSConfig
+
+	for _, nHost := range nHosts {
+		config, err := m.getHostTLSConfig(&nHost)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
+
+func (m *NginxManager) getHostTLSConfig(nHost *parser.NginxHost) (webserver.TLSConfig, error) {
+	config := webserver.TLSConfig{
+		WebServer:      webserver.Nginx,
+		Host:           nHost.Host,
+		Protocols:      defaultSslProtocols,
+		SessionTickets: true,
+	}
+
+	values, err := m.getHostDirectiveValues(nHost, "ssl_protocols")
+	if err != nil {
+		return config, err
+	}
+
+	if len(values) > 0 {
+		config.Protocols = values
+	}
+
+	if config.Ciphers, err = m.getHostDirectiveValue(nHost, "ssl_ciphers"); err != nil {
+		return config, err
+	}
+
+	if config.PreferServerCiphers, err = m.isHostDirectiveOn(nHost, "ssl_prefer_server_ciphers", false); err != nil {
+		return config, err
+	}
+
+	if config.OcspStapling, err = m.isHostDirectiveOn(nHost, "ssl_stapling", false); err != nil {
+		return config, err
+	}
+
+	if config.SessionTickets, err = m.isHostDirectiveOn(nHost, "ssl_session_tickets", true); err != nil {
+		return config, err
+	}
+
+	if config.DHParamPath, err = m.getHostDirectiveValue(nHost, "ssl_dhparam"); err != nil {
+		return config, err
+	}
+
+	headers, err := m.parser.GetHostDirectives(nHost, "add_header")
+	if err != nil {
+		return config, err
+	}
+
+	for _, header := range headers {
+		expressions := header.GetExpressions()
+
+		if len(expressions) > 1 && strings.EqualFold(expressions[0], "Strict-Transport-Security") {
+			config.HstsHeader = strings.Trim(expressions[1], `"'`)
+		}
+	}
+
+	certificates, err := m.parser.GetHostDirectives(nHost, "ssl_certificate")
+	if err != nil {
+		return config, err
+	}
+
+	keys, err := m.parser.GetHostDirectives(nHost, "ssl_certificate_key")
+	if err != nil {
+		return config, err
+	}
+
+	for i, certificate := range certificates {
+		files := webserver.CertificateFiles{CertPath: m.parser.GetAbsPath(certificate.GetFirstValueStr())}
+
+		if i < len(keys) {
+			files.KeyPath = m.parser.GetAbsPath(keys[i].GetFirstValueStr())
+		}
+
+		config.Certificates = append(config.Certificates, files)
+	}
+
+	return config, nil
+}
+
+// getHostDirectiveValues returns values of the last effective directive
+func (m *NginxManager) getHostDirectiveValues(nHost *parser.NginxHost, name string) ([]string, error) {
+	directives, err := m.parser.GetHostDirectives(nHost, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(directives) == 0 {
+		return nil, nil
+	}
+
+	var values []string
+
+	for _, expression := range directives[len(directives)-1].GetExpressions() {
+		values = append(values, strings.Trim(expression, `"'`))
+	}
+
+	return values, nil
+}
+
+func (m *NginxManager) getHostDirectiveValue(nHost *parser.NginxHost, name string) (string, error) {
+	values, err := m.getHostDirectiveValues(nHost, name)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+
+	return values[0], nil
+}
+
+func (m *NginxManager) isHostDirectiveOn(nHost *parser.NginxHost, name string, def bool) (bool, error) {
+	value, err := m.getHostDirectiveValue(nHost, name)
+	if err != nil || value == "" {
+		return def, err
+	}
+
+	return strings.EqualFold(value, "on"), nil
+}
@@ -0,0 +1,119 @@
+package nginx
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/r2dtools/webmng/internal/nginx/parser"
+	"github.com/r2dtools/webmng/internal/nginx/rawparser"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/unknwon/com"
+	"golang.org/x/exp/slices"
+)
+
+// ApplyTLSProfile writes directives of the TLS profile to ssl hosts. All ssl hosts are used if serverName is empty.
+// If snippet is true, directives are written to the shared file which is included by the hosts.
+func (m *NginxManager) ApplyTLSProfile(serverName string, profile tlsprofile.Profile, snippet bool) error {
+	nHosts, err := m.getSslNginxHosts(serverName)
+	if err != nil {
+		return err
+	}
+
+	if len(nHosts) == 0 {
+		return fmt.Errorf("could not find ssl hosts with server name '%s'", serverName)
+	}
+
+	directives := profile.GetDirectives(webserver.Nginx, tlsprofile.PolicySettings, "")
+	var directiveNames []string
+
+	for _, directive := range tlsprofile.GetPolicyDirectives(webserver.Nginx) {
+		directiveNames = append(directiveNames, directive.Name)
+	}
+
+	var hostDirectives []*parser.NginxDirective
+
+	if snippet {
+		snippetPath, err := m.writeTLSSnippet(profile, directives)
+		if err != nil {
+			return err
+		}
+
+		hostDirectives = append(hostDirectives, &parser.NginxDirective{
+			Name:   "include",
+			Values: []string{snippetPath},
+		})
+	} else {
+		for _, directive := range directives {
+			hostDirectives = append(hostDirectives, &parser.NginxDirective{
+				Name:   directive.Name,
+				Values: directive.Values,
+			})
+		}
+	}
+
+	for _, hostDirective := range hostDirectives {
+		hostDirective.NewLineBefore = true
+	}
+
+	hostDirectives[len(hostDirectives)-1].NewLineAfter = true
+
+	// directives of the previously applied profile are replaced
+	isPolicyDirective := func(directive *rawparser.Directive) bool {
+		if directive.Identifier == "include" {
+			return strings.HasPrefix(filepath.Base(directive.GetFirstValueStr()), tlsprofile.SnippetFilePrefix)
+		}
+
+		return slices.Contains(directiveNames, directive.Identifier)
+	}
+
+	for _, nHost := range nHosts {
+		if err := m.parser.RemoveServerDirectives(&nHost, isPolicyDirective); err != nil {
+			return err
+		}
+
+		if err := m.parser.AddServerDirectives(&nHost, hostDirectives, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NginxManager) getSslNginxHosts(serverName string) ([]parser.NginxHost, error) {
+	nHosts, err := m.parser.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var sslHosts []parser.NginxHost
+
+	for _, nHost := range nHosts {
+		if nHost.Ssl && (serverName == "" || nHost.ServerName == serverName) {
+			sslHosts = append(sslHosts, nHost)
+		}
+	}
+
+	return sslHosts, nil
+}
+
+func (m *NginxManager) writeTLSSnippet(profile tlsprofile.Profile, directives []tlsprofile.Directive) (string, error) {
+	snippetPath := m.parser.GetAbsPath(profile.GetSnippetFileName())
+
+	if com.IsFile(snippetPath) {
+		if err := m.reverter.BackupFile(snippetPath); err != nil {
+			return "", err
+		}
+	} else {
+		m.reverter.AddFileToDeletion(snippetPath)
+	}
+
+	content := profile.GetSnippetContent(webserver.Nginx, directives)
+
+	if err := m.changes.WriteFile(snippetPath, []byte(content)); err != nil {
+		return "", fmt.Errorf("could not write TLS snippet %s: %v", snippetPath, err)
+	}
+
+	return snippetPath, nil
+}
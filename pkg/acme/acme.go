@@ -0,0 +1,333 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	DefaultDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	DefaultTimeout      = 5 * time.Minute
+	accountKeyFile      = "account.key"
+	stateDir            = "acme"
+	accountsDir         = "accounts"
+	certsDir            = "certificates"
+	userAgent           = "webmng"
+)
+
+// Config describes ACME server and where issued certificates and accounts are stored
+type Config struct {
+	// DirectoryURL is an ACME directory URL, Let's Encrypt production directory is used if empty
+	DirectoryURL string
+	// Email is an account contact
+	Email string
+	// StateDir is a webmng state directory keeping account keys and certificates
+	StateDir string
+	// CACertPath is a path of a CA bundle used to verify the ACME server, e.g. root of a local Pebble instance
+	CACertPath string
+	// KeyType is a certificate key type: rsa, ecdsa
+	KeyType string
+}
+
+// ChallengeProvider makes HTTP-01 challenge responses available to the ACME server
+type ChallengeProvider interface {
+	Present(token, keyAuth string) error
+	CleanUp(token string) error
+}
+
+// WebrootProvider serves HTTP-01 challenge responses from <webroot>/.well-known/acme-challenge/
+type WebrootProvider struct {
+	Webroot string
+}
+
+func (p WebrootProvider) Present(token, keyAuth string) error {
+	dir := webserver.GetAcmeChallengeDir(p.Webroot)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create challenge directory %s: %v", dir, err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, token), []byte(keyAuth), 0644)
+}
+
+func (p WebrootProvider) CleanUp(token string) error {
+	return os.Remove(filepath.Join(webserver.GetAcmeChallengeDir(p.Webroot), token))
+}
+
+// Client issues certificates with the registered ACME account
+type Client struct {
+	client *acme.Client
+	config Config
+}
+
+// GetClient returns ACME client with the account registered. The account key is created if it does not exist.
+func GetClient(ctx context.Context, config Config) (*Client, error) {
+	if config.DirectoryURL == "" {
+		config.DirectoryURL = DefaultDirectoryURL
+	}
+
+	if config.KeyType == "" {
+		config.KeyType = certificate.KeyTypeRSA
+	}
+
+	httpClient, err := getHTTPClient(config.CACertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	accountKey, err := getAccountKey(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: config.DirectoryURL,
+		HTTPClient:   httpClient,
+		UserAgent:    userAgent,
+	}
+
+	if _, err = client.GetReg(ctx, ""); errors.Is(err, acme.ErrNoAccount) {
+		account := &acme.Account{}
+
+		if config.Email != "" {
+			account.Contact = []string{"mailto:" + config.Email}
+		}
+
+		_, err = client.Register(ctx, account, acme.AcceptTOS)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not register ACME account at %s: %v", config.DirectoryURL, err)
+	}
+
+	return &Client{client: client, config: config}, nil
+}
+
+// Obtain issues a certificate for the names and stores it to a new directory <StateDir>/acme/certificates/<serverName>/<keyType>-<suffix>.
+// Files of previously issued certificates are never overwritten since hosts could reference them.
+func (c *Client) Obtain(ctx context.Context, serverName string, names []string, provider ChallengeProvider) (webserver.Certificate, error) {
+	ids, err := getAuthzIDs(names)
+	if err != nil {
+		return webserver.Certificate{}, err
+	}
+
+	order, err := c.client.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		return webserver.Certificate{}, fmt.Errorf("could not create order: %v", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err = c.authorize(ctx, authzURL, provider); err != nil {
+			return webserver.Certificate{}, err
+		}
+	}
+
+	if order, err = c.client.WaitOrder(ctx, order.URI); err != nil {
+		return webserver.Certificate{}, fmt.Errorf("order is not ready: %v", err)
+	}
+
+	key, err := certificate.GenerateKey(c.config.KeyType)
+	if err != nil {
+		return webserver.Certificate{}, err
+	}
+
+	csr, err := createCSR(names, key)
+	if err != nil {
+		return webserver.Certificate{}, err
+	}
+
+	ders, _, err := c.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return webserver.Certificate{}, fmt.Errorf("could not finalize order: %v", err)
+	}
+
+	var certificates []*x509.Certificate
+
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return webserver.Certificate{}, fmt.Errorf("could not parse issued certificate: %v", err)
+		}
+
+		certificates = append(certificates, cert)
+	}
+
+	dir, err := state.GetDir(c.config.StateDir, stateDir, certsDir, strings.ReplaceAll(serverName, "*", "_"))
+	if err != nil {
+		return webserver.Certificate{}, err
+	}
+
+	if dir, err = os.MkdirTemp(dir, c.config.KeyType+"-"); err != nil {
+		return webserver.Certificate{}, fmt.Errorf("could not create certificate directory: %v", err)
+	}
+
+	return webserver.WriteCertificate(dir, key, certificates)
+}
+
+// authorize completes HTTP-01 challenge of the pending authorization
+func (c *Client) authorize(ctx context.Context, authzURL string, provider ChallengeProvider) error {
+	authz, err := c.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("could not get authorization: %v", err)
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+
+	for _, authzChallenge := range authz.Challenges {
+		if authzChallenge.Type == "http-01" {
+			challenge = authzChallenge
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("ACME server offers no http-01 challenge for '%s'", authz.Identifier.Value)
+	}
+
+	keyAuth, err := c.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+
+	if err = provider.Present(challenge.Token, keyAuth); err != nil {
+		return fmt.Errorf("could not present http-01 challenge for '%s': %v", authz.Identifier.Value, err)
+	}
+	defer provider.CleanUp(challenge.Token)
+
+	if _, err = c.client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("could not accept http-01 challenge for '%s': %v", authz.Identifier.Value, err)
+	}
+
+	if _, err = c.client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("authorization for '%s' failed: %v", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// getAccountKey loads the account key of the directory from the state directory or creates a new one
+func getAccountKey(config Config) (crypto.Signer, error) {
+	directoryURL, err := url.Parse(config.DirectoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACME directory URL %s: %v", config.DirectoryURL, err)
+	}
+
+	dir, err := state.GetDir(config.StateDir, stateDir, accountsDir, strings.ReplaceAll(directoryURL.Host, ":", "_"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyPath := filepath.Join(dir, accountKeyFile)
+
+	if _, err = os.Stat(keyPath); err == nil {
+		key, err := certificate.LoadPrivateKey(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load ACME account key %s: %v", keyPath, err)
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported ACME account key %s", keyPath)
+		}
+
+		return signer, nil
+	}
+
+	key, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := certificate.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.WriteFile(keyPath, data, 0600); err != nil {
+		return nil, fmt.Errorf("could not write ACME account key %s: %v", keyPath, err)
+	}
+
+	return key, nil
+}
+
+func getHTTPClient(caCertPath string) (*http.Client, error) {
+	if caCertPath == "" {
+		return http.DefaultClient, nil
+	}
+
+	caCertificates, err := certificate.LoadCertificates(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ACME server CA %s: %v", caCertPath, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	for _, caCertificate := range caCertificates {
+		pool.AddCert(caCertificate)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// getAuthzIDs returns order identifiers, wildcard names are not supported by http-01 challenge
+func getAuthzIDs(names []string) ([]acme.AuthzID, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no certificate names specified")
+	}
+
+	var ids []acme.AuthzID
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "*.") {
+			return nil, fmt.Errorf("wildcard name '%s' could not be validated with http-01 challenge", name)
+		}
+
+		if net.ParseIP(name) != nil {
+			ids = append(ids, acme.AuthzID{Type: "ip", Value: name})
+		} else {
+			ids = append(ids, acme.AuthzID{Type: "dns", Value: name})
+		}
+	}
+
+	return ids, nil
+}
+
+func createCSR(names []string, key crypto.Signer) ([]byte, error) {
+	template := &x509.CertificateRequest{}
+	template.Subject.CommonName = names[0]
+
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	return x509.CreateCertificateRequest(rand.Reader, template, key)
+}
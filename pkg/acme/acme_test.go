@@ -0,0 +1,339 @@
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebrootProvider(t *testing.T) {
+	provider := WebrootProvider{Webroot: t.TempDir()}
+	tokenPath := filepath.Join(provider.Webroot, ".well-known", "acme-challenge", "token")
+
+	err := provider.Present("token", "token.thumbprint")
+	assert.Nilf(t, err, "could not present challenge: %v", err)
+
+	content, err := os.ReadFile(tokenPath)
+	assert.Nilf(t, err, "could not read challenge response: %v", err)
+	assert.Equal(t, "token.thumbprint", string(content))
+
+	err = provider.CleanUp("token")
+	assert.Nilf(t, err, "could not clean up challenge: %v", err)
+	assert.NoFileExists(t, tokenPath)
+}
+
+func TestGetAccountKey(t *testing.T) {
+	config := Config{DirectoryURL: "https://localhost:14000/dir", StateDir: t.TempDir()}
+
+	key, err := getAccountKey(config)
+	assert.Nilf(t, err, "could not create account key: %v", err)
+	assert.FileExists(t, filepath.Join(config.StateDir, "acme", "accounts", "localhost_14000", "account.key"))
+
+	loadedKey, err := getAccountKey(config)
+	assert.Nilf(t, err, "could not load account key: %v", err)
+	assert.Equal(t, key.Public(), loadedKey.Public(), "account key must be reused")
+}
+
+func TestGetAuthzIDs(t *testing.T) {
+	ids, err := getAuthzIDs([]string{"example.com", "192.168.0.1"})
+	assert.Nilf(t, err, "could not get identifiers: %v", err)
+	assert.Equal(t, "dns", ids[0].Type)
+	assert.Equal(t, "ip", ids[1].Type)
+
+	_, err = getAuthzIDs([]string{"*.example.com"})
+	assert.NotNil(t, err, "wildcard names must not be allowed")
+}
+
+func TestObtain(t *testing.T) {
+	webroot := t.TempDir()
+	server := getACMEServer(t, webroot)
+	defer server.Close()
+
+	caCertPath := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caCertPath, certificate.EncodeCertificates([]*x509.Certificate{server.Certificate()}), 0644)
+	assert.Nilf(t, err, "could not write ACME server CA: %v", err)
+
+	config := Config{
+		DirectoryURL: server.URL + "/directory",
+		Email:        "admin@example.com",
+		StateDir:     t.TempDir(),
+		CACertPath:   caCertPath,
+		KeyType:      certificate.KeyTypeECDSA,
+	}
+	ctx := context.Background()
+	client, err := GetClient(ctx, config)
+	assert.Nilf(t, err, "could not register ACME account: %v", err)
+	assert.Equal(t, []string{"mailto:admin@example.com"}, server.contact)
+
+	cert, err := client.Obtain(ctx, "example.com", []string{"example.com", "www.example.com"}, WebrootProvider{Webroot: webroot})
+	assert.Nilf(t, err, "could not obtain certificate: %v", err)
+	assert.Equal(t, filepath.Join(config.StateDir, "acme", "certificates", "example.com"), filepath.Dir(filepath.Dir(cert.KeyPath)))
+	assert.True(t, strings.HasPrefix(filepath.Base(filepath.Dir(cert.KeyPath)), "ecdsa-"), "certificate directory must be named after the key type")
+	assert.Nilf(t, certificate.CheckKeyPair(cert.CertPath, cert.KeyPath), "issued certificate must match the written key")
+
+	leaf, err := certificate.LoadLeafCertificate(cert.CertPath)
+	assert.Nilf(t, err, "could not load issued certificate: %v", err)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, leaf.DNSNames)
+	chain, err := certificate.LoadCertificates(cert.ChainPath)
+	assert.Nilf(t, err, "could not load issued chain: %v", err)
+	assert.Len(t, chain, 1)
+	assert.Nilf(t, leaf.CheckSignatureFrom(chain[0]), "certificate must be signed by the chain")
+	keyType, err := certificate.LoadKeyType(cert.CertPath)
+	assert.Nilf(t, err, "could not load key type: %v", err)
+	assert.Equal(t, certificate.KeyTypeECDSA, keyType)
+	assert.NoFileExists(t, filepath.Join(webserver.GetAcmeChallengeDir(webroot), server.token), "challenge response must be cleaned up")
+
+	// the registered account is reused and the previous certificate is kept
+	client, err = GetClient(ctx, config)
+	assert.Nilf(t, err, "could not get registered ACME account: %v", err)
+	assert.Equal(t, 1, server.registrations, "account must be registered once")
+	renewed, err := client.Obtain(ctx, "example.com", []string{"example.com"}, WebrootProvider{Webroot: webroot})
+	assert.Nilf(t, err, "could not renew certificate: %v", err)
+	assert.NotEqual(t, cert.CertPath, renewed.CertPath)
+	assert.FileExists(t, cert.CertPath)
+
+	// the certificate is not issued if the challenge response is not served
+	_, err = client.Obtain(ctx, "example.com", []string{"example.com"}, WebrootProvider{Webroot: t.TempDir()})
+	assert.NotNil(t, err, "failed authorization must be reported")
+}
+
+// acmeServer is a minimal RFC 8555 server for a single account and order at a time.
+// http-01 challenges are validated by reading the response from the webroot instead of requesting the host.
+type acmeServer struct {
+	*httptest.Server
+	webroot       string
+	issuer        certificate.Issuer
+	mu            sync.Mutex
+	thumbprint    string
+	contact       []string
+	registrations int
+	names         []string
+	token         string
+	authzStatus   string
+	orderStatus   string
+	certificate   []byte
+}
+
+type acmeRequest struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+}
+
+func getACMEServer(t *testing.T, webroot string) *acmeServer {
+	key, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate CA key: %v", err)
+	caCert, err := certificate.GenerateCA("ACME test CA", key, time.Hour)
+	assert.Nilf(t, err, "could not generate CA: %v", err)
+
+	server := &acmeServer{webroot: webroot, issuer: certificate.Issuer{Certificate: caCert, Key: key}}
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(server.handle))
+
+	return server
+}
+
+func (s *acmeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Replay-Nonce", base64.RawURLEncoding.EncodeToString([]byte(time.Now().String())))
+
+	if r.URL.Path == "/directory" {
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+
+		return
+	}
+
+	if r.URL.Path == "/nonce" {
+		return
+	}
+
+	var request acmeRequest
+	var protected struct {
+		JWK map[string]string `json:"jwk"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeError(w, "malformed", err.Error())
+		return
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(request.Payload)
+	protectedData, _ := base64.RawURLEncoding.DecodeString(request.Protected)
+	json.Unmarshal(protectedData, &protected)
+
+	switch r.URL.Path {
+	case "/account":
+		var account struct {
+			Contact            []string `json:"contact"`
+			OnlyReturnExisting bool     `json:"onlyReturnExisting"`
+		}
+		json.Unmarshal(payload, &account)
+		thumbprint := getJWKThumbprint(protected.JWK)
+
+		if account.OnlyReturnExisting && thumbprint != s.thumbprint {
+			s.writeError(w, "accountDoesNotExist", "no account for the key")
+			return
+		}
+
+		status := http.StatusOK
+
+		if !account.OnlyReturnExisting {
+			s.thumbprint, s.contact, status = thumbprint, account.Contact, http.StatusCreated
+			s.registrations++
+		}
+
+		w.Header().Set("Location", s.URL+"/account/1")
+		s.writeJSON(w, status, map[string]interface{}{"status": "valid", "contact": s.contact})
+	case "/order":
+		var order struct {
+			Identifiers []map[string]string `json:"identifiers"`
+		}
+		json.Unmarshal(payload, &order)
+		s.names = nil
+
+		for _, identifier := range order.Identifiers {
+			s.names = append(s.names, identifier["value"])
+		}
+
+		s.token = base64.RawURLEncoding.EncodeToString([]byte(time.Now().String()))
+		s.authzStatus, s.orderStatus, s.certificate = "pending", "pending", nil
+		w.Header().Set("Location", s.URL+"/order/1")
+		s.writeJSON(w, http.StatusCreated, s.getOrder())
+	case "/order/1":
+		s.writeJSON(w, http.StatusOK, s.getOrder())
+	case "/authz/1":
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":     s.authzStatus,
+			"identifier": map[string]string{"type": "dns", "value": s.names[0]},
+			"challenges": []interface{}{s.getChallenge()},
+		})
+	case "/challenge/1":
+		keyAuth, err := os.ReadFile(filepath.Join(webserver.GetAcmeChallengeDir(s.webroot), s.token))
+
+		if err == nil && string(keyAuth) == s.token+"."+s.thumbprint {
+			s.authzStatus, s.orderStatus = "valid", "ready"
+		} else {
+			s.authzStatus, s.orderStatus = "invalid", "invalid"
+		}
+
+		s.writeJSON(w, http.StatusOK, s.getChallenge())
+	case "/finalize":
+		var finalize struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &finalize)
+
+		if s.orderStatus != "ready" {
+			s.writeError(w, "orderNotReady", "order is "+s.orderStatus)
+			return
+		}
+
+		if err := s.issue(finalize.CSR); err != nil {
+			s.writeError(w, "badCSR", err.Error())
+			return
+		}
+
+		s.writeJSON(w, http.StatusOK, s.getOrder())
+	case "/certificate":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(s.certificate)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *acmeServer) issue(csrData string) error {
+	der, err := base64.RawURLEncoding.DecodeString(csrData)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certDer, err := x509.CreateCertificate(rand.Reader, template, s.issuer.Certificate, csr.PublicKey, s.issuer.Key)
+	if err != nil {
+		return err
+	}
+
+	s.certificate = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}), certificate.EncodeCertificates([]*x509.Certificate{s.issuer.Certificate})...)
+	s.orderStatus = "valid"
+
+	return nil
+}
+
+func (s *acmeServer) getOrder() map[string]interface{} {
+	order := map[string]interface{}{
+		"status":         s.orderStatus,
+		"authorizations": []string{s.URL + "/authz/1"},
+		"finalize":       s.URL + "/finalize",
+	}
+
+	if s.certificate != nil {
+		order["certificate"] = s.URL + "/certificate"
+	}
+
+	return order
+}
+
+func (s *acmeServer) getChallenge() map[string]string {
+	return map[string]string{"type": "http-01", "url": s.URL + "/challenge/1", "token": s.token, "status": s.authzStatus}
+}
+
+func (s *acmeServer) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (s *acmeServer) writeError(w http.ResponseWriter, problem, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"type": "urn:ietf:params:acme:error:" + problem, "detail": detail})
+}
+
+// getJWKThumbprint returns RFC 7638 thumbprint of the EC account key
+func getJWKThumbprint(jwk map[string]string) string {
+	if jwk == nil {
+		return ""
+	}
+
+	data := `{"crv":"` + jwk["crv"] + `","kty":"` + jwk["kty"] + `","x":"` + jwk["x"] + `","y":"` + jwk["y"] + `"}`
+	hash := sha256.Sum256([]byte(data))
+
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
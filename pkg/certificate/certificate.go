@@ -0,0 +1,276 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// KeyInfo describes public key algorithm and size
+type KeyInfo struct {
+	Type string
+	Size int
+	// Curve is an elliptic curve name for ECDSA keys, e.g. P-256
+	Curve string
+}
+
+func (k KeyInfo) ToString() string {
+	if k.Curve != "" {
+		return fmt.Sprintf("%s %s", k.Type, k.Curve)
+	}
+
+	return fmt.Sprintf("%s %d", k.Type, k.Size)
+}
+
+type dhParameters struct {
+	P *big.Int
+	G *big.Int
+}
+
+// LoadCertificates loads all PEM encoded certificates from the file. The first one is a leaf certificate as a rule.
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCertificates(data)
+}
+
+// ParseCertificates parses all PEM encoded certificates
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("no PEM encoded certificates found")
+	}
+
+	return certificates, nil
+}
+
+// LoadLeafCertificate loads the first certificate from the file
+func LoadLeafCertificate(path string) (*x509.Certificate, error) {
+	certificates, err := LoadCertificates(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate %s: %v", path, err)
+	}
+
+	return certificates[0], nil
+}
+
+// LoadKeyType returns public key type of the leaf certificate of the file: rsa, ecdsa, ed25519
+func LoadKeyType(path string) (string, error) {
+	leaf, err := LoadLeafCertificate(path)
+	if err != nil {
+		return "", err
+	}
+
+	keyInfo, err := GetCertificateKeyInfo(leaf)
+	if err != nil {
+		return "", err
+	}
+
+	return keyInfo.Type, nil
+}
+
+// CheckKeyPair checks that the private key matches the leaf certificate
+func CheckKeyPair(certPath, keyPath string) error {
+	if _, err := tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+		return fmt.Errorf("invalid certificate %s and key %s pair: %v", certPath, keyPath, err)
+	}
+
+	return nil
+}
+
+// ValidateCABundle checks that the file contains only valid CA certificates
+func ValidateCABundle(path string) error {
+	certificates, err := LoadCertificates(path)
+	if err != nil {
+		return fmt.Errorf("invalid CA bundle %s: %v", path, err)
+	}
+
+	now := time.Now()
+
+	for _, certificate := range certificates {
+		if !certificate.IsCA {
+			return fmt.Errorf("invalid CA bundle %s: certificate '%s' is not a CA", path, certificate.Subject.String())
+		}
+
+		if now.After(certificate.NotAfter) || now.Before(certificate.NotBefore) {
+			return fmt.Errorf("invalid CA bundle %s: certificate '%s' is not valid at the current time", path, certificate.Subject.String())
+		}
+	}
+
+	return nil
+}
+
+// EncodeCertificates returns PEM encoded certificates
+func EncodeCertificates(certificates []*x509.Certificate) []byte {
+	var data []byte
+
+	for _, certificate := range certificates {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})...)
+	}
+
+	return data
+}
+
+// CheckOCSPServer checks that the leaf certificate of the file has an OCSP responder URL required for OCSP stapling
+func CheckOCSPServer(path string) error {
+	leaf, err := LoadLeafCertificate(path)
+	if err != nil {
+		return err
+	}
+
+	if len(leaf.OCSPServer) == 0 {
+		return fmt.Errorf("certificate %s has no OCSP responder URL", path)
+	}
+
+	return nil
+}
+
+// LoadPrivateKey loads PEM encoded private key in PKCS1, PKCS8 or SEC1 format
+func LoadPrivateKey(path string) (crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey parses the first PEM encoded private key
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+
+		if block == nil {
+			return nil, errors.New("no PEM encoded private key found")
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+	}
+}
+
+// GetKeyInfo returns information about a public or private key
+func GetKeyInfo(key interface{}) (KeyInfo, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return KeyInfo{Type: KeyTypeRSA, Size: k.N.BitLen()}, nil
+	case *rsa.PrivateKey:
+		return GetKeyInfo(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		return KeyInfo{Type: KeyTypeECDSA, Size: k.Curve.Params().BitSize, Curve: k.Curve.Params().Name}, nil
+	case *ecdsa.PrivateKey:
+		return GetKeyInfo(&k.PublicKey)
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return KeyInfo{Type: KeyTypeEd25519, Size: 256}, nil
+	default:
+		return KeyInfo{}, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// GetCertificateKeyInfo returns information about the certificate public key
+func GetCertificateKeyInfo(certificate *x509.Certificate) (KeyInfo, error) {
+	return GetKeyInfo(certificate.PublicKey)
+}
+
+// GetFingerprint returns SHA-256 fingerprint of the certificate
+func GetFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// GetNames returns all DNS names of the certificate: SANs and the common name if it is not listed in SANs
+func GetNames(certificate *x509.Certificate) []string {
+	names := append([]string{}, certificate.DNSNames...)
+	commonName := certificate.Subject.CommonName
+
+	if commonName == "" {
+		return names
+	}
+
+	for _, name := range names {
+		if strings.EqualFold(name, commonName) {
+			return names
+		}
+	}
+
+	return append(names, commonName)
+}
+
+// LoadDHParamsSize returns size in bits of the prime of PEM encoded DH parameters
+func LoadDHParamsSize(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+
+		if block == nil {
+			return 0, fmt.Errorf("no DH parameters found in %s", path)
+		}
+
+		if block.Type != "DH PARAMETERS" {
+			continue
+		}
+
+		var params dhParameters
+		if _, err := asn1.Unmarshal(block.Bytes, &params); err != nil {
+			return 0, fmt.Errorf("could not parse DH parameters %s: %v", path, err)
+		}
+
+		return params.P.BitLen(), nil
+	}
+}
@@ -0,0 +1,112 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const certificateDir = "../../test/certificate"
+
+func TestLoadCertificates(t *testing.T) {
+	certificates, err := LoadCertificates(certificateDir + "/example.com.crt")
+	assert.Nilf(t, err, "could not load certificates: %v", err)
+	assert.Equal(t, 2, len(certificates))
+	assert.Equal(t, []string{"example.com", "www.example.com"}, GetNames(certificates[0]))
+	assert.Equal(t, 64, len(GetFingerprint(certificates[0])))
+
+	_, err = LoadCertificates(certificateDir + "/example.com.key")
+	assert.NotNil(t, err)
+}
+
+func TestGetKeyInfo(t *testing.T) {
+	leaf, err := LoadLeafCertificate(certificateDir + "/example.com.crt")
+	assert.Nilf(t, err, "could not load certificate: %v", err)
+	keyInfo, err := GetCertificateKeyInfo(leaf)
+	assert.Nilf(t, err, "could not get key info: %v", err)
+	assert.Equal(t, KeyTypeECDSA, keyInfo.Type)
+	assert.Equal(t, "P-256", keyInfo.Curve)
+
+	key, err := LoadPrivateKey(certificateDir + "/example.com.key")
+	assert.Nilf(t, err, "could not load private key: %v", err)
+	keyInfo, err = GetKeyInfo(key)
+	assert.Nilf(t, err, "could not get key info: %v", err)
+	assert.Equal(t, "ecdsa P-256", keyInfo.ToString())
+}
+
+func TestCheckOCSPServer(t *testing.T) {
+	err := CheckOCSPServer(certificateDir + "/example.com.crt")
+	assert.Nilf(t, err, "certificate must have OCSP responder: %v", err)
+
+	certificates, err := LoadCertificates(certificateDir + "/example.com.crt")
+	assert.Nilf(t, err, "could not load certificates: %v", err)
+
+	chainPath := t.TempDir() + "/chain.pem"
+	err = os.WriteFile(chainPath, EncodeCertificates(certificates[1:]), 0644)
+	assert.Nilf(t, err, "could not write chain: %v", err)
+
+	chain, err := LoadCertificates(chainPath)
+	assert.Nilf(t, err, "could not load chain: %v", err)
+	assert.Equal(t, certificates[1].Raw, chain[0].Raw)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+	template := x509.Certificate{SerialNumber: big.NewInt(1), DNSNames: []string{"example.com"}}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.Nilf(t, err, "could not create certificate: %v", err)
+
+	selfSignedPath := t.TempDir() + "/self-signed.pem"
+	err = os.WriteFile(selfSignedPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+	assert.NotNil(t, CheckOCSPServer(selfSignedPath))
+}
+
+func TestCheckKeyPair(t *testing.T) {
+	keyType, err := LoadKeyType(certificateDir + "/example.com.crt")
+	assert.Nilf(t, err, "could not load key type: %v", err)
+	assert.Equal(t, KeyTypeECDSA, keyType)
+
+	err = CheckKeyPair(certificateDir+"/example.com.crt", certificateDir+"/example.com.key")
+	assert.Nilf(t, err, "key must match certificate: %v", err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.Nilf(t, err, "could not encode key: %v", err)
+
+	keyPath := t.TempDir() + "/other.key"
+	err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	assert.Nilf(t, err, "could not write key: %v", err)
+	assert.NotNil(t, CheckKeyPair(certificateDir+"/example.com.crt", keyPath))
+}
+
+func TestValidateCABundle(t *testing.T) {
+	err := ValidateCABundle(certificateDir + "/example.com.issuer.crt")
+	assert.Nilf(t, err, "CA bundle must be valid: %v", err)
+
+	err = ValidateCABundle(certificateDir + "/example.com.crt")
+	assert.NotNil(t, err, "leaf certificate must not be accepted as a CA")
+
+	err = ValidateCABundle(certificateDir + "/example.com.key")
+	assert.NotNil(t, err)
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	key, err := GenerateKey(KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+
+	leaf, err := GenerateCertificate([]string{"example.com", "127.0.0.1"}, key, 90*24*time.Hour, nil)
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+
+	assert.False(t, NeedsRenewal(leaf, []string{"Example.com", "127.0.0.1"}, 30*24*time.Hour))
+	assert.True(t, NeedsRenewal(leaf, []string{"example.com"}, 30*24*time.Hour), "names changed")
+	assert.True(t, NeedsRenewal(leaf, []string{"example.com", "127.0.0.1"}, 100*24*time.Hour), "certificate expires")
+}
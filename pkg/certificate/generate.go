@@ -0,0 +1,161 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+const rsaKeySize = 2048
+
+// Issuer is a CA certificate and its key used to sign certificates
+type Issuer struct {
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+}
+
+// GenerateKey generates a private key of the type: rsa (2048 bits) or ecdsa (P-256)
+func GenerateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case KeyTypeECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s', supported types: rsa, ecdsa", keyType)
+	}
+}
+
+// EncodePrivateKey returns PEM encoded private key in PKCS8 format
+func EncodePrivateKey(key crypto.PrivateKey) ([]byte, error) {
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), nil
+}
+
+// GenerateCertificate generates a server certificate for DNS names and IP addresses.
+// The certificate is self-signed if the issuer is nil.
+func GenerateCertificate(names []string, key crypto.Signer, validity time.Duration, issuer *Issuer) (*x509.Certificate, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no certificate names specified")
+	}
+
+	template, err := getCertificateTemplate(names[0], validity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	parent, signer := template, key
+
+	if issuer != nil {
+		parent, signer = issuer.Certificate, issuer.Key
+	}
+
+	return createCertificate(template, parent, key.Public(), signer)
+}
+
+// GenerateCA generates a self-signed CA certificate
+func GenerateCA(commonName string, key crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
+	template, err := getCertificateTemplate(commonName, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	return createCertificate(template, template, key.Public(), key)
+}
+
+// GetSubjectNames returns DNS names and IP addresses of the certificate SANs
+func GetSubjectNames(certificate *x509.Certificate) []string {
+	names := append([]string{}, certificate.DNSNames...)
+
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+
+	return names
+}
+
+// NeedsRenewal checks that the certificate expires within the renewal period or its SANs differ from the names
+func NeedsRenewal(certificate *x509.Certificate, names []string, renewBefore time.Duration) bool {
+	if time.Now().Add(renewBefore).After(certificate.NotAfter) {
+		return true
+	}
+
+	subjectNames := GetSubjectNames(certificate)
+
+	if len(subjectNames) != len(names) {
+		return true
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(subjectNames, func(subjectName string) bool {
+			return strings.EqualFold(subjectName, name)
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getCertificateTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		// tolerate clock skew of clients
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, publicKey crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("could not create certificate: %v", err)
+	}
+
+	return x509.ParseCertificate(data)
+}
@@ -0,0 +1,242 @@
+package certstore
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/reverter"
+	"github.com/r2dtools/webmng/pkg/webserver/staging"
+)
+
+const (
+	storeDir      = "store"
+	currentLink   = "current"
+	versionFormat = "20060102T150405Z"
+)
+
+// Store keeps certificate versions: <dir>/<name>/<keyType>/<version>/.
+// Hosts reference files through the "current" symlink pointing at the active version.
+type Store struct {
+	dir string
+}
+
+// Entry is a certificate of the store
+type Entry struct {
+	Name    string
+	KeyType string
+	// Current is an active version
+	Current  string
+	Versions []string
+}
+
+// GetStore returns the store within the state directory
+func GetStore(stateDir string) (*Store, error) {
+	dir, err := state.GetDir(stateDir, storeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Import validates the certificate and copies it to a new version of the name. Returns key type and version.
+// Certificates are normalized to cert.pem, chain.pem, fullchain.pem and privkey.pem.
+func (s *Store) Import(name string, cert webserver.Certificate) (keyType, version string, err error) {
+	if err = validateName(name); err != nil {
+		return "", "", err
+	}
+
+	keyTypes, err := webserver.GetCertificatesKeyTypes([]webserver.Certificate{cert})
+	if err != nil {
+		return "", "", err
+	}
+
+	if cert.KeyPath == "" {
+		return "", "", errors.New("certificate key path is not specified")
+	}
+
+	certificates, err := loadCertificates(cert)
+	if err != nil {
+		return "", "", err
+	}
+
+	if time.Now().After(certificates[0].NotAfter) {
+		return "", "", fmt.Errorf("certificate %s is expired", cert.GetLeafPath())
+	}
+
+	key, err := certificate.LoadPrivateKey(cert.KeyPath)
+	if err != nil {
+		return "", "", fmt.Errorf("could not load certificate key %s: %v", cert.KeyPath, err)
+	}
+
+	keyType = keyTypes[0]
+	keyTypeDir := s.getKeyTypeDir(name, keyType)
+	version = time.Now().UTC().Format(versionFormat)
+
+	for i := 1; isExist(filepath.Join(keyTypeDir, version)); i++ {
+		version = fmt.Sprintf("%s-%d", time.Now().UTC().Format(versionFormat), i)
+	}
+
+	if _, err = webserver.WriteCertificate(filepath.Join(keyTypeDir, version), key, certificates); err != nil {
+		return "", "", err
+	}
+
+	return keyType, version, nil
+}
+
+// Activate atomically points the current symlink at the version. In staged mode the symlink is switched with the configuration.
+// The previous symlink target is restored by the reverter on rollback.
+func (s *Store) Activate(name, keyType, version string, rev reverter.Reverter, changes *staging.Changes) error {
+	keyTypeDir := s.getKeyTypeDir(name, keyType)
+
+	if !isExist(filepath.Join(keyTypeDir, version)) {
+		return fmt.Errorf("certificate %s %s version %s does not exist", name, keyType, version)
+	}
+
+	linkPath := filepath.Join(keyTypeDir, currentLink)
+
+	if err := rev.BackupSymlink(linkPath); err != nil {
+		return fmt.Errorf("could not backup symlink %s: %v", linkPath, err)
+	}
+
+	// relative target keeps the store relocatable
+	if err := changes.ReplaceSymlink(version, linkPath); err != nil {
+		return fmt.Errorf("could not activate certificate version %s: %v", version, err)
+	}
+
+	return nil
+}
+
+// ActivatePrevious activates the version preceding the current one
+func (s *Store) ActivatePrevious(name, keyType string, rev reverter.Reverter, changes *staging.Changes) (string, error) {
+	entry, err := s.GetEntry(name, keyType)
+	if err != nil {
+		return "", err
+	}
+
+	index := len(entry.Versions)
+
+	for i, version := range entry.Versions {
+		if version == entry.Current {
+			index = i
+		}
+	}
+
+	if index == 0 {
+		return "", fmt.Errorf("certificate %s %s has no version preceding %s", name, keyType, entry.Current)
+	}
+
+	previous := entry.Versions[index-1]
+
+	return previous, s.Activate(name, keyType, previous, rev, changes)
+}
+
+// GetCertificate returns certificate files referenced through the current symlink
+func (s *Store) GetCertificate(name, keyType string) webserver.Certificate {
+	return webserver.GetCertificateInDir(filepath.Join(s.getKeyTypeDir(name, keyType), currentLink))
+}
+
+// GetEntry returns versions of the certificate sorted from the oldest one
+func (s *Store) GetEntry(name, keyType string) (Entry, error) {
+	entry := Entry{Name: name, KeyType: keyType}
+	keyTypeDir := s.getKeyTypeDir(name, keyType)
+	items, err := os.ReadDir(keyTypeDir)
+
+	if err != nil {
+		return entry, fmt.Errorf("could not read certificate %s %s versions: %v", name, keyType, err)
+	}
+
+	for _, item := range items {
+		if item.IsDir() {
+			entry.Versions = append(entry.Versions, item.Name())
+		}
+	}
+
+	sort.Strings(entry.Versions)
+	entry.Current, _ = os.Readlink(filepath.Join(keyTypeDir, currentLink))
+
+	return entry, nil
+}
+
+// GetEntries returns all certificates of the name
+func (s *Store) GetEntries(name string) ([]Entry, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	items, err := os.ReadDir(filepath.Join(s.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var entries []Entry
+
+	for _, item := range items {
+		if !item.IsDir() {
+			continue
+		}
+
+		entry, err := s.GetEntry(name, item.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s *Store) getKeyTypeDir(name, keyType string) string {
+	return filepath.Join(s.dir, name, keyType)
+}
+
+// loadCertificates returns the leaf certificate followed by its chain
+func loadCertificates(cert webserver.Certificate) ([]*x509.Certificate, error) {
+	if cert.FullChainPath != "" {
+		return certificate.LoadCertificates(cert.FullChainPath)
+	}
+
+	certificates, err := certificate.LoadCertificates(cert.CertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.ChainPath == "" {
+		return certificates, nil
+	}
+
+	chain, err := certificate.LoadCertificates(cert.ChainPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(certificates[:1], chain...), nil
+}
+
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid certificate name '%s'", name)
+	}
+
+	return nil
+}
+
+func isExist(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
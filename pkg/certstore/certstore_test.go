@@ -0,0 +1,98 @@
+package certstore
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/reverter"
+	"github.com/r2dtools/webmng/pkg/webserver/staging"
+	"github.com/stretchr/testify/assert"
+)
+
+type hostDisabler struct{}
+
+func (h *hostDisabler) Disable(hostConfigPath string) error {
+	return nil
+}
+
+func TestStoreRotation(t *testing.T) {
+	store, err := GetStore(t.TempDir())
+	assert.Nilf(t, err, "could not create store: %v", err)
+
+	rev := reverter.GetConfigReveter(&hostDisabler{}, logger.NilLogger{})
+	changes := staging.GetChanges(false)
+	firstCert := writeCertificate(t)
+	keyType, firstVersion, err := store.Import("example.com", firstCert)
+	assert.Nilf(t, err, "could not import certificate: %v", err)
+	assert.Equal(t, certificate.KeyTypeECDSA, keyType)
+
+	err = store.Activate("example.com", keyType, firstVersion, rev, changes)
+	assert.Nilf(t, err, "could not activate certificate: %v", err)
+	assert.Nil(t, rev.Commit())
+
+	current := store.GetCertificate("example.com", keyType)
+	info, err := os.Stat(current.KeyPath)
+	assert.Nilf(t, err, "could not stat key: %v", err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assertSameCertificate(t, firstCert.CertPath, current.CertPath)
+
+	secondCert := writeCertificate(t)
+	_, secondVersion, err := store.Import("example.com", secondCert)
+	assert.Nilf(t, err, "could not import certificate: %v", err)
+	assert.NotEqual(t, firstVersion, secondVersion)
+
+	err = store.Activate("example.com", keyType, secondVersion, rev, changes)
+	assert.Nilf(t, err, "could not activate certificate: %v", err)
+	assertSameCertificate(t, secondCert.CertPath, current.CertPath)
+
+	// failed configuration check
+	assert.Nil(t, rev.Rollback())
+	assertSameCertificate(t, firstCert.CertPath, current.CertPath)
+
+	err = store.Activate("example.com", keyType, secondVersion, rev, changes)
+	assert.Nilf(t, err, "could not activate certificate: %v", err)
+	assert.Nil(t, rev.Commit())
+
+	previous, err := store.ActivatePrevious("example.com", keyType, rev, changes)
+	assert.Nilf(t, err, "could not activate previous certificate: %v", err)
+	assert.Equal(t, firstVersion, previous)
+	assertSameCertificate(t, firstCert.CertPath, current.CertPath)
+
+	_, err = store.ActivatePrevious("example.com", keyType, rev, changes)
+	assert.NotNil(t, err, "the first version has no previous one")
+
+	entries, err := store.GetEntries("example.com")
+	assert.Nilf(t, err, "could not get entries: %v", err)
+	assert.Equal(t, []Entry{{Name: "example.com", KeyType: keyType, Current: firstVersion, Versions: []string{firstVersion, secondVersion}}}, entries)
+
+	_, _, err = store.Import("../example.com", firstCert)
+	assert.NotNil(t, err, "name must not contain path separators")
+}
+
+func writeCertificate(t *testing.T) webserver.Certificate {
+	key, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+
+	leaf, err := certificate.GenerateCertificate([]string{"example.com"}, key, 24*time.Hour, nil)
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+
+	cert, err := webserver.WriteCertificate(t.TempDir(), key, []*x509.Certificate{leaf})
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+
+	return webserver.Certificate{CertPath: cert.CertPath, KeyPath: cert.KeyPath}
+}
+
+func assertSameCertificate(t *testing.T, expectedPath, path string) {
+	expected, err := certificate.LoadLeafCertificate(expectedPath)
+	assert.Nilf(t, err, "could not load certificate: %v", err)
+
+	actual, err := certificate.LoadLeafCertificate(path)
+	assert.Nilf(t, err, "could not load certificate: %v", err)
+
+	assert.Equal(t, certificate.GetFingerprint(expected), certificate.GetFingerprint(actual))
+}
@@ -0,0 +1,266 @@
+package lineage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	SourceCertbot = "certbot"
+	SourceAcmeSh  = "acme.sh"
+
+	DefaultCertbotDir = "/etc/letsencrypt"
+	DefaultAcmeShDir  = "/root/.acme.sh"
+)
+
+// Lineage is a certificate managed by an ACME client: certbot lineage or acme.sh domain
+type Lineage struct {
+	Source  string
+	Name    string
+	Domains []string
+	// Certificate contains paths of the current certificate files, certbot live symlinks
+	Certificate webserver.Certificate
+	// ArchiveDir contains all versions of certbot certificate files, live files are symlinks to them
+	ArchiveDir string
+	NotAfter   time.Time
+}
+
+// Usage is a certificate of a host that belongs to a lineage
+type Usage struct {
+	Host     webserver.Host
+	CertPath string
+	KeyPath  string
+	Lineage  *Lineage
+	// Stale is true if the host points at certbot archive files instead of live symlinks
+	Stale bool
+}
+
+// Skipped is a lineage which could not be loaded, e.g. certbot renewal configuration of deleted certificate files
+type Skipped struct {
+	Source string
+	Name   string
+	Path   string
+	Error  string
+}
+
+// Report describes lineages usage by hosts
+type Report struct {
+	Usages  []Usage
+	Unused  []Lineage
+	Skipped []Skipped
+}
+
+// LoadCertbotLineages loads lineages from certbot renewal configuration files: <configDir>/renewal/*.conf
+// Lineages which could not be loaded are skipped and returned separately.
+func LoadCertbotLineages(configDir string) ([]Lineage, []Skipped, error) {
+	renewalFiles, err := filepath.Glob(filepath.Join(configDir, "renewal", "*.conf"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lineages []Lineage
+	var skipped []Skipped
+
+	for _, renewalFile := range renewalFiles {
+		name := strings.TrimSuffix(filepath.Base(renewalFile), ".conf")
+		params, err := readConfigParams(renewalFile)
+
+		if err != nil {
+			skipped = append(skipped, Skipped{Source: SourceCertbot, Name: name, Path: renewalFile, Error: err.Error()})
+
+			continue
+		}
+
+		lineage := Lineage{
+			Source: SourceCertbot,
+			Name:   name,
+			Certificate: webserver.Certificate{
+				CertPath:      params["cert"],
+				KeyPath:       params["privkey"],
+				ChainPath:     params["chain"],
+				FullChainPath: params["fullchain"],
+			},
+			ArchiveDir: params["archive_dir"],
+		}
+
+		if lineage.ArchiveDir == "" {
+			lineage.ArchiveDir = filepath.Join(configDir, "archive", name)
+		}
+
+		if err = loadCertificateInfo(&lineage); err != nil {
+			skipped = append(skipped, Skipped{Source: SourceCertbot, Name: name, Path: renewalFile, Error: err.Error()})
+
+			continue
+		}
+
+		lineages = append(lineages, lineage)
+	}
+
+	return lineages, skipped, nil
+}
+
+// LoadAcmeShLineages loads certificates issued by acme.sh: <homeDir>/<domain>/ and <homeDir>/<domain>_ecc/
+// Lineages which could not be loaded are skipped and returned separately.
+func LoadAcmeShLineages(homeDir string) ([]Lineage, []Skipped, error) {
+	configFiles, err := filepath.Glob(filepath.Join(homeDir, "*", "*.conf"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lineages []Lineage
+	var skipped []Skipped
+
+	for _, configFile := range configFiles {
+		dir := filepath.Dir(configFile)
+		domain := strings.TrimSuffix(filepath.Base(configFile), ".conf")
+
+		// domain configuration file has the same name as its directory, e.g. example.com/example.com.conf
+		if domain != strings.TrimSuffix(filepath.Base(dir), "_ecc") {
+			continue
+		}
+
+		lineage := Lineage{
+			Source: SourceAcmeSh,
+			Name:   filepath.Base(dir),
+			Certificate: webserver.Certificate{
+				CertPath:      filepath.Join(dir, domain+".cer"),
+				KeyPath:       filepath.Join(dir, domain+".key"),
+				ChainPath:     filepath.Join(dir, "ca.cer"),
+				FullChainPath: filepath.Join(dir, "fullchain.cer"),
+			},
+		}
+
+		if err = loadCertificateInfo(&lineage); err != nil {
+			skipped = append(skipped, Skipped{Source: SourceAcmeSh, Name: lineage.Name, Path: dir, Error: err.Error()})
+
+			continue
+		}
+
+		lineages = append(lineages, lineage)
+	}
+
+	return lineages, skipped, nil
+}
+
+// GetReport matches certificates of the hosts with the lineages
+func GetReport(lineages []Lineage, configs []webserver.TLSConfig) Report {
+	var report Report
+	used := make([]bool, len(lineages))
+
+	for _, config := range configs {
+		for _, files := range config.Certificates {
+			for i := range lineages {
+				stale, ok := lineages[i].matches(files.CertPath)
+				if !ok {
+					continue
+				}
+
+				used[i] = true
+				report.Usages = append(report.Usages, Usage{
+					Host:     config.Host,
+					CertPath: files.CertPath,
+					KeyPath:  files.KeyPath,
+					Lineage:  &lineages[i],
+					Stale:    stale,
+				})
+
+				break
+			}
+		}
+	}
+
+	for i, lineage := range lineages {
+		if !used[i] {
+			report.Unused = append(report.Unused, lineage)
+		}
+	}
+
+	return report
+}
+
+// GetStaleServerNames returns unique server names of hosts pointing at archive files
+func (r Report) GetStaleServerNames() []string {
+	var serverNames []string
+
+	for _, usage := range r.Usages {
+		if usage.Stale && !slices.Contains(serverNames, usage.Host.ServerName) {
+			serverNames = append(serverNames, usage.Host.ServerName)
+		}
+	}
+
+	sort.Strings(serverNames)
+
+	return serverNames
+}
+
+// matches checks that the certificate path belongs to the lineage and whether it is an archive path
+func (l Lineage) matches(certPath string) (stale bool, ok bool) {
+	certPath = filepath.Clean(certPath)
+
+	for _, path := range []string{l.Certificate.CertPath, l.Certificate.FullChainPath} {
+		if path != "" && filepath.Clean(path) == certPath {
+			return false, true
+		}
+	}
+
+	if l.ArchiveDir != "" && filepath.Dir(certPath) == filepath.Clean(l.ArchiveDir) {
+		return true, true
+	}
+
+	return false, false
+}
+
+func loadCertificateInfo(lineage *Lineage) error {
+	leaf, err := certificate.LoadLeafCertificate(lineage.Certificate.GetLeafPath())
+	if err != nil {
+		return fmt.Errorf("invalid %s lineage %s: %v", lineage.Source, lineage.Name, err)
+	}
+
+	lineage.Domains = certificate.GetNames(leaf)
+	lineage.NotAfter = leaf.NotAfter
+
+	return nil
+}
+
+// readConfigParams reads "key = value" parameters of the certbot renewal configuration file.
+// Parameters of sections are skipped, file paths are kept at the top level.
+func readConfigParams(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	params := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		params[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `'"`)
+	}
+
+	return params, scanner.Err()
+}
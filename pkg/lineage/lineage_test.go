@@ -0,0 +1,122 @@
+package lineage
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReport(t *testing.T) {
+	certbotDir := t.TempDir()
+	acmeShDir := t.TempDir()
+	archiveDir := filepath.Join(certbotDir, "archive", "example.com")
+	liveDir := filepath.Join(certbotDir, "live", "example.com")
+
+	writeCertificate(t, archiveDir, "1", []string{"example.com", "www.example.com"})
+	assert.Nil(t, os.MkdirAll(liveDir, 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(certbotDir, "renewal"), 0755))
+
+	for _, name := range []string{"cert", "privkey", "chain", "fullchain"} {
+		assert.Nil(t, os.Symlink(filepath.Join(archiveDir, name+"1.pem"), filepath.Join(liveDir, name+".pem")))
+	}
+
+	renewalConfig := fmt.Sprintf(
+		"version = 2.1.0\narchive_dir = %[1]s\ncert = %[2]s/cert.pem\nprivkey = %[2]s/privkey.pem\nchain = %[2]s/chain.pem\nfullchain = %[2]s/fullchain.pem\n\n[renewalparams]\nauthenticator = webroot\n",
+		archiveDir,
+		liveDir,
+	)
+	assert.Nil(t, os.WriteFile(filepath.Join(certbotDir, "renewal", "example.com.conf"), []byte(renewalConfig), 0644))
+
+	acmeShDomainDir := filepath.Join(acmeShDir, "example.org_ecc")
+	writeAcmeShCertificate(t, acmeShDomainDir, "example.org")
+
+	// renewal configuration of the deleted certificate files must not break the import
+	staleConfig := strings.ReplaceAll(renewalConfig, "example.com", "deleted.example.com")
+	assert.Nil(t, os.WriteFile(filepath.Join(certbotDir, "renewal", "deleted.example.com.conf"), []byte(staleConfig), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(acmeShDir, "deleted.example.org"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(acmeShDir, "deleted.example.org", "deleted.example.org.conf"), []byte(""), 0644))
+
+	certbotLineages, skipped, err := LoadCertbotLineages(certbotDir)
+	assert.Nilf(t, err, "could not load certbot lineages: %v", err)
+	assert.Len(t, certbotLineages, 1)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, certbotLineages[0].Domains)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, "deleted.example.com", skipped[0].Name)
+	assert.NotEmpty(t, skipped[0].Error)
+
+	acmeShLineages, skipped, err := LoadAcmeShLineages(acmeShDir)
+	assert.Nilf(t, err, "could not load acme.sh lineages: %v", err)
+	assert.Len(t, acmeShLineages, 1)
+	assert.Equal(t, "example.org_ecc", acmeShLineages[0].Name)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, SourceAcmeSh, skipped[0].Source)
+
+	configs := []webserver.TLSConfig{
+		{
+			Host:         webserver.Host{ServerName: "example.com"},
+			Certificates: []webserver.CertificateFiles{{CertPath: filepath.Join(liveDir, "fullchain.pem")}},
+		},
+		{
+			Host:         webserver.Host{ServerName: "www.example.com"},
+			Certificates: []webserver.CertificateFiles{{CertPath: filepath.Join(archiveDir, "fullchain1.pem")}},
+		},
+	}
+
+	report := GetReport(append(certbotLineages, acmeShLineages...), configs)
+	assert.Len(t, report.Usages, 2)
+	assert.False(t, report.Usages[0].Stale)
+	assert.True(t, report.Usages[1].Stale)
+	assert.Equal(t, []string{"www.example.com"}, report.GetStaleServerNames())
+	assert.Len(t, report.Unused, 1)
+	assert.Equal(t, SourceAcmeSh, report.Unused[0].Source)
+}
+
+// writeCertificate writes certificate files in certbot archive layout: cert1.pem, privkey1.pem, ...
+func writeCertificate(t *testing.T, dir, version string, names []string) {
+	key, certificates := generateCertificate(t, names)
+	files, err := webserver.WriteCertificate(dir, key, certificates)
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+
+	for _, path := range []string{files.CertPath, files.KeyPath, files.ChainPath, files.FullChainPath} {
+		assert.Nil(t, os.Rename(path, strings.TrimSuffix(path, ".pem")+version+".pem"))
+	}
+}
+
+// writeAcmeShCertificate writes certificate files in acme.sh layout
+func writeAcmeShCertificate(t *testing.T, dir, domain string) {
+	key, certificates := generateCertificate(t, []string{domain})
+	files, err := webserver.WriteCertificate(dir, key, certificates)
+	assert.Nilf(t, err, "could not write certificate: %v", err)
+
+	renames := map[string]string{
+		files.CertPath:      domain + ".cer",
+		files.KeyPath:       domain + ".key",
+		files.ChainPath:     "ca.cer",
+		files.FullChainPath: "fullchain.cer",
+	}
+
+	for path, name := range renames {
+		assert.Nil(t, os.Rename(path, filepath.Join(dir, name)))
+	}
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, domain+".conf"), []byte("Le_Domain='"+domain+"'\n"), 0644))
+}
+
+func generateCertificate(t *testing.T, names []string) (crypto.Signer, []*x509.Certificate) {
+	key, err := certificate.GenerateKey(certificate.KeyTypeECDSA)
+	assert.Nilf(t, err, "could not generate key: %v", err)
+
+	leaf, err := certificate.GenerateCertificate(names, key, 90*24*time.Hour, nil)
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+
+	return key, []*x509.Certificate{leaf}
+}
@@ -1,5 +1,10 @@
 package logger
 
+import (
+	"fmt"
+	"io"
+)
+
 type LoggerInterface interface {
 	Error(message string, args ...interface{})
 	Warning(message string, args ...interface{})
@@ -13,3 +18,27 @@ func (l NilLogger) Error(message string, args ...interface{})   {}
 func (l NilLogger) Warning(message string, args ...interface{}) {}
 func (l NilLogger) Info(message string, args ...interface{})    {}
 func (l NilLogger) Debug(message string, args ...interface{})   {}
+
+// WriterLogger writes errors and warnings to the writer, e.g. stderr of the command
+type WriterLogger struct {
+	Writer io.Writer
+}
+
+func (l WriterLogger) Error(message string, args ...interface{}) {
+	l.write("error", message, args...)
+}
+
+func (l WriterLogger) Warning(message string, args ...interface{}) {
+	l.write("warning", message, args...)
+}
+
+func (l WriterLogger) Info(message string, args ...interface{})  {}
+func (l WriterLogger) Debug(message string, args ...interface{}) {}
+
+func (l WriterLogger) write(level, message string, args ...interface{}) {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+
+	fmt.Fprintf(l.Writer, "%s: %s\n", level, message)
+}
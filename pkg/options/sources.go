@@ -0,0 +1,236 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+
+	// EnvPrefix is the prefix of environment variables setting options, e.g. WEBMNG_SERVER_ROOT
+	EnvPrefix = "WEBMNG_"
+	// ConfigPathEnv is the environment variable with the path to webmng config file
+	ConfigPathEnv = "WEBMNG_CONFIG"
+	// DefaultConfigPath is webmng config file used if no other file is specified
+	DefaultConfigPath = "/etc/webmng/config.yaml"
+	// InstanceEnv is the environment variable with the name of the selected webserver instance
+	InstanceEnv = "WEBMNG_INSTANCE"
+
+	instancesSection  = "instances"
+	instanceWebServer = "webserver"
+)
+
+// Value is an effective option value and the source it is taken from
+type Value struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ConfigFile contains options of webmng config file grouped by webserver and named webserver instances.
+// Instance options override options of its webserver:
+//
+//	nginx:
+//	  server_root: /usr/local/nginx/conf
+//	instances:
+//	  site1:
+//	    webserver: nginx
+//	    bin: /opt/site1/sbin/nginx
+//	    prefix: /opt/site1
+//	    server_root: /opt/site1/conf
+type ConfigFile struct {
+	Path      string
+	Servers   map[string]map[string]string
+	Instances map[string]Instance
+}
+
+// Instance is a named webserver instance
+type Instance struct {
+	Name      string            `json:"name"`
+	WebServer string            `json:"webserver"`
+	Options   map[string]string `json:"options"`
+}
+
+// LoadConfigFile loads webmng config file. Missing file is not an error if skipMissing is true.
+func LoadConfigFile(path string, skipMissing bool) (*ConfigFile, error) {
+	configFile := ConfigFile{Path: path, Servers: make(map[string]map[string]string), Instances: make(map[string]Instance)}
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		if skipMissing && os.IsNotExist(err) {
+			return &configFile, nil
+		}
+
+		return nil, fmt.Errorf("could not read config file '%s': %v", path, err)
+	}
+
+	var sections map[string]map[string]interface{}
+
+	if err = yaml.Unmarshal(content, &sections); err != nil {
+		return nil, fmt.Errorf("could not parse config file '%s': %v", path, err)
+	}
+
+	for section, sectionOptions := range sections {
+		if section != instancesSection {
+			if configFile.Servers[section], err = getScalarOptions(section, sectionOptions); err != nil {
+				return nil, fmt.Errorf("invalid config file '%s': %v", path, err)
+			}
+
+			continue
+		}
+
+		for name, value := range sectionOptions {
+			instanceOptions, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid config file '%s': instance '%s' must be a map of options", path, name)
+			}
+
+			instance := Instance{Name: name}
+
+			if instance.Options, err = getScalarOptions(section+"."+name, instanceOptions); err != nil {
+				return nil, fmt.Errorf("invalid config file '%s': %v", path, err)
+			}
+
+			instance.WebServer = instance.Options[instanceWebServer]
+			delete(instance.Options, instanceWebServer)
+
+			if instance.WebServer == "" {
+				return nil, fmt.Errorf("invalid config file '%s': webserver of instance '%s' is not specified", path, name)
+			}
+
+			configFile.Instances[name] = instance
+		}
+	}
+
+	return &configFile, nil
+}
+
+// GetOptions returns options of the webserver section overridden by options of the instance if it is specified
+func (f *ConfigFile) GetOptions(webServer, instanceName string) (map[string]string, error) {
+	fileOptions := make(map[string]string)
+
+	for name, value := range f.Servers[webServer] {
+		fileOptions[name] = value
+	}
+
+	if instanceName == "" {
+		return fileOptions, nil
+	}
+
+	instance, ok := f.Instances[instanceName]
+	if !ok {
+		return nil, fmt.Errorf("instance '%s' is not defined in config file '%s'", instanceName, f.Path)
+	}
+
+	if instance.WebServer != webServer {
+		return nil, fmt.Errorf("instance '%s' is %s instance, not %s", instanceName, instance.WebServer, webServer)
+	}
+
+	for name, value := range instance.Options {
+		fileOptions[name] = value
+	}
+
+	return fileOptions, nil
+}
+
+// GetInstances returns instances sorted by name
+func (f *ConfigFile) GetInstances() []Instance {
+	var instances []Instance
+
+	for _, instance := range f.Instances {
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+
+	return instances
+}
+
+func getScalarOptions(section string, rawOptions map[string]interface{}) (map[string]string, error) {
+	scalarOptions := make(map[string]string)
+
+	for name, value := range rawOptions {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("option '%s.%s' must be a scalar", section, name)
+		case nil:
+			scalarOptions[strings.ToLower(name)] = ""
+		default:
+			scalarOptions[strings.ToLower(name)] = fmt.Sprint(value)
+		}
+	}
+
+	return scalarOptions, nil
+}
+
+// GetEnvName returns the environment variable name of the option
+func GetEnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(name)
+}
+
+// GetEnvOptions returns options set via WEBMNG_* environment variables
+func GetEnvOptions(names []string) map[string]string {
+	envOptions := make(map[string]string)
+
+	for _, name := range names {
+		if value, ok := os.LookupEnv(GetEnvName(name)); ok {
+			envOptions[name] = value
+		}
+	}
+
+	return envOptions
+}
+
+// Resolve returns effective values of the options. Precedence: flag, env, file, default.
+func Resolve(defaults, flags, env, file map[string]string) ([]Value, error) {
+	for name := range file {
+		if _, ok := defaults[name]; !ok {
+			return nil, fmt.Errorf("unknown option '%s' in config file", name)
+		}
+	}
+
+	var values []Value
+
+	for name, def := range defaults {
+		value := Value{Name: name, Value: def, Source: SourceDefault}
+
+		if option, ok := flags[name]; ok {
+			value.Value, value.Source = option, SourceFlag
+		} else if option, ok := env[name]; ok {
+			value.Value, value.Source = option, SourceEnv
+		} else if option, ok := file[name]; ok {
+			value.Value, value.Source = option, SourceFile
+		}
+
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+
+	return values, nil
+}
+
+// GetParams returns options params set by any source except defaults
+func GetParams(values []Value) map[string]string {
+	params := make(map[string]string)
+
+	for _, value := range values {
+		if value.Source != SourceDefault {
+			params[value.Name] = value.Value
+		}
+	}
+
+	return params
+}
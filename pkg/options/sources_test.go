@@ -0,0 +1,104 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "nginx:\n  server_root: /usr/local/nginx/conf\n  https_port: 8443\napache:\n  ctl: apache2ctl\n"
+	err := os.WriteFile(path, []byte(content), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+
+	configFile, err := LoadConfigFile(path, false)
+	assert.Nilf(t, err, "could not load config file: %v", err)
+	fileOptions, err := configFile.GetOptions("nginx", "")
+	assert.Nilf(t, err, "could not get options: %v", err)
+	assert.Equal(t, map[string]string{"server_root": "/usr/local/nginx/conf", "https_port": "8443"}, fileOptions)
+	fileOptions, err = configFile.GetOptions("apache", "")
+	assert.Nilf(t, err, "could not get options: %v", err)
+	assert.Equal(t, map[string]string{"ctl": "apache2ctl"}, fileOptions)
+
+	configFile, err = LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), true)
+	assert.Nilf(t, err, "missing config file must be skipped: %v", err)
+	fileOptions, err = configFile.GetOptions("nginx", "")
+	assert.Nilf(t, err, "could not get options: %v", err)
+	assert.Empty(t, fileOptions)
+
+	_, err = LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), false)
+	assert.NotNil(t, err)
+
+	err = os.WriteFile(path, []byte("nginx:\n  server_root:\n    - /etc/nginx\n"), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+	_, err = LoadConfigFile(path, false)
+	assert.NotNil(t, err, "list option must not be accepted")
+}
+
+func TestConfigFileInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `nginx:
+  https_port: 8443
+  server_root: /etc/nginx
+instances:
+  site2:
+    webserver: nginx
+    bin: /opt/site2/sbin/nginx
+    server_root: /opt/site2/conf
+  site1:
+    webserver: apache
+    server_root: /etc/apache2-site1
+`
+	err := os.WriteFile(path, []byte(content), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+
+	configFile, err := LoadConfigFile(path, false)
+	assert.Nilf(t, err, "could not load config file: %v", err)
+
+	instances := configFile.GetInstances()
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, "site1", instances[0].Name)
+	assert.Equal(t, "apache", instances[0].WebServer)
+
+	fileOptions, err := configFile.GetOptions("nginx", "site2")
+	assert.Nilf(t, err, "could not get instance options: %v", err)
+	assert.Equal(t, map[string]string{"https_port": "8443", "bin": "/opt/site2/sbin/nginx", "server_root": "/opt/site2/conf"}, fileOptions)
+
+	_, err = configFile.GetOptions("nginx", "site1")
+	assert.NotNil(t, err, "apache instance must not be used for nginx")
+	_, err = configFile.GetOptions("nginx", "site3")
+	assert.NotNil(t, err, "undefined instance must not be used")
+
+	err = os.WriteFile(path, []byte("instances:\n  site1:\n    bin: /opt/site1/sbin/nginx\n"), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+	_, err = LoadConfigFile(path, false)
+	assert.NotNil(t, err, "instance without webserver must not be accepted")
+}
+
+func TestResolve(t *testing.T) {
+	defaults := map[string]string{"server_root": "/etc/nginx", "http_port": "80", "https_port": "443"}
+	file := map[string]string{"server_root": "/opt/nginx", "http_port": "8080", "https_port": "8443"}
+
+	t.Setenv("WEBMNG_HTTP_PORT", "8081")
+	t.Setenv("WEBMNG_HTTPS_PORT", "9443")
+	env := GetEnvOptions([]string{"server_root", "http_port", "https_port"})
+	assert.Equal(t, map[string]string{"http_port": "8081", "https_port": "9443"}, env)
+
+	values, err := Resolve(defaults, map[string]string{"https_port": "10443"}, env, file)
+	assert.Nilf(t, err, "could not resolve options: %v", err)
+	assert.Equal(t, []Value{
+		{Name: "http_port", Value: "8081", Source: SourceEnv},
+		{Name: "https_port", Value: "10443", Source: SourceFlag},
+		{Name: "server_root", Value: "/opt/nginx", Source: SourceFile},
+	}, values)
+
+	values, err = Resolve(defaults, nil, nil, nil)
+	assert.Nilf(t, err, "could not resolve options: %v", err)
+	assert.Empty(t, GetParams(values))
+
+	_, err = Resolve(defaults, nil, nil, map[string]string{"ctl": "apache2ctl"})
+	assert.NotNil(t, err, "unknown option must not be accepted")
+}
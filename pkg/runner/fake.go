@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// FakeResponse is a scripted result of the command run by FakeRunner
+type FakeResponse struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// Err is returned instead of the exit code error, e.g. ErrTimeout
+	Err error
+}
+
+// FakeRunner is a scriptable runner for tests. Commands are matched by their command line.
+// Responses of the same command are returned in order, the last returned one is repeated until new responses are added.
+// Commands without responses fail with exit code 127.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses map[string][]FakeResponse
+	last      map[string]FakeResponse
+	matchers  []fakeMatcher
+	paths     map[string]string
+	calls     []Command
+}
+
+type fakeMatcher struct {
+	pattern  *regexp.Regexp
+	response FakeResponse
+}
+
+// On adds response of the command line, e.g. "nginx -t"
+func (f *FakeRunner) On(commandLine string, response FakeResponse) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[commandLine] = append(f.responses[commandLine], response)
+
+	return f
+}
+
+// OnMatch adds response of the commands which lines match the pattern, e.g. commands with temporary paths.
+// Responses added by On take precedence, the last added matching pattern is used.
+func (f *FakeRunner) OnMatch(pattern string, response FakeResponse) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.matchers = append(f.matchers, fakeMatcher{pattern: regexp.MustCompile(pattern), response: response})
+
+	return f
+}
+
+// AddPath makes the command binary available for LookPath
+func (f *FakeRunner) AddPath(name, path string) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.paths[name] = path
+
+	return f
+}
+
+// GetCalls returns command lines of all commands run in order
+func (f *FakeRunner) GetCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []string
+
+	for _, command := range f.calls {
+		calls = append(calls, command.String())
+	}
+
+	return calls
+}
+
+func (f *FakeRunner) Run(ctx context.Context, command Command) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, command)
+	commandLine := command.String()
+	response, ok := f.last[commandLine]
+
+	if responses := f.responses[commandLine]; len(responses) > 0 {
+		response, ok = responses[0], true
+		f.responses[commandLine] = responses[1:]
+		f.last[commandLine] = response
+	}
+
+	for i := len(f.matchers) - 1; !ok && i >= 0; i-- {
+		if f.matchers[i].pattern.MatchString(commandLine) {
+			response, ok = f.matchers[i].response, true
+		}
+	}
+
+	if !ok {
+		result := Result{Stderr: []byte(commandLine + ": command not found"), ExitCode: 127}
+
+		return result, &Error{Command: command, Result: result, Err: fmt.Errorf("exit status %d", result.ExitCode)}
+	}
+
+	result := Result{Stdout: []byte(response.Stdout), Stderr: []byte(response.Stderr), ExitCode: response.ExitCode}
+
+	if response.Err != nil {
+		return result, &Error{Command: command, Result: result, Err: response.Err}
+	}
+
+	if response.ExitCode != 0 {
+		return result, &Error{Command: command, Result: result, Err: fmt.Errorf("exit status %d", response.ExitCode)}
+	}
+
+	return result, nil
+}
+
+func (f *FakeRunner) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path, ok := f.paths[name]; ok {
+		return path, nil
+	}
+
+	return "", fmt.Errorf("%s: executable file not found in $PATH", name)
+}
+
+// GetFakeRunner returns runner without scripted responses
+func GetFakeRunner() *FakeRunner {
+	return &FakeRunner{
+		responses: make(map[string][]FakeResponse),
+		last:      make(map[string]FakeResponse),
+		paths:     make(map[string]string),
+	}
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is used for commands if no other timeout is specified
+const DefaultTimeout = 2 * time.Minute
+
+// waitDelay is a time to wait for output of the killed command
+const waitDelay = time.Second
+
+// ErrTimeout is returned if the command is not finished in time
+var ErrTimeout = errors.New("command timed out")
+
+// Command is an external command to run
+type Command struct {
+	Name string
+	Args []string
+	// Env contains additional environment variables in KEY=value form
+	Env []string
+	// Timeout overrides the runner timeout if it is not zero
+	Timeout time.Duration
+}
+
+// String returns the command line
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Result is captured output and exit code of the command
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// Output returns stdout followed by stderr
+func (r Result) Output() []byte {
+	return append(append([]byte{}, r.Stdout...), r.Stderr...)
+}
+
+// Error is returned if the command could not be started, failed or timed out
+type Error struct {
+	Command Command
+	Result  Result
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, bytes.TrimSpace(e.Result.Output()))
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Runner runs external commands
+type Runner interface {
+	// Run runs the command and returns its result. Error is returned if the command exits with non-zero code.
+	Run(ctx context.Context, command Command) (Result, error)
+	// LookPath searches for the command binary in PATH
+	LookPath(name string) (string, error)
+}
+
+// ExecRunner runs commands via os/exec
+type ExecRunner struct {
+	timeout time.Duration
+}
+
+func (r ExecRunner) Run(ctx context.Context, command Command) (Result, error) {
+	timeout := command.Timeout
+
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// do not wait for child processes holding output pipes after the command is killed
+	cmd.WaitDelay = waitDelay
+
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+
+	err := cmd.Run()
+	result := Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), ExitCode: -1}
+
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, &Error{Command: command, Result: result, Err: fmt.Errorf("%w after %s: %s", ErrTimeout, timeout, command)}
+	}
+
+	if err != nil {
+		return result, &Error{Command: command, Result: result, Err: err}
+	}
+
+	return result, nil
+}
+
+func (r ExecRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// GetExecRunner returns runner executing commands with the timeout. DefaultTimeout is used if timeout is zero.
+func GetExecRunner(timeout time.Duration) ExecRunner {
+	return ExecRunner{timeout: timeout}
+}
+
+// Run runs the command line with the background context
+func Run(runner Runner, name string, args ...string) (Result, error) {
+	return runner.Run(context.Background(), Command{Name: name, Args: args})
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecRunner(t *testing.T) {
+	runner := GetExecRunner(time.Minute)
+	result, err := runner.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}})
+	assert.Nilf(t, err, "could not run command: %v", err)
+	assert.Equal(t, "out\n", string(result.Stdout))
+	assert.Equal(t, "err\n", string(result.Stderr))
+	assert.Equal(t, 0, result.ExitCode)
+
+	result, err = runner.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo $WEBMNG_TEST; exit 3"}, Env: []string{"WEBMNG_TEST=failed"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "exit status 3: failed", err.Error())
+
+	_, err = runner.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "sleep 5"}, Timeout: 50 * time.Millisecond})
+	assert.True(t, errors.Is(err, ErrTimeout), "command must time out: %v", err)
+
+	_, err = runner.LookPath("sh")
+	assert.Nilf(t, err, "could not find sh: %v", err)
+}
+
+func TestFakeRunner(t *testing.T) {
+	runner := GetFakeRunner().
+		On("nginx -t", FakeResponse{Stderr: "syntax is ok"}).
+		On("nginx -s reload", FakeResponse{Stderr: "reload failed", ExitCode: 1}).
+		On("nginx -s reload", FakeResponse{}).
+		AddPath("nginx", "/usr/sbin/nginx")
+
+	result, err := Run(runner, "nginx", "-t")
+	assert.Nilf(t, err, "could not run command: %v", err)
+	assert.Equal(t, "syntax is ok", string(result.Output()))
+
+	result, err = Run(runner, "nginx", "-s", "reload")
+	assert.Equal(t, "exit status 1: reload failed", err.Error())
+	assert.Equal(t, 1, result.ExitCode)
+
+	for i := 0; i < 2; i++ {
+		_, err = Run(runner, "nginx", "-s", "reload")
+		assert.Nilf(t, err, "last response must be repeated: %v", err)
+	}
+
+	result, err = Run(runner, "nginx", "-V")
+	assert.NotNil(t, err, "unknown command must fail")
+	assert.Equal(t, 127, result.ExitCode)
+
+	path, err := runner.LookPath("nginx")
+	assert.Nilf(t, err, "could not find nginx: %v", err)
+	assert.Equal(t, "/usr/sbin/nginx", path)
+	_, err = runner.LookPath("apache2ctl")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{"nginx -t", "nginx -s reload", "nginx -s reload", "nginx -s reload", "nginx -V"}, runner.GetCalls())
+
+	runner.OnMatch(`^nginx -t -c /tmp/stage-\d+/nginx.conf$`, FakeResponse{Stderr: "syntax is ok"})
+	result, err = Run(runner, "nginx", "-t", "-c", "/tmp/stage-123/nginx.conf")
+	assert.Nilf(t, err, "matched command must succeed: %v", err)
+	assert.Equal(t, "syntax is ok", string(result.Stderr))
+	_, err = Run(runner, "nginx", "-t", "-c", "/etc/nginx/nginx.conf")
+	assert.NotNil(t, err, "not matched command must fail")
+}
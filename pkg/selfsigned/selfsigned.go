@@ -0,0 +1,237 @@
+package selfsigned
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/state"
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+const (
+	DefaultKeyType  = certificate.KeyTypeRSA
+	DefaultValidity = 365 * 24 * time.Hour
+	// maxRenewBefore is a period before the certificate expiration when it is rotated
+	maxRenewBefore = 30 * 24 * time.Hour
+	caValidity     = 10 * 365 * 24 * time.Hour
+	caCommonName   = "webmng local CA"
+	caDir          = "ca"
+	certsDir       = "certificates"
+	caCertFile     = "ca.crt"
+	caKeyFile      = "ca.key"
+)
+
+// Config describes how host certificates are generated
+type Config struct {
+	// KeyType is a certificate key type: rsa, ecdsa
+	KeyType  string
+	Validity time.Duration
+	// OutputDir is a directory for certificate files, <StateDir>/certificates is used if empty
+	OutputDir string
+	// StateDir is a webmng state directory keeping the local CA
+	StateDir string
+	// UseCA signs certificates with the local CA instead of self-signing them
+	UseCA bool
+}
+
+// Result contains files of the host certificate and whether it was (re)generated
+type Result struct {
+	Certificate webserver.Certificate
+	Names       []string
+	NotAfter    time.Time
+	Rotated     bool
+}
+
+// Issue generates a certificate for the names and stores its files to <OutputDir>/<serverName>/<keyType>.
+// The existing certificate is kept if it is not close to expiration, its SANs match the names
+// and it is signed by the configured issuer.
+func Issue(serverName string, names []string, config Config) (*Result, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no names suitable for a certificate found for host '%s'", serverName)
+	}
+
+	config = config.withDefaults()
+
+	if config.KeyType != certificate.KeyTypeRSA && config.KeyType != certificate.KeyTypeECDSA {
+		return nil, fmt.Errorf("unsupported key type '%s', supported types: rsa, ecdsa", config.KeyType)
+	}
+
+	if config.Validity <= 0 {
+		return nil, errors.New("certificate validity must be positive")
+	}
+
+	var issuer *certificate.Issuer
+	var err error
+
+	if config.UseCA {
+		if issuer, err = GetCA(config.StateDir, config.KeyType); err != nil {
+			return nil, err
+		}
+	}
+
+	outputDir := config.OutputDir
+	if outputDir == "" {
+		if outputDir, err = state.GetDir(config.StateDir, certsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(outputDir, strings.ReplaceAll(serverName, "*", "_"), config.KeyType)
+	files := webserver.GetCertificateInDir(dir)
+	result := &Result{Certificate: files, Names: names}
+
+	if leaf := loadValidCertificate(files, names, config, issuer); leaf != nil {
+		result.NotAfter = leaf.NotAfter
+
+		return result, nil
+	}
+
+	key, err := certificate.GenerateKey(config.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := certificate.GenerateCertificate(names, key, config.Validity, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := []*x509.Certificate{leaf}
+
+	if issuer != nil {
+		certificates = append(certificates, issuer.Certificate)
+	}
+
+	if _, err = webserver.WriteCertificate(dir, key, certificates); err != nil {
+		return nil, err
+	}
+
+	result.NotAfter = leaf.NotAfter
+	result.Rotated = true
+
+	return result, nil
+}
+
+// GetCA loads the local CA from the state directory. The CA is created if it does not exist.
+func GetCA(stateDir, keyType string) (*certificate.Issuer, error) {
+	dir, err := state.GetDir(stateDir, caDir)
+	if err != nil {
+		return nil, err
+	}
+
+	certPath := filepath.Join(dir, caCertFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	if _, err = os.Stat(certPath); err == nil {
+		return loadCA(certPath, keyPath)
+	}
+
+	key, err := certificate.GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	caCertificate, err := certificate.GenerateCA(caCommonName, key, caValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeKey(keyPath, key); err != nil {
+		return nil, err
+	}
+
+	if err = os.WriteFile(certPath, certificate.EncodeCertificates([]*x509.Certificate{caCertificate}), 0644); err != nil {
+		return nil, fmt.Errorf("could not write CA certificate %s: %v", certPath, err)
+	}
+
+	return &certificate.Issuer{Certificate: caCertificate, Key: key}, nil
+}
+
+func loadCA(certPath, keyPath string) (*certificate.Issuer, error) {
+	caCertificate, err := certificate.LoadLeafCertificate(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := certificate.LoadPrivateKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load CA key %s: %v", keyPath, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA key %s", keyPath)
+	}
+
+	if err = certificate.CheckKeyPair(certPath, keyPath); err != nil {
+		return nil, err
+	}
+
+	return &certificate.Issuer{Certificate: caCertificate, Key: signer}, nil
+}
+
+// loadValidCertificate returns the existing certificate if it does not need to be rotated
+func loadValidCertificate(files webserver.Certificate, names []string, config Config, issuer *certificate.Issuer) *x509.Certificate {
+	leaf, err := certificate.LoadLeafCertificate(files.CertPath)
+	if err != nil {
+		return nil
+	}
+
+	if certificate.CheckKeyPair(files.CertPath, files.KeyPath) != nil {
+		return nil
+	}
+
+	renewBefore := config.Validity / 3
+	if renewBefore > maxRenewBefore {
+		renewBefore = maxRenewBefore
+	}
+
+	if certificate.NeedsRenewal(leaf, names, renewBefore) {
+		return nil
+	}
+
+	if issuer != nil {
+		err = leaf.CheckSignatureFrom(issuer.Certificate)
+	} else {
+		// CheckSignatureFrom could not be used since the self-signed leaf is not a CA
+		err = leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return leaf
+}
+
+func writeKey(path string, key crypto.PrivateKey) error {
+	data, err := certificate.EncodePrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("could not write private key %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (c Config) withDefaults() Config {
+	if c.KeyType == "" {
+		c.KeyType = DefaultKeyType
+	}
+
+	if c.Validity == 0 {
+		c.Validity = DefaultValidity
+	}
+
+	return c
+}
@@ -0,0 +1,64 @@
+package selfsigned
+
+import (
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueSelfSigned(t *testing.T) {
+	config := Config{KeyType: certificate.KeyTypeECDSA, StateDir: t.TempDir()}
+	names := []string{"example.com", "www.example.com"}
+
+	result, err := Issue("example.com", names, config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+	assert.True(t, result.Rotated)
+
+	leaf, err := certificate.LoadLeafCertificate(result.Certificate.FullChainPath)
+	assert.Nilf(t, err, "could not load certificate: %v", err)
+	assert.Equal(t, names, certificate.GetSubjectNames(leaf))
+
+	keyType, err := certificate.LoadKeyType(result.Certificate.CertPath)
+	assert.Nilf(t, err, "could not load key type: %v", err)
+	assert.Equal(t, certificate.KeyTypeECDSA, keyType)
+
+	err = certificate.CheckKeyPair(result.Certificate.CertPath, result.Certificate.KeyPath)
+	assert.Nilf(t, err, "key must match the certificate: %v", err)
+
+	result, err = Issue("example.com", names, config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+	assert.False(t, result.Rotated, "valid certificate must not be rotated")
+
+	result, err = Issue("example.com", append(names, "api.example.com"), config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+	assert.True(t, result.Rotated, "certificate must be rotated if names changed")
+}
+
+func TestIssueWithLocalCA(t *testing.T) {
+	config := Config{StateDir: t.TempDir(), UseCA: true}
+	names := []string{"example.com"}
+
+	result, err := Issue("example.com", names, config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+
+	ca, err := GetCA(config.StateDir, certificate.KeyTypeRSA)
+	assert.Nilf(t, err, "could not load CA: %v", err)
+
+	fullChain, err := certificate.LoadCertificates(result.Certificate.FullChainPath)
+	assert.Nilf(t, err, "could not load certificate: %v", err)
+	assert.Len(t, fullChain, 2)
+	assert.Nil(t, fullChain[0].CheckSignatureFrom(ca.Certificate), "certificate must be signed by the local CA")
+
+	err = certificate.ValidateCABundle(result.Certificate.ChainPath)
+	assert.Nilf(t, err, "chain must contain the local CA: %v", err)
+
+	result, err = Issue("example.com", names, config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+	assert.False(t, result.Rotated)
+
+	config.UseCA = false
+	result, err = Issue("example.com", names, config)
+	assert.Nilf(t, err, "could not issue certificate: %v", err)
+	assert.True(t, result.Rotated, "certificate must be rotated if issuer changed")
+}
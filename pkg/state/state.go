@@ -0,0 +1,25 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDir is a directory where webmng keeps its data: local CA, generated certificates
+const DefaultDir = "/var/lib/webmng"
+
+// GetDir returns the sub directory of the state directory. The directory is created if it does not exist.
+func GetDir(stateDir string, elem ...string) (string, error) {
+	if stateDir == "" {
+		stateDir = DefaultDir
+	}
+
+	dir := filepath.Join(append([]string{stateDir}, elem...)...)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("could not create state directory %s: %v", dir, err)
+	}
+
+	return dir, nil
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
@@ -92,3 +93,21 @@ func IsRhelOsFamily() (bool, error) {
 
 	return info.PlatformFamily == "rhel" || info.Platform == "almalinux", nil
 }
+
+// ReplaceSymlink atomically points the symlink at the target. The symlink is created if it does not exist.
+func ReplaceSymlink(target, linkPath string) error {
+	tmpLinkPath := linkPath + ".tmp"
+	os.Remove(tmpLinkPath)
+
+	if err := os.Symlink(target, tmpLinkPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpLinkPath, linkPath); err != nil {
+		os.Remove(tmpLinkPath)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package webserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AcmeChallengePath is the URL path HTTP-01 challenge tokens are served from
+const AcmeChallengePath = "/.well-known/acme-challenge/"
+
+const acmeSelfTestTimeout = 10 * time.Second
+
+// GetAcmeChallengeDir returns the directory of challenge tokens within the webroot
+func GetAcmeChallengeDir(webroot string) string {
+	return filepath.Join(webroot, filepath.FromSlash(strings.Trim(AcmeChallengePath, "/")))
+}
+
+// GetHttpPort returns the port of the first non-ssl host address, 80 is used by default
+func GetHttpPort(hosts []Host) string {
+	for _, host := range hosts {
+		if host.Ssl {
+			continue
+		}
+
+		for _, address := range host.Addresses {
+			if address.Port != "" {
+				return address.Port
+			}
+		}
+	}
+
+	return "80"
+}
+
+// CheckAcmeWebroot drops a token file into the webroot and requests it from the local webserver
+// on behalf of the server name. Redirects are followed, but all connections go to the local webserver.
+func CheckAcmeWebroot(serverName, webroot, httpPort string) error {
+	challengeDir := GetAcmeChallengeDir(webroot)
+
+	if err := os.MkdirAll(challengeDir, 0755); err != nil {
+		return fmt.Errorf("could not create challenge directory %s: %v", challengeDir, err)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return err
+	}
+
+	token := "webmng-" + hex.EncodeToString(tokenBytes)
+	tokenPath := filepath.Join(challengeDir, token)
+
+	if err := os.WriteFile(tokenPath, []byte(token), 0644); err != nil {
+		return fmt.Errorf("could not write challenge token %s: %v", tokenPath, err)
+	}
+	defer os.Remove(tokenPath)
+
+	body, err := getLocal(fmt.Sprintf("http://%s%s%s", net.JoinHostPort(serverName, httpPort), AcmeChallengePath, token))
+	if err != nil {
+		return fmt.Errorf("could not get challenge token for '%s': %v", serverName, err)
+	}
+
+	if strings.TrimSpace(body) != token {
+		return fmt.Errorf("challenge token for '%s' is not served from %s", serverName, challengeDir)
+	}
+
+	return nil
+}
+
+// getLocal requests the URL from the local webserver
+func getLocal(url string) (string, error) {
+	dialer := net.Dialer{Timeout: acmeSelfTestTimeout}
+	client := http.Client{
+		Timeout: acmeSelfTestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+
+				return dialer.DialContext(ctx, network, net.JoinHostPort("127.0.0.1", port))
+			},
+			// certificate is not issued yet, only routing is checked
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	response, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status %s", response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,36 @@
+package webserver
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/webserver/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAcmeWebroot(t *testing.T) {
+	webroot := t.TempDir()
+	server := httptest.NewServer(http.FileServer(http.Dir(webroot)))
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.Nilf(t, err, "could not get server port: %v", err)
+
+	err = CheckAcmeWebroot("example.com", webroot, port)
+	assert.Nilf(t, err, "challenge token must be served: %v", err)
+
+	err = CheckAcmeWebroot("example.com", t.TempDir(), port)
+	assert.NotNil(t, err, "challenge token must not be served from another directory")
+}
+
+func TestGetHttpPort(t *testing.T) {
+	hosts := []Host{
+		{Ssl: true, Addresses: map[string]host.Address{"*:443": {Host: "*", Port: "443"}}},
+		{Addresses: map[string]host.Address{"*:8080": {Host: "*", Port: "8080"}}},
+	}
+
+	assert.Equal(t, "8080", GetHttpPort(hosts))
+	assert.Equal(t, "80", GetHttpPort(hosts[:1]))
+}
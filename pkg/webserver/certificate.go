@@ -0,0 +1,127 @@
+package webserver
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"golang.org/x/exp/slices"
+)
+
+// certbot compatible names of certificate files
+const (
+	certFile      = "cert.pem"
+	chainFile     = "chain.pem"
+	fullChainFile = "fullchain.pem"
+	keyFile       = "privkey.pem"
+)
+
+// Certificate contains files of a certificate to deploy
+type Certificate struct {
+	CertPath,
+	KeyPath,
+	ChainPath,
+	FullChainPath string
+}
+
+// GetLeafPath returns path of the file containing the leaf certificate
+func (c Certificate) GetLeafPath() string {
+	if c.CertPath != "" {
+		return c.CertPath
+	}
+
+	return c.FullChainPath
+}
+
+// GetCertificateInDir returns paths of certificate files stored in the directory by WriteCertificate
+func GetCertificateInDir(dir string) Certificate {
+	return Certificate{
+		CertPath:      filepath.Join(dir, certFile),
+		KeyPath:       filepath.Join(dir, keyFile),
+		ChainPath:     filepath.Join(dir, chainFile),
+		FullChainPath: filepath.Join(dir, fullChainFile),
+	}
+}
+
+// WriteCertificate writes the key and the certificates to the directory. The first certificate is a leaf one,
+// the others are issuers. The leaf certificate is used as a chain if it is self-signed.
+func WriteCertificate(dir string, key crypto.PrivateKey, certificates []*x509.Certificate) (Certificate, error) {
+	if len(certificates) == 0 {
+		return Certificate{}, errors.New("no certificates to write")
+	}
+
+	files := GetCertificateInDir(dir)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return files, err
+	}
+
+	keyData, err := certificate.EncodePrivateKey(key)
+	if err != nil {
+		return files, err
+	}
+
+	if err = os.WriteFile(files.KeyPath, keyData, 0600); err != nil {
+		return files, fmt.Errorf("could not write private key %s: %v", files.KeyPath, err)
+	}
+
+	chain := certificates[1:]
+	if len(chain) == 0 {
+		chain = certificates
+	}
+
+	contents := map[string][]byte{
+		files.CertPath:      certificate.EncodeCertificates(certificates[:1]),
+		files.ChainPath:     certificate.EncodeCertificates(chain),
+		files.FullChainPath: certificate.EncodeCertificates(certificates),
+	}
+
+	for path, content := range contents {
+		if err = os.WriteFile(path, content, 0644); err != nil {
+			return files, fmt.Errorf("could not write certificate %s: %v", path, err)
+		}
+	}
+
+	return files, nil
+}
+
+// GetCertificatesKeyTypes returns key type of each certificate: rsa, ecdsa.
+// Certificate keys are validated and several certificates with the same key type are not allowed.
+func GetCertificatesKeyTypes(certificates []Certificate) ([]string, error) {
+	if len(certificates) == 0 {
+		return nil, errors.New("no certificates specified")
+	}
+
+	var keyTypes []string
+
+	for _, cert := range certificates {
+		leafPath := cert.GetLeafPath()
+
+		if leafPath == "" {
+			return nil, errors.New("certificate path is not specified")
+		}
+
+		keyType, err := certificate.LoadKeyType(leafPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(keyTypes, keyType) {
+			return nil, fmt.Errorf("several certificates with %s key are specified", keyType)
+		}
+
+		if cert.KeyPath != "" {
+			if err := certificate.CheckKeyPair(leafPath, cert.KeyPath); err != nil {
+				return nil, err
+			}
+		}
+
+		keyTypes = append(keyTypes, keyType)
+	}
+
+	return keyTypes, nil
+}
@@ -0,0 +1,33 @@
+package webserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const certificateDir = "../../test/certificate"
+
+func TestGetCertificatesKeyTypes(t *testing.T) {
+	certificate := Certificate{
+		KeyPath:       certificateDir + "/example.com.key",
+		FullChainPath: certificateDir + "/example.com.crt",
+	}
+
+	keyTypes, err := GetCertificatesKeyTypes([]Certificate{certificate})
+	assert.Nilf(t, err, "could not get key types: %v", err)
+	assert.Equal(t, []string{"ecdsa"}, keyTypes)
+
+	_, err = GetCertificatesKeyTypes([]Certificate{certificate, certificate})
+	assert.NotNil(t, err, "certificates with the same key type must not be allowed")
+
+	issuer := Certificate{
+		CertPath: certificateDir + "/example.com.issuer.crt",
+		KeyPath:  certificateDir + "/example.com.key",
+	}
+	_, err = GetCertificatesKeyTypes([]Certificate{certificate, issuer})
+	assert.NotNil(t, err, "key must match the certificate")
+
+	_, err = GetCertificatesKeyTypes(nil)
+	assert.NotNil(t, err)
+}
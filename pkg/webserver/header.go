@@ -0,0 +1,9 @@
+package webserver
+
+import "github.com/r2dtools/webmng/pkg/webserver/headers"
+
+// HostHeaders contains response headers effective for a host
+type HostHeaders struct {
+	Host    Host
+	Headers []headers.Header
+}
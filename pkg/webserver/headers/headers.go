@@ -0,0 +1,184 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Security response headers
+const (
+	StrictTransportSecurity = "Strict-Transport-Security"
+	XContentTypeOptions     = "X-Content-Type-Options"
+	XFrameOptions           = "X-Frame-Options"
+	ReferrerPolicy          = "Referrer-Policy"
+	PermissionsPolicy       = "Permissions-Policy"
+	ContentSecurityPolicy   = "Content-Security-Policy"
+)
+
+const (
+	DefaultHstsMaxAge = 63072000
+	// hstsPreloadMinMaxAge is a minimal max-age accepted by https://hstspreload.org
+	hstsPreloadMinMaxAge = 31536000
+)
+
+var nameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+var hstsMaxAgeRegex = regexp.MustCompile(`(?i)^max-age=(\d+)$`)
+
+var frameOptions = []string{"DENY", "SAMEORIGIN"}
+var referrerPolicies = []string{
+	"no-referrer",
+	"no-referrer-when-downgrade",
+	"origin",
+	"origin-when-cross-origin",
+	"same-origin",
+	"strict-origin",
+	"strict-origin-when-cross-origin",
+	"unsafe-url",
+}
+
+// Header is a response header set by a webserver
+type Header struct {
+	Name,
+	Value string
+}
+
+// ToString returns header in the HTTP format: "Name: value"
+func (h Header) ToString() string {
+	return fmt.Sprintf("%s: %s", h.Name, h.Value)
+}
+
+// Validate checks header name and value. Values of the known security headers are validated against their syntax.
+func (h Header) Validate() error {
+	if !nameRegex.MatchString(h.Name) {
+		return fmt.Errorf("invalid header name '%s'", h.Name)
+	}
+
+	if strings.TrimSpace(h.Value) == "" {
+		return fmt.Errorf("header %s value is empty", h.Name)
+	}
+
+	if strings.ContainsAny(h.Value, "\r\n") {
+		return fmt.Errorf("header %s value contains line break", h.Name)
+	}
+
+	switch CanonicalName(h.Name) {
+	case StrictTransportSecurity:
+		return validateHsts(h.Value)
+	case XContentTypeOptions:
+		if !strings.EqualFold(h.Value, "nosniff") {
+			return fmt.Errorf("%s value must be 'nosniff'", XContentTypeOptions)
+		}
+	case XFrameOptions:
+		if !slices.Contains(frameOptions, strings.ToUpper(h.Value)) {
+			return fmt.Errorf("%s value must be one of: %s", XFrameOptions, strings.Join(frameOptions, ", "))
+		}
+	case ReferrerPolicy:
+		for _, policy := range strings.Split(h.Value, ",") {
+			if !slices.Contains(referrerPolicies, strings.ToLower(strings.TrimSpace(policy))) {
+				return fmt.Errorf("unknown %s '%s'", ReferrerPolicy, strings.TrimSpace(policy))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse parses header in the HTTP format: "Name: value"
+func Parse(header string) (Header, error) {
+	name, value, ok := strings.Cut(header, ":")
+
+	if !ok {
+		return Header{}, fmt.Errorf("invalid header '%s', expected format is 'Name: value'", header)
+	}
+
+	return Header{Name: CanonicalName(strings.TrimSpace(name)), Value: strings.TrimSpace(value)}, nil
+}
+
+// GetHstsHeader returns Strict-Transport-Security header
+func GetHstsHeader(maxAge int, includeSubDomains, preload bool) (Header, error) {
+	if preload && !includeSubDomains {
+		return Header{}, errors.New("HSTS preload requires includeSubDomains")
+	}
+
+	if preload && maxAge < hstsPreloadMinMaxAge {
+		return Header{}, fmt.Errorf("HSTS preload requires max-age of at least %d seconds", hstsPreloadMinMaxAge)
+	}
+
+	value := fmt.Sprintf("max-age=%d", maxAge)
+
+	if includeSubDomains {
+		value += "; includeSubDomains"
+	}
+
+	if preload {
+		value += "; preload"
+	}
+
+	return Header{Name: StrictTransportSecurity, Value: value}, nil
+}
+
+// CanonicalName returns canonical header name, e.g. x-frame-options -> X-Frame-Options
+func CanonicalName(name string) string {
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+// IsSecureOnly returns true if the header is meaningful for https hosts only
+func IsSecureOnly(name string) bool {
+	return CanonicalName(name) == StrictTransportSecurity
+}
+
+// Quote quotes header value for webserver configuration files
+func Quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// Unquote removes quotes added by Quote
+func Unquote(value string) string {
+	if len(value) < 2 || (value[0] != '"' && value[0] != '\'') || value[len(value)-1] != value[0] {
+		return value
+	}
+
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, `\"`, `"`)
+
+	return strings.ReplaceAll(value, `\\`, `\`)
+}
+
+func validateHsts(value string) error {
+	var maxAgeFound, includeSubDomains, preload bool
+	var maxAge int
+
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+
+		switch {
+		case part == "":
+			continue
+		case hstsMaxAgeRegex.MatchString(part):
+			maxAgeFound = true
+			maxAge, _ = strconv.Atoi(hstsMaxAgeRegex.FindStringSubmatch(part)[1])
+		case strings.EqualFold(part, "includeSubDomains"):
+			includeSubDomains = true
+		case strings.EqualFold(part, "preload"):
+			preload = true
+		default:
+			return fmt.Errorf("unknown %s directive '%s'", StrictTransportSecurity, part)
+		}
+	}
+
+	if !maxAgeFound {
+		return fmt.Errorf("%s requires max-age", StrictTransportSecurity)
+	}
+
+	_, err := GetHstsHeader(maxAge, includeSubDomains, preload)
+
+	return err
+}
@@ -0,0 +1,56 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	header, err := Parse("x-frame-options:  DENY ")
+	assert.Nilf(t, err, "could not parse header: %v", err)
+	assert.Equal(t, Header{Name: XFrameOptions, Value: "DENY"}, header)
+
+	_, err = Parse("X-Frame-Options DENY")
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	items := []struct {
+		header Header
+		valid  bool
+	}{
+		{Header{XFrameOptions, "SAMEORIGIN"}, true},
+		{Header{XFrameOptions, "ALLOW-FROM https://example.com"}, false},
+		{Header{XContentTypeOptions, "nosniff"}, true},
+		{Header{ReferrerPolicy, "no-referrer, strict-origin-when-cross-origin"}, true},
+		{Header{ReferrerPolicy, "never"}, false},
+		{Header{StrictTransportSecurity, "max-age=63072000; includeSubDomains; preload"}, true},
+		{Header{StrictTransportSecurity, "max-age=63072000; preload"}, false},
+		{Header{StrictTransportSecurity, "includeSubDomains"}, false},
+		{Header{ContentSecurityPolicy, "default-src 'self'"}, true},
+		{Header{"Bad Name", "value"}, false},
+		{Header{PermissionsPolicy, ""}, false},
+	}
+
+	for _, item := range items {
+		err := item.header.Validate()
+		assert.Equalf(t, item.valid, err == nil, "header '%s': %v", item.header.ToString(), err)
+	}
+}
+
+func TestGetHstsHeader(t *testing.T) {
+	header, err := GetHstsHeader(DefaultHstsMaxAge, true, true)
+	assert.Nilf(t, err, "could not get HSTS header: %v", err)
+	assert.Equal(t, "max-age=63072000; includeSubDomains; preload", header.Value)
+
+	_, err = GetHstsHeader(300, true, true)
+	assert.NotNil(t, err)
+}
+
+func TestQuote(t *testing.T) {
+	value := `geolocation=(self "https://example.com")`
+	assert.Equal(t, `"geolocation=(self \"https://example.com\")"`, Quote(value))
+	assert.Equal(t, value, Unquote(Quote(value)))
+	assert.Equal(t, "DENY", Unquote("DENY"))
+}
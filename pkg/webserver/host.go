@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/r2dtools/webmng/pkg/webserver/host"
+	"golang.org/x/exp/slices"
 )
 
 type Host struct {
@@ -59,3 +60,25 @@ func (h *Host) IsIpv4Enabled() bool {
 
 	return false
 }
+
+// GetHostsNames returns unique server names and aliases of the hosts suitable for certificate SANs.
+// Regular expression names and the catch-all "_" name are skipped.
+func GetHostsNames(hosts []Host) []string {
+	var names []string
+
+	for _, host := range hosts {
+		for _, name := range append([]string{host.ServerName}, host.Aliases...) {
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if name == "" || name == "_" || strings.HasPrefix(name, "~") {
+				continue
+			}
+
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
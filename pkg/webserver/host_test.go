@@ -23,3 +23,13 @@ func TestGetConfigName(t *testing.T) {
 		assert.Equal(t, item.configName, configName)
 	}
 }
+
+func TestGetHostsNames(t *testing.T) {
+	hosts := []Host{
+		{ServerName: "example.com", Aliases: []string{"www.example.com", "~^(?<sub>.+)\\.example\\.com$"}},
+		{ServerName: "Example.com", Aliases: []string{"192.168.0.1"}},
+		{ServerName: "_"},
+	}
+
+	assert.Equal(t, []string{"example.com", "www.example.com", "192.168.0.1"}, GetHostsNames(hosts))
+}
@@ -0,0 +1,274 @@
+package webserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/unknwon/com"
+)
+
+const (
+	LintDuplicateServerName   = "duplicate-server-name"
+	LintNoListen              = "no-listen"
+	LintSslWithoutCertificate = "ssl-without-certificate"
+	LintAliasMismatch         = "alias-mismatch"
+	LintMissingDocRoot        = "missing-docroot"
+	LintBackupFileIncluded    = "backup-file-included"
+	LintSslDirectiveConflict  = "ssl-directive-conflict"
+	LintMissingModule         = "missing-module"
+)
+
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityNone:    "none",
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ExitCode returns process exit code for the severity: 0 - none/info, 1 - warning, 2 - error
+func (s Severity) ExitCode() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Finding is a single problem found in the webserver configuration
+type Finding struct {
+	Severity   Severity `json:"severity"`
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	ServerName string   `json:"serverName,omitempty"`
+}
+
+func (f Finding) ToString() string {
+	location := f.File
+
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+
+	return fmt.Sprintf("%s\t%s\t[%s] %s", f.Severity, location, f.Code, f.Message)
+}
+
+// LintHost is a host with additional information required by the common lint checks
+type LintHost struct {
+	Host
+	// Line is a line number of the host block in the host config file
+	Line int
+	// Certificates contains paths of the certificates effective for the host
+	Certificates []string
+}
+
+// GetHighestSeverity returns the highest severity of the findings
+func GetHighestSeverity(findings []Finding) Severity {
+	severity := SeverityNone
+
+	for _, finding := range findings {
+		if finding.Severity > severity {
+			severity = finding.Severity
+		}
+	}
+
+	return severity
+}
+
+// SortFindings sorts findings by file and line
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+
+		return findings[i].Line < findings[j].Line
+	})
+}
+
+// LintHosts runs checks that do not depend on the webserver type. Paths are checked relative to the alternate root if it is not empty.
+func LintHosts(hosts []LintHost, root string) []Finding {
+	var findings []Finding
+
+	findings = append(findings, lintDuplicateServerNames(hosts)...)
+	findings = append(findings, lintAliasMismatch(hosts)...)
+
+	for _, host := range hosts {
+		if len(host.Addresses) == 0 {
+			findings = append(findings, host.finding(
+				SeverityWarning,
+				LintNoListen,
+				"host has no listen address, the default one is used",
+			))
+		}
+
+		if host.Ssl && len(host.Certificates) == 0 {
+			findings = append(findings, host.finding(
+				SeverityError,
+				LintSslWithoutCertificate,
+				"ssl host has no certificate directives",
+			))
+		}
+
+		// document root with variables could not be resolved statically
+		if host.DocRoot != "" && !strings.Contains(host.DocRoot, "$") && !com.IsDir(options.GetRootedPath(root, host.DocRoot)) {
+			findings = append(findings, host.finding(
+				SeverityWarning,
+				LintMissingDocRoot,
+				fmt.Sprintf("document root '%s' does not exist", host.DocRoot),
+			))
+		}
+	}
+
+	return findings
+}
+
+func lintDuplicateServerNames(hosts []LintHost) []Finding {
+	var findings []Finding
+	seen := make(map[string]LintHost)
+
+	for _, host := range hosts {
+		var names []string
+
+		for _, name := range append([]string{host.ServerName}, host.Aliases...) {
+			if name != "" && !com.IsSliceContainsStr(names, name) {
+				names = append(names, name)
+			}
+		}
+
+		for _, address := range host.Addresses {
+			for _, name := range names {
+				key := fmt.Sprintf("%s:%s|%s", address.GetNormalizedHost(), address.Port, strings.ToLower(name))
+				prevHost, ok := seen[key]
+
+				if !ok {
+					seen[key] = host
+					continue
+				}
+
+				if prevHost.FilePath == host.FilePath && prevHost.Line == host.Line {
+					continue
+				}
+
+				findings = append(findings, host.finding(
+					SeverityError,
+					LintDuplicateServerName,
+					fmt.Sprintf("server name '%s' on address '%s' is already defined in %s:%d", name, address.ToString(), prevHost.FilePath, prevHost.Line),
+				))
+			}
+		}
+	}
+
+	return findings
+}
+
+func lintAliasMismatch(hosts []LintHost) []Finding {
+	var findings []Finding
+	httpHosts := make(map[string]LintHost)
+
+	for _, host := range hosts {
+		if !host.Ssl && host.ServerName != "" {
+			if _, ok := httpHosts[host.ServerName]; !ok {
+				httpHosts[host.ServerName] = host
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		if !host.Ssl {
+			continue
+		}
+
+		httpHost, ok := httpHosts[host.ServerName]
+
+		if !ok {
+			continue
+		}
+
+		missing := getMissingNames(httpHost.Aliases, host.Aliases)
+		extra := getMissingNames(host.Aliases, httpHost.Aliases)
+
+		if len(missing) == 0 && len(extra) == 0 {
+			continue
+		}
+
+		var details []string
+
+		if len(missing) > 0 {
+			details = append(details, fmt.Sprintf("missing in https host: %s", strings.Join(missing, ", ")))
+		}
+
+		if len(extra) > 0 {
+			details = append(details, fmt.Sprintf("missing in http host: %s", strings.Join(extra, ", ")))
+		}
+
+		findings = append(findings, host.finding(
+			SeverityWarning,
+			LintAliasMismatch,
+			fmt.Sprintf("aliases of http host %s:%d and https host differ (%s)", httpHost.FilePath, httpHost.Line, strings.Join(details, "; ")),
+		))
+	}
+
+	return findings
+}
+
+// getMissingNames returns names from a that are absent in b, case insensitive
+func getMissingNames(a, b []string) []string {
+	var missing []string
+
+	for _, name := range a {
+		found := false
+
+		for _, bName := range b {
+			if strings.EqualFold(name, bName) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
+func (h LintHost) finding(severity Severity, code, message string) Finding {
+	return Finding{
+		Severity:   severity,
+		Code:       code,
+		Message:    message,
+		File:       h.FilePath,
+		Line:       h.Line,
+		ServerName: h.ServerName,
+	}
+}
@@ -0,0 +1,78 @@
+package webserver
+
+import (
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/webserver/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintHosts(t *testing.T) {
+	address := host.CreateHostAddressFromString("*:80")
+	sslAddress := host.CreateHostAddressFromString("*:443")
+	docRoot := t.TempDir()
+
+	hosts := []LintHost{
+		{
+			Host: Host{
+				FilePath:   "/etc/webserver/sites-enabled/example.com.conf",
+				ServerName: "example.com",
+				DocRoot:    docRoot,
+				Aliases:    []string{"www.example.com"},
+				Addresses:  map[string]host.Address{address.GetHash(): address},
+			},
+			Line: 1,
+		},
+		{
+			Host: Host{
+				FilePath:   "/etc/webserver/sites-enabled/example.com.conf",
+				ServerName: "example.com",
+				DocRoot:    "/not/existed/docroot",
+				Addresses:  map[string]host.Address{address.GetHash(): address},
+			},
+			Line: 10,
+		},
+		{
+			Host: Host{
+				FilePath:   "/etc/webserver/sites-enabled/example-ssl.com.conf",
+				ServerName: "example.com",
+				DocRoot:    docRoot,
+				Addresses:  map[string]host.Address{sslAddress.GetHash(): sslAddress},
+				Ssl:        true,
+			},
+			Line: 1,
+		},
+		{
+			Host: Host{
+				FilePath:   "/etc/webserver/sites-enabled/example2.com.conf",
+				ServerName: "example2.com",
+			},
+			Line:         1,
+			Certificates: []string{"/etc/ssl/example2.com.crt"},
+		},
+	}
+
+	findings := LintHosts(hosts, "")
+	codes := make(map[string]int)
+
+	for _, finding := range findings {
+		codes[finding.Code]++
+	}
+
+	assert.Equal(t, 1, codes[LintDuplicateServerName])
+	assert.Equal(t, 1, codes[LintMissingDocRoot])
+	assert.Equal(t, 1, codes[LintSslWithoutCertificate])
+	assert.Equal(t, 1, codes[LintAliasMismatch])
+	assert.Equal(t, 1, codes[LintNoListen])
+	assert.Equal(t, SeverityError, GetHighestSeverity(findings))
+	assert.Equal(t, 2, GetHighestSeverity(findings).ExitCode())
+}
+
+func TestGetHighestSeverity(t *testing.T) {
+	assert.Equal(t, SeverityNone, GetHighestSeverity(nil))
+	assert.Equal(t, 0, GetHighestSeverity(nil).ExitCode())
+
+	findings := []Finding{{Severity: SeverityInfo}, {Severity: SeverityWarning}}
+	assert.Equal(t, SeverityWarning, GetHighestSeverity(findings))
+	assert.Equal(t, 1, GetHighestSeverity(findings).ExitCode())
+}
@@ -0,0 +1,272 @@
+// Package lock provides advisory locking of webserver instances, so concurrent webmng runs do not overwrite each other's changes
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/state"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+)
+
+// Mode is a lock mode
+type Mode int
+
+const (
+	// Shared lock is taken by read-only operations. Several shared locks may be held at once.
+	Shared Mode = iota
+	// Exclusive lock is taken by operations changing the configuration or the webserver state
+	Exclusive
+)
+
+// DefaultDir is a directory of lock files. The locks directory of webmng state is used if /run/lock does not exist.
+const DefaultDir = "/run/lock/webmng"
+
+const stateLocksDir = "locks"
+
+const pollInterval = 100 * time.Millisecond
+
+// HeldError is returned if the lock is held by another process. Pid is zero if the holder is unknown, e.g. the lock is shared.
+type HeldError struct {
+	Path    string
+	Pid     int
+	Command string
+	// Waited is a time spent waiting for the lock
+	Waited time.Duration
+}
+
+func (e HeldError) Error() string {
+	holder := "another process"
+
+	if e.Pid != 0 {
+		holder = fmt.Sprintf("pid %d", e.Pid)
+
+		if e.Command != "" {
+			holder += fmt.Sprintf(" (%s)", e.Command)
+		}
+	}
+
+	message := fmt.Sprintf("webserver configuration is locked by %s, lock file %s", holder, e.Path)
+
+	if e.Waited > 0 {
+		message += fmt.Sprintf(": gave up waiting after %s", e.Waited)
+	}
+
+	return message
+}
+
+// Lock is an acquired lock. It must be released after the operation is finished.
+type Lock struct {
+	Path string
+	Mode Mode
+	file *os.File
+	// owned is set if the lock file is owned by the caller, holder info is written only to the own lock file
+	owned bool
+}
+
+// Release releases the lock. Holder info written by the exclusive lock is cleared.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	if l.Mode == Exclusive && l.owned {
+		l.file.Truncate(0)
+	}
+
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	l.file = nil
+
+	return err
+}
+
+// Acquire acquires the lock of the lock file. The lock is retried until timeout is elapsed, zero timeout means no waiting.
+// The exclusive lock holder writes its pid and command to the lock file, so other processes can report who holds the lock.
+func Acquire(path string, mode Mode, timeout time.Duration) (*Lock, error) {
+	if err := checkDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	file, owned, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_SH
+
+	if mode == Exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	start := time.Now()
+
+	for {
+		err = syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+
+			return nil, fmt.Errorf("could not lock %s: %v", path, err)
+		}
+
+		if time.Since(start) >= timeout {
+			pid, command := readHolder(file)
+			file.Close()
+
+			return nil, HeldError{Path: path, Pid: pid, Command: command, Waited: timeout}
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	if mode == Exclusive && owned {
+		writeHolder(file)
+	}
+
+	return &Lock{Path: path, Mode: mode, file: file, owned: owned}, nil
+}
+
+// AcquireInstance acquires the lock of the webserver instance with the server root and main config file detected by the manager or cli
+func AcquireInstance(o options.Options, webServer, serverRoot, configFile string, mode Mode) (*Lock, error) {
+	return Acquire(GetPath(o, webServer, serverRoot, configFile), mode, webserverOptions.GetLockTimeout(o))
+}
+
+// GetPath returns the lock file of the webserver instance. The lock file option is used if set.
+// Otherwise, instances are distinguished by the resolved server root and main config file,
+// so the same instance has the same lock however its paths are specified, e.g. detected or set via options.
+func GetPath(o options.Options, webServer, serverRoot, configFile string) string {
+	if path := o.Get(webserverOptions.LockFile); path != "" {
+		return path
+	}
+
+	dir := DefaultDir
+
+	if _, err := os.Stat(filepath.Dir(dir)); err != nil {
+		dir = filepath.Join(state.DefaultDir, stateLocksDir)
+	}
+
+	hash := sha256.Sum256([]byte(resolvePath(serverRoot) + "\x00" + resolvePath(configFile)))
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.lock", webServer, hex.EncodeToString(hash[:])[:12]))
+}
+
+// resolvePath returns the absolute path with symlinks evaluated. The cleaned path is returned if it does not exist.
+func resolvePath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+
+	if realPath, err := filepath.EvalSymlinks(path); err == nil {
+		return realPath
+	}
+
+	return filepath.Clean(path)
+}
+
+// checkDir creates the lock directory accessible by its owner only. Existing directory must be owned by the caller or root
+// and must not be writable by others, otherwise lock files could be replaced with symlinks to the files truncated by the holder.
+func checkDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create lock directory: %v", err)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("could not check lock directory: %v", err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !info.IsDir() || !ok {
+		return fmt.Errorf("lock directory %s is not a directory", dir)
+	}
+
+	if uid := uint32(os.Geteuid()); stat.Uid != uid && stat.Uid != 0 {
+		return fmt.Errorf("lock directory %s is not owned by the current user or root", dir)
+	}
+
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("lock directory %s must not be writable by group or others", dir)
+	}
+
+	return nil
+}
+
+// openFile opens the lock file without following symlinks. Lock file of another user is opened for reading only:
+// it still could be locked, but holder info is not written to it.
+func openFile(path string) (*os.File, bool, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|syscall.O_NOFOLLOW, 0600)
+	if errors.Is(err, os.ErrPermission) {
+		file, err = os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("could not open lock file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, false, fmt.Errorf("could not check lock file: %v", err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !info.Mode().IsRegular() || !ok {
+		file.Close()
+
+		return nil, false, fmt.Errorf("lock file %s is not a regular file", path)
+	}
+
+	return file, stat.Uid == uint32(os.Geteuid()), nil
+}
+
+func writeHolder(file *os.File) {
+	if err := file.Truncate(0); err != nil {
+		return
+	}
+
+	command := strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " ")
+	file.WriteAt([]byte(fmt.Sprintf("%d\n%s\n", os.Getpid(), command)), 0)
+}
+
+func readHolder(file *os.File) (int, string) {
+	content, err := io.ReadAll(io.NewSectionReader(file, 0, 4096))
+	if err != nil {
+		return 0, ""
+	}
+
+	pidLine, command, _ := strings.Cut(string(content), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+
+	// holder info is left by the process terminated without releasing the lock, the lock is held by a shared holder then
+	if err != nil || syscall.Kill(pid, 0) == syscall.ESRCH {
+		return 0, ""
+	}
+
+	return pid, strings.TrimSpace(command)
+}
@@ -0,0 +1,131 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/options"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExclusiveLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "nginx.lock")
+
+	exclusiveLock, err := Acquire(path, Exclusive, 0)
+	assert.Nilf(t, err, "could not acquire exclusive lock: %v", err)
+
+	_, err = Acquire(path, Exclusive, 0)
+	var heldErr HeldError
+	assert.True(t, errors.As(err, &heldErr), "held lock must be reported")
+	assert.Equal(t, os.Getpid(), heldErr.Pid)
+	assert.Equal(t, path, heldErr.Path)
+	assert.True(t, strings.HasPrefix(heldErr.Command, filepath.Base(os.Args[0])), "holder command must be reported")
+	assert.Contains(t, err.Error(), "pid")
+
+	_, err = Acquire(path, Shared, 0)
+	assert.NotNil(t, err, "shared lock must not be acquired while exclusive lock is held")
+
+	assert.Nil(t, exclusiveLock.Release())
+	assert.Nil(t, exclusiveLock.Release(), "released lock must be released again without error")
+
+	content, err := os.ReadFile(path)
+	assert.Nilf(t, err, "could not read lock file: %v", err)
+	assert.Empty(t, content, "holder info must be cleared on release")
+
+	exclusiveLock, err = Acquire(path, Exclusive, 0)
+	assert.Nilf(t, err, "could not acquire released lock: %v", err)
+	assert.Nil(t, exclusiveLock.Release())
+}
+
+func TestSharedLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.lock")
+
+	firstLock, err := Acquire(path, Shared, 0)
+	assert.Nilf(t, err, "could not acquire shared lock: %v", err)
+	defer firstLock.Release()
+
+	secondLock, err := Acquire(path, Shared, 0)
+	assert.Nilf(t, err, "several shared locks must be held at once: %v", err)
+	defer secondLock.Release()
+
+	_, err = Acquire(path, Exclusive, 0)
+	var heldErr HeldError
+	assert.True(t, errors.As(err, &heldErr), "held lock must be reported")
+	assert.Equal(t, 0, heldErr.Pid, "shared lock holder is unknown")
+	assert.Contains(t, err.Error(), "another process")
+}
+
+func TestLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.lock")
+
+	exclusiveLock, err := Acquire(path, Exclusive, 0)
+	assert.Nilf(t, err, "could not acquire exclusive lock: %v", err)
+
+	start := time.Now()
+	_, err = Acquire(path, Exclusive, 300*time.Millisecond)
+	assert.NotNil(t, err, "held lock must not be acquired")
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "lock must be waited for")
+	assert.Contains(t, err.Error(), "gave up waiting after 300ms")
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		exclusiveLock.Release()
+	}()
+
+	waitedLock, err := Acquire(path, Exclusive, 5*time.Second)
+	assert.Nilf(t, err, "lock must be acquired after release: %v", err)
+	assert.Nil(t, waitedLock.Release())
+}
+
+func TestUnsafeLockFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "passwd")
+	err := os.WriteFile(target, []byte("root:x:0:0"), 0644)
+	assert.Nilf(t, err, "could not create target file: %v", err)
+
+	lockDir := filepath.Join(dir, "locks")
+	err = os.Mkdir(lockDir, 0700)
+	assert.Nilf(t, err, "could not create lock directory: %v", err)
+	err = os.Symlink(target, filepath.Join(lockDir, "nginx.lock"))
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+
+	_, err = Acquire(filepath.Join(lockDir, "nginx.lock"), Exclusive, 0)
+	assert.NotNil(t, err, "symlinked lock file must not be opened")
+	content, _ := os.ReadFile(target)
+	assert.Equal(t, "root:x:0:0", string(content), "symlink target must not be truncated")
+
+	err = os.Chmod(lockDir, 0777)
+	assert.Nilf(t, err, "could not change lock directory mode: %v", err)
+	_, err = Acquire(filepath.Join(lockDir, "apache.lock"), Exclusive, 0)
+	assert.NotNil(t, err, "lock directory writable by others must be refused")
+}
+
+func TestGetPath(t *testing.T) {
+	o := options.Options{Defaults: webserverOptions.GetDefaults(), Params: map[string]string{}}
+	serverRoot := t.TempDir()
+	configFile := filepath.Join(serverRoot, "nginx.conf")
+	err := os.WriteFile(configFile, []byte("events {}\n"), 0644)
+	assert.Nilf(t, err, "could not write config file: %v", err)
+	linkRoot := filepath.Join(t.TempDir(), "nginx")
+	err = os.Symlink(serverRoot, linkRoot)
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+
+	defaultPath := GetPath(o, "nginx", serverRoot, configFile)
+	assert.Regexp(t, `^nginx-[0-9a-f]{12}\.lock$`, filepath.Base(defaultPath))
+	assert.Equal(t, defaultPath, GetPath(o, "nginx", serverRoot+"/", filepath.Join(serverRoot, "conf.d", "..", "nginx.conf")), "cleaned paths must have the same lock file")
+	assert.Equal(t, defaultPath, GetPath(o, "nginx", linkRoot, filepath.Join(linkRoot, "nginx.conf")), "resolved paths must have the same lock file")
+
+	site1Path := GetPath(o, "nginx", "/etc/nginx-site1", "/etc/nginx-site1/nginx.conf")
+	assert.Equal(t, filepath.Dir(defaultPath), filepath.Dir(site1Path))
+	assert.NotEqual(t, defaultPath, site1Path, "instances must have own lock files")
+	assert.Equal(t, site1Path, GetPath(o, "nginx", "/etc/nginx-site1/", "/etc/nginx-site1/nginx.conf"))
+	assert.NotEqual(t, site1Path, GetPath(o, "nginx", "/etc/nginx-site2", "/etc/nginx-site2/nginx.conf"), "instances must have own lock files")
+
+	o.Params[webserverOptions.LockFile] = "/tmp/site.lock"
+	assert.Equal(t, "/tmp/site.lock", GetPath(o, "nginx", serverRoot, configFile))
+}
@@ -1,5 +1,13 @@
 package webserver
 
+import (
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"github.com/r2dtools/webmng/pkg/webserver/lock"
+	"github.com/r2dtools/webmng/pkg/webserver/reverter"
+	"github.com/r2dtools/webmng/pkg/webserver/staging"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+)
+
 const (
 	Apache = "apache"
 	Nginx  = "nginx"
@@ -8,12 +16,34 @@ const (
 type WebServerManagerInterface interface {
 	GetHosts() ([]Host, error)
 	GetVersion() (string, error)
+	// GetOpenSSLVersion returns OpenSSL version the webserver is built with. Empty version is returned if it could not be detected.
+	GetOpenSSLVersion() (string, error)
 	GetHostsByServerName(serverName string) ([]Host, error)
 	EnableHost(host *Host) error
 	DeployCertificate(serverName, certPath, certKeyPath, chainPath, fullChainPath string) error
+	DeployCertificates(serverName string, certificates []Certificate) error
 	CheckConfiguration() error
-	Restart() error
+	ServiceController
+	// Lock acquires the instance lock shared with other webmng runs and re-reads the configuration, so it reflects changes of the previous holder.
+	// It should be called before changes are made, the lock must be released after they are committed or rolled back.
+	Lock(mode lock.Mode) (*lock.Lock, error)
 	SaveChanges() error
 	CommitChanges() error
 	RollbackChanges() error
+	GetReverter() reverter.Reverter
+	// GetChanges returns files and symlinks changed besides the configuration, they are written on save in staged mode
+	GetChanges() *staging.Changes
+	Lint() ([]Finding, error)
+	GetTLSConfigs(serverName string) ([]TLSConfig, error)
+	ApplyTLSProfile(serverName string, profile tlsprofile.Profile, snippet bool) error
+	GetHeaders(serverName string) ([]HostHeaders, error)
+	SetHeaders(serverName string, hostHeaders []headers.Header) error
+	UnsetHeaders(serverName string, names []string) error
+	EnableStapling(serverName string, config StaplingConfig) error
+	DisableStapling(serverName string) error
+	EnableClientAuth(serverName string, config ClientAuthConfig) error
+	DisableClientAuth(serverName, path string) error
+	EnableAcmeWebroot(serverName, webroot string) error
+	GetAcmeWebroot(serverName string) (string, error)
+	DisableAcmeWebroot(serverName string) error
 }
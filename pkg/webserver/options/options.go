@@ -1,14 +1,189 @@
 package options
 
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/options"
+)
+
 const (
 	HttpPort  = "http_port"
 	HttpsPort = "https_port"
+	// ServerConfig is webserver main configuration file. It is detected in the server root if not specified.
+	ServerConfig = "server_config"
+	// EnabledDir is the directory of enabled hosts configs. It is detected in the server root if not specified.
+	EnabledDir = "enabled_dir"
+	// AvailableDir is the directory of available hosts configs where created host configs are placed.
+	// It is detected from the symlinks of enabled hosts configs if not specified.
+	AvailableDir = "available_dir"
+	// ReloadCmd is a command used to reload webserver instead of its cli, e.g. "systemctl reload nginx@site1"
+	ReloadCmd = "reload_cmd"
+	// Root is an alternate root directory. All configuration paths are resolved relative to it.
+	Root = "root"
+	// Offline enables managing configuration files without webserver binary
+	Offline = "offline"
+	// ServerVersion is webserver version used in offline mode
+	ServerVersion = "server_version"
+	// Modules is a comma separated list of webserver modules available in offline mode
+	Modules = "modules"
+	// CheckCmd is a command used to check configuration in offline mode
+	CheckCmd = "check_cmd"
+	// CmdTimeout is a timeout of webserver commands, e.g. 30s, 2m
+	CmdTimeout = "cmd_timeout"
+	// ServiceBackend is a way the webserver process is controlled: auto, systemd or ctl
+	ServiceBackend = "service_backend"
+	// ServiceUnit is a systemd unit of the webserver. It is detected if not specified.
+	ServiceUnit = "service_unit"
+	// PidFile is a pid file of the webserver used by ctl backend. It is detected from the configuration if not specified.
+	PidFile = "pid_file"
+	// VerifyTimeout limits waiting for the webserver to become healthy after reload
+	VerifyTimeout = "verify_timeout"
+	// Probe enables local HTTP(S) requests to the changed hosts after reload
+	Probe = "probe"
+	// PortCheck is a reaction on ports bound by other processes when listen directives are added: refuse, warn or off
+	PortCheck = "port_check"
+	// Staged enables testing changes against a temporary copy of the configuration before the live files are replaced
+	Staged = "staged"
+	// LockFile is a lock file of the webserver instance. It is derived from the instance paths if not specified.
+	LockFile = "lock_file"
+	// LockWait enables waiting for the lock held by another webmng run
+	LockWait = "lock_wait"
+	// LockTimeout limits waiting for the lock, e.g. 30s
+	LockTimeout = "lock_timeout"
 )
 
 func GetDefaults() map[string]string {
 	defaults := make(map[string]string)
 	defaults[HttpPort] = "80"
 	defaults[HttpsPort] = "443"
+	defaults[ServerConfig] = ""
+	defaults[EnabledDir] = ""
+	defaults[AvailableDir] = ""
+	defaults[ReloadCmd] = ""
+	defaults[Root] = ""
+	defaults[Offline] = "false"
+	defaults[ServerVersion] = ""
+	defaults[Modules] = ""
+	defaults[CheckCmd] = ""
+	defaults[CmdTimeout] = "2m"
+	defaults[ServiceBackend] = "auto"
+	defaults[ServiceUnit] = ""
+	defaults[PidFile] = ""
+	defaults[VerifyTimeout] = "10s"
+	defaults[Probe] = "false"
+	defaults[PortCheck] = "refuse"
+	defaults[Staged] = "false"
+	defaults[LockFile] = ""
+	defaults[LockWait] = "true"
+	defaults[LockTimeout] = "1m"
 
 	return defaults
 }
+
+// ValidatePort checks that the port option is a valid TCP port number
+func ValidatePort(port string) error {
+	number, err := strconv.Atoi(port)
+
+	if err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("invalid port '%s': must be a number between 1 and 65535", port)
+	}
+
+	return nil
+}
+
+// ValidateBool checks that the option is a boolean value
+func ValidateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid boolean value '%s'", value)
+	}
+
+	return nil
+}
+
+// ValidateDuration checks that the option is a positive duration, e.g. 30s
+func ValidateDuration(value string) error {
+	if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration '%s'", value)
+	}
+
+	return nil
+}
+
+// GetCmdTimeout returns timeout of webserver commands. Zero is returned if the option is invalid.
+func GetCmdTimeout(o options.Options) time.Duration {
+	timeout, _ := time.ParseDuration(o.Get(CmdTimeout))
+
+	return timeout
+}
+
+// GetVerifyTimeout returns timeout of the webserver verification after reload. Zero is returned if the option is invalid.
+func GetVerifyTimeout(o options.Options) time.Duration {
+	timeout, _ := time.ParseDuration(o.Get(VerifyTimeout))
+
+	return timeout
+}
+
+// GetLockTimeout returns time of waiting for the instance lock. Zero is returned if waiting is disabled or the option is invalid.
+func GetLockTimeout(o options.Options) time.Duration {
+	if wait, _ := strconv.ParseBool(o.Get(LockWait)); !wait {
+		return 0
+	}
+
+	timeout, _ := time.ParseDuration(o.Get(LockTimeout))
+
+	return timeout
+}
+
+// IsProbeEnabled checks if the changed hosts should be probed after reload
+func IsProbeEnabled(o options.Options) bool {
+	probe, _ := strconv.ParseBool(o.Get(Probe))
+
+	return probe
+}
+
+// IsStaged checks if changes should be tested against a temporary copy of the configuration.
+// Staged apply is not used in offline mode since there is no webserver binary to test the copy.
+func IsStaged(o options.Options) bool {
+	staged, _ := strconv.ParseBool(o.Get(Staged))
+
+	return staged && !IsOffline(o)
+}
+
+// IsOffline checks if offline mode is enabled
+func IsOffline(o options.Options) bool {
+	offline, _ := strconv.ParseBool(o.Get(Offline))
+
+	return offline
+}
+
+// GetRootedPath returns the absolute path inside the alternate root directory
+func GetRootedPath(root, path string) string {
+	if root == "" || !filepath.IsAbs(path) {
+		return path
+	}
+
+	root = filepath.Clean(root)
+
+	if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return path
+	}
+
+	return filepath.Join(root, path)
+}
+
+// GetList splits comma separated option value
+func GetList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,46 @@
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePort(t *testing.T) {
+	for _, port := range []string{"443", "8443", "1", "65535"} {
+		err := ValidatePort(port)
+		assert.Nilf(t, err, "port must be valid: %v", err)
+	}
+
+	for _, port := range []string{"", "0", "65536", "https", "-1", "443 https"} {
+		assert.NotNilf(t, ValidatePort(port), "port '%s' must be invalid", port)
+	}
+}
+
+func TestGetRootedPath(t *testing.T) {
+	assert.Equal(t, "/etc/nginx", GetRootedPath("", "/etc/nginx"))
+	assert.Equal(t, "/backup/etc/nginx", GetRootedPath("/backup", "/etc/nginx"))
+	assert.Equal(t, "/backup/etc/nginx", GetRootedPath("/backup/", "/backup/etc/nginx"))
+	assert.Equal(t, "sites-enabled", GetRootedPath("/backup", "sites-enabled"))
+}
+
+func TestGetList(t *testing.T) {
+	assert.Equal(t, []string{"ssl", "rewrite"}, GetList(" ssl, rewrite,,"))
+	assert.Empty(t, GetList(""))
+}
+
+func TestValidateDuration(t *testing.T) {
+	assert.Nil(t, ValidateDuration("30s"))
+	assert.NotNil(t, ValidateDuration("30"))
+	assert.NotNil(t, ValidateDuration("-1m"))
+}
+
+func TestGetLockTimeout(t *testing.T) {
+	o := options.Options{Defaults: GetDefaults(), Params: map[string]string{LockTimeout: "30s"}}
+	assert.Equal(t, 30*time.Second, GetLockTimeout(o))
+
+	o.Params[LockWait] = "false"
+	assert.Equal(t, time.Duration(0), GetLockTimeout(o), "lock must not be waited for")
+}
@@ -0,0 +1,129 @@
+package procstat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// PortCheckRefuse refuses to add listen directives for ports bound by other processes
+	PortCheckRefuse = "refuse"
+	// PortCheckWarn only logs ports bound by other processes
+	PortCheckWarn = "warn"
+	PortCheckOff  = "off"
+)
+
+// PortConflictError is returned if another process listens on the address
+type PortConflictError struct {
+	Ip        string
+	Port      string
+	Owner     Socket
+	OwnerName string
+}
+
+func (e PortConflictError) Error() string {
+	address := e.Port
+
+	if e.Ip != "" {
+		address = Socket{Ip: e.Ip, Port: e.Port}.String()
+	}
+
+	owner := "another process"
+
+	if e.Owner.Pid != 0 {
+		owner = fmt.Sprintf("%s (pid %d)", e.OwnerName, e.Owner.Pid)
+	}
+
+	return fmt.Sprintf("could not listen on %s: %s is already bound by %s", address, e.Owner, owner)
+}
+
+// PortChecker checks that listen addresses are not bound by processes other than the webserver
+type PortChecker struct {
+	mode string
+	// controller returns the webserver processes whose sockets are not conflicts
+	controller webserver.ServiceController
+	logger     logger.LoggerInterface
+	getSockets func() ([]Socket, error)
+}
+
+// Check returns error if another process listens on the address in refuse mode. Conflicts are logged in warn mode.
+// Sockets of inaccessible processes are logged only since they could belong to the webserver itself. Empty ip means all interfaces.
+func (c PortChecker) Check(ip, port string) error {
+	if c.mode == PortCheckOff || c.mode == "" {
+		return nil
+	}
+
+	ip = strings.Trim(ip, "[]")
+
+	sockets, err := c.getSockets()
+	if err != nil {
+		c.logger.Warning(fmt.Sprintf("could not check port %s: %v", port, err))
+
+		return nil
+	}
+
+	ownPids := c.getOwnPids()
+
+	for _, socket := range sockets {
+		if socket.Port != port || !isAddressOverlapped(ip, socket.Ip) || slices.Contains(ownPids, socket.Pid) {
+			continue
+		}
+
+		conflict := PortConflictError{Ip: ip, Port: port, Owner: socket, OwnerName: getProcessName(socket.Pid)}
+
+		if c.mode == PortCheckWarn || socket.Pid == 0 {
+			c.logger.Warning(conflict.Error())
+
+			return nil
+		}
+
+		return conflict
+	}
+
+	return nil
+}
+
+func (c PortChecker) getOwnPids() []int32 {
+	status, err := c.controller.Status()
+	if err != nil || !status.Running || status.Pid == 0 {
+		return nil
+	}
+
+	workers, _ := GetWorkers(status.Pid)
+
+	return append(workers, status.Pid)
+}
+
+// GetPortChecker returns checker of the ports listened by processes other than the webserver controlled by the controller
+func GetPortChecker(mode string, controller webserver.ServiceController, logger logger.LoggerInterface) PortChecker {
+	return PortChecker{mode: mode, controller: controller, logger: logger, getSockets: GetListeningSockets}
+}
+
+// GetPortCheckMode returns port check mode set by the options.
+// Check is off in offline mode or for alternate root since its configuration is not served on this machine.
+func GetPortCheckMode(o options.Options) string {
+	if webserverOptions.IsOffline(o) || o.Get(webserverOptions.Root) != "" {
+		return PortCheckOff
+	}
+
+	return o.Get(webserverOptions.PortCheck)
+}
+
+// isAddressOverlapped checks if the listen ip and the socket ip share an interface. Empty and unspecified ips mean all interfaces.
+func isAddressOverlapped(ip, socketIp string) bool {
+	if isWildcardIp(ip) || isWildcardIp(socketIp) {
+		return true
+	}
+
+	return ip == socketIp
+}
+
+func isWildcardIp(ip string) bool {
+	return ip == "" || ip == "*" || ip == "0.0.0.0" || ip == "::"
+}
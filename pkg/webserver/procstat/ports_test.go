@@ -0,0 +1,78 @@
+package procstat
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/options"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	webserverOptions "github.com/r2dtools/webmng/pkg/webserver/options"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeController struct {
+	webserver.ServiceController
+	status webserver.ServiceStatus
+}
+
+func (c fakeController) Status() (webserver.ServiceStatus, error) {
+	return c.status, nil
+}
+
+type warningLogger struct {
+	warnings []string
+}
+
+func (l *warningLogger) Error(message string, args ...interface{}) {}
+
+func (l *warningLogger) Warning(message string, args ...interface{}) {
+	l.warnings = append(l.warnings, message)
+}
+
+func (l *warningLogger) Info(message string, args ...interface{})  {}
+func (l *warningLogger) Debug(message string, args ...interface{}) {}
+
+func TestPortChecker(t *testing.T) {
+	sockets := []Socket{
+		{Ip: "0.0.0.0", Port: "80", Pid: 100},
+		{Ip: "127.0.0.1", Port: "8080", Pid: int32(os.Getpid())},
+		{Ip: "::", Port: "8443"},
+	}
+	logger := &warningLogger{}
+	checker := GetPortChecker(PortCheckRefuse, fakeController{status: webserver.ServiceStatus{Running: true, Pid: 100}}, logger)
+	checker.getSockets = func() ([]Socket, error) {
+		return sockets, nil
+	}
+
+	assert.Nil(t, checker.Check("", "80"), "port bound by the webserver itself is not a conflict")
+	assert.Nil(t, checker.Check("", "443"))
+	assert.Nil(t, checker.Check("10.0.0.1", "8080"), "port bound on another interface is not a conflict")
+
+	err := checker.Check("", "8080")
+	assert.IsType(t, PortConflictError{}, err)
+	assert.Contains(t, err.Error(), "127.0.0.1:8080 is already bound by")
+	assert.Contains(t, err.Error(), "(pid "+strconv.Itoa(os.Getpid())+")")
+	assert.NotNil(t, checker.Check("[127.0.0.1]", "8080"))
+
+	assert.Nil(t, checker.Check("", "8443"), "port bound by inaccessible process must be only logged")
+	assert.Equal(t, 1, len(logger.warnings))
+	assert.Contains(t, logger.warnings[0], "already bound by another process")
+
+	checker.mode = PortCheckWarn
+	assert.Nil(t, checker.Check("", "8080"))
+	assert.Equal(t, 2, len(logger.warnings))
+
+	checker.mode = PortCheckOff
+	assert.Nil(t, checker.Check("", "8080"))
+	assert.Equal(t, 2, len(logger.warnings))
+}
+
+func TestGetPortCheckMode(t *testing.T) {
+	defaults := webserverOptions.GetDefaults()
+
+	assert.Equal(t, PortCheckRefuse, GetPortCheckMode(options.Options{Defaults: defaults}))
+	assert.Equal(t, PortCheckWarn, GetPortCheckMode(options.Options{Defaults: defaults, Params: map[string]string{webserverOptions.PortCheck: PortCheckWarn}}))
+	assert.Equal(t, PortCheckOff, GetPortCheckMode(options.Options{Defaults: defaults, Params: map[string]string{webserverOptions.Offline: "true"}}))
+	assert.Equal(t, PortCheckOff, GetPortCheckMode(options.Options{Defaults: defaults, Params: map[string]string{webserverOptions.Root: "/srv/image"}}))
+}
@@ -0,0 +1,256 @@
+package procstat
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	psnet "github.com/shirou/gopsutil/net"
+	"github.com/shirou/gopsutil/process"
+	"golang.org/x/exp/slices"
+)
+
+const listenStatus = "LISTEN"
+
+// ProcessStatus contains resources used by the webserver master process and its workers
+type ProcessStatus struct {
+	Pid       int32     `json:"pid"`
+	Workers   int       `json:"workers"`
+	StartedAt time.Time `json:"started_at"`
+	// Rss is a resident memory of the master and workers in bytes
+	Rss uint64 `json:"rss"`
+	// Cpu is an average cpu usage of the master and workers since their start in percent
+	Cpu float64 `json:"cpu"`
+	// ConfigGeneration is a time of the last reload detected by the start of the newest worker
+	ConfigGeneration time.Time `json:"config_generation"`
+	Sockets          []Socket  `json:"sockets"`
+}
+
+// Uptime returns time elapsed since the master process start
+func (s ProcessStatus) Uptime() time.Duration {
+	return time.Since(s.StartedAt).Truncate(time.Second)
+}
+
+// Socket is a listening tcp socket
+type Socket struct {
+	Ip   string `json:"ip"`
+	Port string `json:"port"`
+	Pid  int32  `json:"pid,omitempty"`
+}
+
+func (s Socket) String() string {
+	return net.JoinHostPort(s.Ip, s.Port)
+}
+
+// PortStatus compares the port configured for the webserver hosts with the listening sockets
+type PortStatus struct {
+	Port string `json:"port"`
+	// Bound is true if the webserver listens on the port
+	Bound bool `json:"bound"`
+	// OwnerPid is a process listening on the port instead of the webserver. Zero pid means the process is not accessible.
+	OwnerPid  int32  `json:"owner_pid,omitempty"`
+	OwnerName string `json:"owner_name,omitempty"`
+	// Conflict is true if another process listens on the port
+	Conflict bool `json:"conflict"`
+}
+
+// GetProcessStatus returns status of the webserver master process and its workers
+func GetProcessStatus(pid int32) (ProcessStatus, error) {
+	status := ProcessStatus{Pid: pid}
+	master, err := process.NewProcess(pid)
+
+	if err != nil {
+		return status, fmt.Errorf("could not find webserver process %d: %v", pid, err)
+	}
+
+	createTime, err := master.CreateTime()
+	if err != nil {
+		return status, fmt.Errorf("could not get start time of webserver process %d: %v", pid, err)
+	}
+
+	status.StartedAt = time.UnixMilli(createTime)
+	status.ConfigGeneration = status.StartedAt
+	workers, err := GetWorkers(pid)
+
+	if err != nil {
+		return status, err
+	}
+
+	status.Workers = len(workers)
+	processes := []*process.Process{master}
+
+	for _, workerPid := range workers {
+		worker, err := process.NewProcess(workerPid)
+		// the worker could exit after the list is taken
+		if err != nil {
+			continue
+		}
+
+		if createTime, err := worker.CreateTime(); err == nil && time.UnixMilli(createTime).After(status.ConfigGeneration) {
+			status.ConfigGeneration = time.UnixMilli(createTime)
+		}
+
+		processes = append(processes, worker)
+	}
+
+	for _, p := range processes {
+		if memory, err := p.MemoryInfo(); err == nil {
+			status.Rss += memory.RSS
+		}
+
+		if cpu, err := p.CPUPercent(); err == nil {
+			status.Cpu += cpu
+		}
+
+		sockets, err := getListeningSockets(p.Pid)
+		if err != nil {
+			return status, err
+		}
+
+		for _, socket := range sockets {
+			if !slices.ContainsFunc(status.Sockets, func(s Socket) bool { return s.String() == socket.String() }) {
+				status.Sockets = append(status.Sockets, socket)
+			}
+		}
+	}
+
+	slices.SortFunc(status.Sockets, func(a, b Socket) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	return status, nil
+}
+
+// GetWorkers returns child processes of the webserver master process
+func GetWorkers(pid int32) ([]int32, error) {
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("could not get processes: %v", err)
+	}
+
+	var workers []int32
+
+	for _, p := range processes {
+		// the process could exit while the list is iterated
+		if ppid, err := p.Ppid(); err == nil && ppid == pid {
+			workers = append(workers, p.Pid)
+		}
+	}
+
+	return workers, nil
+}
+
+// GetListeningSockets returns tcp sockets listened by all processes
+func GetListeningSockets() ([]Socket, error) {
+	connections, err := psnet.Connections("tcp")
+	if err != nil {
+		return nil, fmt.Errorf("could not get listening sockets: %v", err)
+	}
+
+	return filterListeningSockets(connections), nil
+}
+
+// GetConfiguredPorts returns ports of the hosts addresses sorted numerically
+func GetConfiguredPorts(hosts []webserver.Host) []string {
+	var ports []string
+
+	for _, host := range hosts {
+		for _, address := range host.Addresses {
+			port := address.Port
+
+			if port == "" {
+				port = "80"
+
+				if host.Ssl {
+					port = "443"
+				}
+			}
+
+			if !slices.Contains(ports, port) {
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	slices.SortFunc(ports, func(a, b string) int {
+		aNumber, _ := strconv.Atoi(a)
+		bNumber, _ := strconv.Atoi(b)
+
+		return aNumber - bNumber
+	})
+
+	return ports
+}
+
+// ComparePorts checks which configured ports are bound by the webserver sockets and which ones by other processes
+func ComparePorts(ports []string, serverSockets, sockets []Socket) []PortStatus {
+	var statuses []PortStatus
+
+	for _, port := range ports {
+		status := PortStatus{Port: port}
+
+		for _, socket := range serverSockets {
+			if socket.Port == port {
+				status.Bound = true
+
+				break
+			}
+		}
+
+		if !status.Bound {
+			for _, socket := range sockets {
+				if socket.Port == port {
+					status.Conflict, status.OwnerPid = true, socket.Pid
+					status.OwnerName = getProcessName(socket.Pid)
+
+					break
+				}
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func getListeningSockets(pid int32) ([]Socket, error) {
+	connections, err := psnet.ConnectionsPid("tcp", pid)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sockets of process %d: %v", pid, err)
+	}
+
+	return filterListeningSockets(connections), nil
+}
+
+func filterListeningSockets(connections []psnet.ConnectionStat) []Socket {
+	var sockets []Socket
+
+	for _, connection := range connections {
+		if connection.Status != listenStatus {
+			continue
+		}
+
+		sockets = append(sockets, Socket{Ip: connection.Laddr.IP, Port: strconv.FormatUint(uint64(connection.Laddr.Port), 10), Pid: connection.Pid})
+	}
+
+	return sockets
+}
+
+func getProcessName(pid int32) string {
+	if pid == 0 {
+		return ""
+	}
+
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ""
+	}
+
+	name, _ := p.Name()
+
+	return name
+}
@@ -0,0 +1,80 @@
+package procstat
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/host"
+	psnet "github.com/shirou/gopsutil/net"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProcessStatus(t *testing.T) {
+	// the test process plays the webserver master, its children are workers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nilf(t, err, "could not listen: %v", err)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	worker := exec.Command("sleep", "30")
+	err = worker.Start()
+	assert.Nilf(t, err, "could not start worker process: %v", err)
+	defer func() {
+		worker.Process.Kill()
+		worker.Wait()
+	}()
+
+	status, err := GetProcessStatus(int32(os.Getpid()))
+	assert.Nilf(t, err, "could not get process status: %v", err)
+	assert.Equal(t, int32(os.Getpid()), status.Pid)
+	assert.GreaterOrEqual(t, status.Workers, 1)
+	assert.Greater(t, status.Rss, uint64(0))
+	assert.False(t, status.ConfigGeneration.Before(status.StartedAt), "config generation must not be older than the master process")
+	assert.Less(t, status.Uptime(), time.Hour)
+	assert.Contains(t, status.Sockets, Socket{Ip: "127.0.0.1", Port: port, Pid: int32(os.Getpid())})
+
+	workers, err := GetWorkers(int32(os.Getpid()))
+	assert.Nilf(t, err, "could not get workers: %v", err)
+	assert.Contains(t, workers, int32(worker.Process.Pid))
+
+	_, err = GetProcessStatus(int32(worker.Process.Pid) + 1000000)
+	assert.NotNil(t, err, "missing process must be reported")
+}
+
+func TestGetConfiguredPorts(t *testing.T) {
+	hosts := []webserver.Host{
+		{Ssl: true, Addresses: map[string]host.Address{"443": {Port: "443"}, "8443": {Port: "8443"}}},
+		{Addresses: map[string]host.Address{"*": {Host: "*"}, "[::]:443": {Host: "[::]", Port: "443", IsIpv6: true}}},
+	}
+
+	assert.Equal(t, []string{"80", "443", "8443"}, GetConfiguredPorts(hosts))
+}
+
+func TestComparePorts(t *testing.T) {
+	serverSockets := []Socket{{Ip: "0.0.0.0", Port: "80"}, {Ip: "::", Port: "80"}}
+	sockets := []Socket{{Ip: "0.0.0.0", Port: "80"}, {Ip: "127.0.0.1", Port: "443", Pid: int32(os.Getpid())}, {Ip: "0.0.0.0", Port: "8080"}}
+
+	statuses := ComparePorts([]string{"80", "443", "8080", "8443"}, serverSockets, sockets)
+	assert.Equal(t, 4, len(statuses))
+	assert.Equal(t, PortStatus{Port: "80", Bound: true}, statuses[0])
+	assert.True(t, statuses[1].Conflict, "port bound by another process must be reported")
+	assert.Equal(t, int32(os.Getpid()), statuses[1].OwnerPid)
+	assert.NotEmpty(t, statuses[1].OwnerName)
+	assert.Equal(t, PortStatus{Port: "8080", Conflict: true}, statuses[2], "inaccessible process must be reported without pid")
+	assert.Equal(t, PortStatus{Port: "8443"}, statuses[3])
+}
+
+func TestFilterListeningSockets(t *testing.T) {
+	connections := []psnet.ConnectionStat{
+		{Laddr: psnet.Addr{IP: "::", Port: 443}, Status: "LISTEN", Pid: 10},
+		{Laddr: psnet.Addr{IP: "10.0.0.1", Port: 443}, Raddr: psnet.Addr{IP: "10.0.0.2", Port: 50000}, Status: "ESTABLISHED", Pid: 11},
+	}
+
+	sockets := filterListeningSockets(connections)
+	assert.Equal(t, []Socket{{Ip: "::", Port: "443", Pid: 10}}, sockets)
+	assert.Equal(t, "[::]:443", sockets[0].String())
+}
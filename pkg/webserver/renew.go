@@ -0,0 +1,91 @@
+package webserver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"golang.org/x/exp/slices"
+)
+
+// CertificateUsage is a certificate referenced by a host
+type CertificateUsage struct {
+	Host        Host
+	CertPath    string
+	Fingerprint string
+	Names       []string
+	KeyType     string
+}
+
+// GetCertificateUsages fingerprints certificates referenced by the hosts. Unreadable certificates are skipped.
+func GetCertificateUsages(configs []TLSConfig) []CertificateUsage {
+	var usages []CertificateUsage
+
+	for _, config := range configs {
+		for _, files := range config.Certificates {
+			leaf, err := certificate.LoadLeafCertificate(files.CertPath)
+			if err != nil {
+				continue
+			}
+
+			keyInfo, err := certificate.GetCertificateKeyInfo(leaf)
+			if err != nil {
+				continue
+			}
+
+			usages = append(usages, CertificateUsage{
+				Host:        config.Host,
+				CertPath:    files.CertPath,
+				Fingerprint: certificate.GetFingerprint(leaf),
+				Names:       certificate.GetNames(leaf),
+				KeyType:     keyInfo.Type,
+			})
+		}
+	}
+
+	return usages
+}
+
+// GetOutdatedUsages returns usages of certificates with the key type which names are covered by the renewed certificate names.
+// Usages of the renewed certificate itself are skipped.
+func GetOutdatedUsages(usages []CertificateUsage, names []string, keyType, fingerprint string) []CertificateUsage {
+	var outdated []CertificateUsage
+
+	for _, usage := range usages {
+		if usage.Fingerprint == fingerprint || usage.KeyType != keyType || len(usage.Names) == 0 {
+			continue
+		}
+
+		covered := true
+
+		for _, name := range usage.Names {
+			if !slices.ContainsFunc(names, func(renewedName string) bool {
+				return strings.EqualFold(renewedName, name)
+			}) {
+				covered = false
+				break
+			}
+		}
+
+		if covered {
+			outdated = append(outdated, usage)
+		}
+	}
+
+	return outdated
+}
+
+// GetUsagesServerNames returns sorted unique server names of hosts of the usages
+func GetUsagesServerNames(usages []CertificateUsage) []string {
+	var serverNames []string
+
+	for _, usage := range usages {
+		if !slices.Contains(serverNames, usage.Host.ServerName) {
+			serverNames = append(serverNames, usage.Host.ServerName)
+		}
+	}
+
+	sort.Strings(serverNames)
+
+	return serverNames
+}
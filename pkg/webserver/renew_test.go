@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOutdatedUsages(t *testing.T) {
+	usages := []CertificateUsage{
+		{Host: Host{ServerName: "example.com"}, Fingerprint: "A", Names: []string{"example.com", "www.example.com"}, KeyType: "rsa"},
+		{Host: Host{ServerName: "www.example.com"}, Fingerprint: "B", Names: []string{"www.example.com"}, KeyType: "rsa"},
+		{Host: Host{ServerName: "example.com"}, Fingerprint: "C", Names: []string{"example.com"}, KeyType: "ecdsa"},
+		{Host: Host{ServerName: "example.org"}, Fingerprint: "D", Names: []string{"example.org"}, KeyType: "rsa"},
+		{Host: Host{ServerName: "new.example.com"}, Fingerprint: "E", Names: []string{"example.com"}, KeyType: "rsa"},
+	}
+
+	outdated := GetOutdatedUsages(usages, []string{"Example.com", "www.example.com"}, "rsa", "E")
+	assert.Len(t, outdated, 2)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, GetUsagesServerNames(outdated))
+}
+
+func TestGetCertificateUsages(t *testing.T) {
+	configs := []TLSConfig{
+		{
+			Host: Host{ServerName: "example.com"},
+			Certificates: []CertificateFiles{
+				{CertPath: certificateDir + "/example.com.crt"},
+				{CertPath: certificateDir + "/missing.crt"},
+			},
+		},
+	}
+
+	usages := GetCertificateUsages(configs)
+	assert.Len(t, usages, 1)
+	assert.Equal(t, "ecdsa", usages[0].KeyType)
+	assert.NotEmpty(t, usages[0].Fingerprint)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/r2dtools/webmng/pkg/logger"
+	"github.com/r2dtools/webmng/pkg/utils"
 	"github.com/unknwon/com"
 	"golang.org/x/exp/slices"
 )
@@ -17,9 +18,11 @@ type Reverter interface {
 	BackupFile(filePath string) error
 	BackupFiles(filePaths []string) error
 	AddFileToDeletion(filePath string)
+	BackupSymlink(linkPath string) error
 	AddHostConfigToDisable(configPath string)
 	Commit() error
 	Rollback() error
+	GetChangedFiles() []string
 }
 
 type rollbackError struct {
@@ -37,6 +40,8 @@ type configReverter struct {
 	configsToDisable []string
 	hostDisabler     HostDisabler
 	logger           logger.LoggerInterface
+	// symlinksToRestore contains previous symlinks targets, empty target means the symlink did not exist
+	symlinksToRestore map[string]string
 }
 
 // AddFileToDeletion marks file to delete on rollback
@@ -49,6 +54,23 @@ func (r *configReverter) AddHostConfigToDisable(configPath string) {
 	r.configsToDisable = append(r.configsToDisable, configPath)
 }
 
+// BackupSymlink remembers the symlink target. The symlink will be pointed back at it on rollback
+// or removed if it does not exist yet.
+func (r *configReverter) BackupSymlink(linkPath string) error {
+	if _, ok := r.symlinksToRestore[linkPath]; ok {
+		return nil
+	}
+
+	target, err := os.Readlink(linkPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	r.symlinksToRestore[linkPath] = target
+
+	return nil
+}
+
 // BackupFiles makes files backups
 func (r *configReverter) BackupFiles(filePaths []string) error {
 	for _, filePath := range filePaths {
@@ -113,6 +135,19 @@ func (r *configReverter) Rollback() error {
 		}
 	}
 
+	// point symlinks back at their previous targets
+	for linkPath, target := range r.symlinksToRestore {
+		if target == "" {
+			if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+				return rollbackError{err}
+			}
+		} else if err := utils.ReplaceSymlink(target, linkPath); err != nil {
+			return rollbackError{err}
+		}
+
+		delete(r.symlinksToRestore, linkPath)
+	}
+
 	// restore the content of backed up files
 	for originFilePath, bFilePath := range r.filesToRestore {
 		bContent, err := os.ReadFile(bFilePath)
@@ -150,19 +185,40 @@ func (r *configReverter) Commit() error {
 	}
 
 	r.filesToDelete = nil
+	r.symlinksToRestore = make(map[string]string)
 
 	return nil
 }
 
+// GetChangedFiles returns files changed, created or enabled since the last commit
+func (r *configReverter) GetChangedFiles() []string {
+	var files []string
+	files = append(files, r.filesToDelete...)
+	files = append(files, r.configsToDisable...)
+
+	for filePath := range r.filesToRestore {
+		files = append(files, filePath)
+	}
+
+	for linkPath := range r.symlinksToRestore {
+		files = append(files, linkPath)
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files)
+}
+
 func getBackupFilePath(filePath string) string {
 	return filePath + ".back"
 }
 
 func GetConfigReveter(hostDisabler HostDisabler, logger logger.LoggerInterface) Reverter {
 	reverter := configReverter{
-		hostDisabler:   hostDisabler,
-		logger:         logger,
-		filesToRestore: make(map[string]string),
+		hostDisabler:      hostDisabler,
+		logger:            logger,
+		filesToRestore:    make(map[string]string),
+		symlinksToRestore: make(map[string]string),
 	}
 
 	return &reverter
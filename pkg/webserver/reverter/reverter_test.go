@@ -2,6 +2,8 @@ package reverter
 
 import (
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/r2dtools/webmng/pkg/logger"
@@ -48,6 +50,24 @@ func TestReverterCommit(t *testing.T) {
 	assert.Equalf(t, true, com.IsExist(fileToBackup), "file '%s' does not exist", fileToBackup)
 }
 
+func TestReverterChangedFiles(t *testing.T) {
+	reverter := getReverter()
+	dir := t.TempDir()
+	changedFile := filepath.Join(dir, "example.com.conf")
+	createdFile := filepath.Join(dir, "example.com-ssl.conf")
+	createFile(t, changedFile)
+
+	err := reverter.BackupFile(changedFile)
+	assert.Nilf(t, err, "could not backup file: %v", err)
+	reverter.AddFileToDeletion(createdFile)
+	reverter.AddFileToDeletion(createdFile)
+	assert.Equal(t, []string{createdFile, changedFile}, reverter.GetChangedFiles())
+
+	err = reverter.Commit()
+	assert.Nilf(t, err, "commit error: %v", err)
+	assert.Empty(t, reverter.GetChangedFiles(), "committed files must not be reported as changed")
+}
+
 func getReverter() Reverter {
 	return GetConfigReveter(&hostDisabler{}, logger.NilLogger{})
 }
@@ -57,3 +77,27 @@ func createFile(t *testing.T, path string) {
 	assert.Nilf(t, err, "could not create tmp file: %v", err)
 	assert.Equal(t, true, com.IsExist(path), "create file does not exist")
 }
+
+func TestReverterSymlinkRollback(t *testing.T) {
+	reverter := getReverter()
+	dir := t.TempDir()
+	linkPath := filepath.Join(dir, "current")
+	newLinkPath := filepath.Join(dir, "new")
+	assert.Nil(t, os.Symlink("v1", linkPath))
+
+	err := reverter.BackupSymlink(linkPath)
+	assert.Nilf(t, err, "could not backup symlink: %v", err)
+	err = reverter.BackupSymlink(newLinkPath)
+	assert.Nilf(t, err, "could not backup symlink: %v", err)
+	assert.Nil(t, os.Remove(linkPath))
+	assert.Nil(t, os.Symlink("v2", linkPath))
+	assert.Nil(t, os.Symlink("v2", newLinkPath))
+
+	err = reverter.Rollback()
+	assert.Nilf(t, err, "revert error: %v", err)
+
+	target, err := os.Readlink(linkPath)
+	assert.Nilf(t, err, "could not read symlink: %v", err)
+	assert.Equal(t, "v1", target)
+	assert.NoFileExists(t, newLinkPath)
+}
@@ -0,0 +1,29 @@
+package webserver
+
+const (
+	// ServiceBackendAuto uses systemd if the webserver unit is loaded, otherwise ctl
+	ServiceBackendAuto    = "auto"
+	ServiceBackendSystemd = "systemd"
+	ServiceBackendCtl     = "ctl"
+)
+
+// ServiceStatus is a state of the webserver service
+type ServiceStatus struct {
+	Running bool   `json:"running"`
+	State   string `json:"state"`
+	Backend string `json:"backend"`
+	Unit    string `json:"unit,omitempty"`
+	Pid     int32  `json:"pid,omitempty"`
+}
+
+// ServiceController controls the webserver process
+type ServiceController interface {
+	// Reload gracefully reloads configuration without dropping connections
+	Reload() error
+	// Restart stops and starts the webserver
+	Restart() error
+	Start() error
+	// Stop stops the webserver. Graceful stop waits for the current connections to be served.
+	Stop(graceful bool) error
+	Status() (ServiceStatus, error)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/shirou/gopsutil/process"
+)
+
+const (
+	stopTimeout  = 30 * time.Second
+	stopInterval = 200 * time.Millisecond
+)
+
+// Ctl controls the webserver process directly via its binary or signals
+type Ctl interface {
+	Reload() error
+	Start() error
+	Stop(graceful bool) error
+}
+
+// Restarter is implemented by Ctl supporting restart natively, e.g. apachectl -k restart
+type Restarter interface {
+	Restart() error
+}
+
+// CtlController controls the webserver via its ctl. Status is detected via pid file.
+type CtlController struct {
+	ctl     Ctl
+	pidFile string
+}
+
+func (c CtlController) Reload() error {
+	return c.ctl.Reload()
+}
+
+// Restart restarts the webserver natively if ctl supports it. Otherwise the webserver is stopped and started again.
+func (c CtlController) Restart() error {
+	if restarter, ok := c.ctl.(Restarter); ok {
+		return restarter.Restart()
+	}
+
+	if err := c.ctl.Stop(false); err != nil {
+		return err
+	}
+
+	if err := c.waitStopped(stopTimeout); err != nil {
+		return err
+	}
+
+	return c.ctl.Start()
+}
+
+func (c CtlController) Start() error {
+	return c.ctl.Start()
+}
+
+func (c CtlController) Stop(graceful bool) error {
+	return c.ctl.Stop(graceful)
+}
+
+func (c CtlController) Status() (webserver.ServiceStatus, error) {
+	return GetPidFileStatus(c.pidFile)
+}
+
+func (c CtlController) waitStopped(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+
+		if !status.Running {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("webserver process %d is not stopped in %s", status.Pid, timeout)
+		}
+
+		time.Sleep(stopInterval)
+	}
+}
+
+// GetCtlController returns controller of the webserver ctl. pidFile is used to detect the webserver status.
+func GetCtlController(ctl Ctl, pidFile string) CtlController {
+	return CtlController{ctl: ctl, pidFile: pidFile}
+}
+
+// GetPidFileStatus returns status of the webserver process written to the pid file.
+// The webserver is considered stopped if the pid file does not exist or the process is not running.
+func GetPidFileStatus(pidFile string) (webserver.ServiceStatus, error) {
+	status := webserver.ServiceStatus{Backend: webserver.ServiceBackendCtl, State: "stopped"}
+	content, err := os.ReadFile(pidFile)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+
+		return status, fmt.Errorf("could not read pid file: %v", err)
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 32)
+	if err != nil {
+		return status, fmt.Errorf("invalid pid file '%s': %v", pidFile, err)
+	}
+
+	exists, err := process.PidExists(int32(pid))
+	if err != nil {
+		return status, err
+	}
+
+	if exists {
+		status.Running, status.State, status.Pid = true, "running", int32(pid)
+	}
+
+	return status, nil
+}
+
+// reloadController reloads the webserver via the function instead of the backend
+type reloadController struct {
+	webserver.ServiceController
+	reload func() error
+}
+
+func (c reloadController) Reload() error {
+	return c.reload()
+}
+
+// WithReload returns controller reloading the webserver via the function, e.g. running user reload command
+func WithReload(controller webserver.ServiceController, reload func() error) webserver.ServiceController {
+	return reloadController{ServiceController: controller, reload: reload}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/runner"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemdController(t *testing.T) {
+	cmdRunner := runner.GetFakeRunner().
+		On("systemctl reload nginx.service", runner.FakeResponse{}).
+		On("systemctl restart nginx.service", runner.FakeResponse{}).
+		On("systemctl start nginx.service", runner.FakeResponse{}).
+		On("systemctl stop nginx.service", runner.FakeResponse{}).
+		On("systemctl kill --kill-who=main --signal=QUIT nginx.service", runner.FakeResponse{}).
+		On("systemctl show --property=ActiveState,MainPID nginx.service", runner.FakeResponse{Stdout: "ActiveState=active\nMainPID=1234\n"})
+	controller := GetSystemdController(cmdRunner, "nginx.service", "QUIT")
+
+	assert.Nil(t, controller.Reload())
+	assert.Nil(t, controller.Restart())
+	assert.Nil(t, controller.Start())
+	assert.Nil(t, controller.Stop(false))
+	assert.Nil(t, controller.Stop(true))
+
+	status, err := controller.Status()
+	assert.Nilf(t, err, "could not get status: %v", err)
+	assert.Equal(t, webserver.ServiceStatus{Running: true, State: "active", Backend: webserver.ServiceBackendSystemd, Unit: "nginx.service", Pid: 1234}, status)
+
+	cmdRunner.On("systemctl reload nginx.service", runner.FakeResponse{Stderr: "Job for nginx.service failed", ExitCode: 1})
+	err = controller.Reload()
+	assert.NotNil(t, err, "failed reload must be reported")
+	assert.Contains(t, err.Error(), "Job for nginx.service failed")
+}
+
+func TestGetController(t *testing.T) {
+	ctl := GetCtlController(&fakeCtl{}, "")
+
+	controller, err := GetController(runner.GetFakeRunner(), webserver.ServiceBackendAuto, []string{"apache2.service"}, "WINCH", ctl)
+	assert.Nilf(t, err, "could not get controller: %v", err)
+	assert.IsType(t, CtlController{}, controller, "ctl must be used without systemd")
+
+	cmdRunner := runner.GetFakeRunner().
+		AddPath("systemctl", "/usr/bin/systemctl").
+		On("systemctl show --property=LoadState apache2.service", runner.FakeResponse{Stdout: "LoadState=not-found\n"}).
+		On("systemctl show --property=LoadState httpd.service", runner.FakeResponse{Stdout: "LoadState=loaded\n"})
+	controller, err = GetController(cmdRunner, webserver.ServiceBackendAuto, []string{"apache2.service", "httpd.service"}, "WINCH", ctl)
+	assert.Nilf(t, err, "could not get controller: %v", err)
+	assert.Equal(t, GetSystemdController(cmdRunner, "httpd.service", "WINCH"), controller)
+
+	controller, err = GetController(cmdRunner, webserver.ServiceBackendCtl, []string{"httpd.service"}, "WINCH", ctl)
+	assert.Nilf(t, err, "could not get controller: %v", err)
+	assert.Equal(t, ctl, controller)
+
+	_, err = GetController(runner.GetFakeRunner(), webserver.ServiceBackendSystemd, []string{"apache2.service", "httpd.service"}, "WINCH", ctl)
+	assert.NotNil(t, err, "missing systemd units must be reported")
+
+	_, err = GetController(cmdRunner, "init", nil, "", ctl)
+	assert.NotNil(t, err, "unknown backend must be reported")
+}
+
+func TestCtlController(t *testing.T) {
+	ctl := &fakeCtl{}
+	controller := GetCtlController(ctl, filepath.Join(t.TempDir(), "nginx.pid"))
+
+	assert.Nil(t, controller.Reload())
+	assert.Nil(t, controller.Stop(true))
+	assert.Nil(t, controller.Restart())
+	assert.Equal(t, []string{"reload", "graceful-stop", "stop", "start"}, ctl.calls, "webserver must be stopped and started on restart")
+
+	restarter := &fakeRestarterCtl{}
+	assert.Nil(t, GetCtlController(restarter, "").Restart())
+	assert.Equal(t, []string{"restart"}, restarter.calls, "native restart must be used")
+
+	ctl.err = errors.New("stop failed")
+	assert.NotNil(t, controller.Restart(), "failed stop must be reported")
+	assert.Equal(t, "stop", ctl.calls[len(ctl.calls)-1], "webserver must not be started if stop failed")
+}
+
+func TestGetPidFileStatus(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "nginx.pid")
+
+	status, err := GetPidFileStatus(pidFile)
+	assert.Nilf(t, err, "could not get status: %v", err)
+	assert.False(t, status.Running, "missing pid file means stopped webserver")
+
+	err = os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	assert.Nilf(t, err, "could not write pid file: %v", err)
+	status, err = GetPidFileStatus(pidFile)
+	assert.Nilf(t, err, "could not get status: %v", err)
+	assert.Equal(t, webserver.ServiceStatus{Running: true, State: "running", Backend: webserver.ServiceBackendCtl, Pid: int32(os.Getpid())}, status)
+
+	err = os.WriteFile(pidFile, []byte("invalid"), 0644)
+	assert.Nilf(t, err, "could not write pid file: %v", err)
+	_, err = GetPidFileStatus(pidFile)
+	assert.NotNil(t, err, "invalid pid file must be reported")
+}
+
+func TestWithReload(t *testing.T) {
+	ctl := &fakeCtl{}
+	var reloaded bool
+	controller := WithReload(GetCtlController(ctl, ""), func() error {
+		reloaded = true
+
+		return nil
+	})
+
+	assert.Nil(t, controller.Reload())
+	assert.True(t, reloaded, "reload function must be used")
+	assert.Nil(t, controller.Start())
+	assert.Equal(t, []string{"start"}, ctl.calls)
+}
+
+type fakeCtl struct {
+	calls []string
+	err   error
+}
+
+func (c *fakeCtl) Reload() error {
+	return c.call("reload")
+}
+
+func (c *fakeCtl) Start() error {
+	return c.call("start")
+}
+
+func (c *fakeCtl) Stop(graceful bool) error {
+	if graceful {
+		return c.call("graceful-stop")
+	}
+
+	return c.call("stop")
+}
+
+func (c *fakeCtl) call(name string) error {
+	c.calls = append(c.calls, name)
+
+	return c.err
+}
+
+type fakeRestarterCtl struct {
+	fakeCtl
+}
+
+func (c *fakeRestarterCtl) Restart() error {
+	return c.call("restart")
+}
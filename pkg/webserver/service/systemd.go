@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/runner"
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+const systemctlCmd = "systemctl"
+
+// SystemdController controls the webserver via systemctl
+type SystemdController struct {
+	runner runner.Runner
+	unit   string
+	// gracefulStopSignal is sent to the main process of the unit on graceful stop, e.g. WINCH for apache
+	gracefulStopSignal string
+}
+
+func (s SystemdController) Reload() error {
+	return s.systemctl("reload", s.unit)
+}
+
+func (s SystemdController) Restart() error {
+	return s.systemctl("restart", s.unit)
+}
+
+func (s SystemdController) Start() error {
+	return s.systemctl("start", s.unit)
+}
+
+func (s SystemdController) Stop(graceful bool) error {
+	if graceful && s.gracefulStopSignal != "" {
+		return s.systemctl("kill", "--kill-who=main", "--signal="+s.gracefulStopSignal, s.unit)
+	}
+
+	return s.systemctl("stop", s.unit)
+}
+
+func (s SystemdController) Status() (webserver.ServiceStatus, error) {
+	status := webserver.ServiceStatus{Backend: webserver.ServiceBackendSystemd, Unit: s.unit}
+	properties, err := s.show("ActiveState", "MainPID")
+
+	if err != nil {
+		return status, err
+	}
+
+	status.State = properties["ActiveState"]
+	status.Running = status.State == "active" || status.State == "reloading"
+
+	if pid, err := strconv.ParseInt(properties["MainPID"], 10, 32); err == nil {
+		status.Pid = int32(pid)
+	}
+
+	return status, nil
+}
+
+func (s SystemdController) show(names ...string) (map[string]string, error) {
+	result, err := s.runner.Run(context.Background(), runner.Command{
+		Name: systemctlCmd,
+		Args: []string{"show", "--property=" + strings.Join(names, ","), s.unit},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get state of '%s' unit: %v", s.unit, err)
+	}
+
+	properties := make(map[string]string)
+
+	for _, line := range strings.Split(string(result.Stdout), "\n") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			properties[name] = value
+		}
+	}
+
+	return properties, nil
+}
+
+func (s SystemdController) systemctl(args ...string) error {
+	if _, err := s.runner.Run(context.Background(), runner.Command{Name: systemctlCmd, Args: args}); err != nil {
+		return fmt.Errorf("systemctl %s failed: %v", args[0], err)
+	}
+
+	return nil
+}
+
+// GetSystemdController returns controller of the systemd unit
+func GetSystemdController(cmdRunner runner.Runner, unit, gracefulStopSignal string) SystemdController {
+	return SystemdController{runner: cmdRunner, unit: unit, gracefulStopSignal: gracefulStopSignal}
+}
+
+// DetectSystemdUnit returns the first of the units loaded by systemd.
+// False is returned if systemd is not available or none of the units is loaded.
+func DetectSystemdUnit(cmdRunner runner.Runner, units []string) (string, bool) {
+	if _, err := cmdRunner.LookPath(systemctlCmd); err != nil {
+		return "", false
+	}
+
+	for _, unit := range units {
+		properties, err := GetSystemdController(cmdRunner, unit, "").show("LoadState")
+
+		if err == nil && properties["LoadState"] == "loaded" {
+			return unit, true
+		}
+	}
+
+	return "", false
+}
+
+// GetController returns controller of the backend. Auto backend uses systemd if any of the units is loaded, otherwise ctl controller.
+func GetController(cmdRunner runner.Runner, backend string, units []string, gracefulStopSignal string, ctl CtlController) (webserver.ServiceController, error) {
+	switch backend {
+	case webserver.ServiceBackendAuto, "":
+		if unit, ok := DetectSystemdUnit(cmdRunner, units); ok {
+			return GetSystemdController(cmdRunner, unit, gracefulStopSignal), nil
+		}
+
+		return ctl, nil
+	case webserver.ServiceBackendSystemd:
+		if len(units) == 1 {
+			return GetSystemdController(cmdRunner, units[0], gracefulStopSignal), nil
+		}
+
+		if unit, ok := DetectSystemdUnit(cmdRunner, units); ok {
+			return GetSystemdController(cmdRunner, unit, gracefulStopSignal), nil
+		}
+
+		return nil, fmt.Errorf("none of systemd units is loaded: %s", strings.Join(units, ", "))
+	case webserver.ServiceBackendCtl:
+		return ctl, nil
+	default:
+		return nil, fmt.Errorf("unknown service backend '%s'", backend)
+	}
+}
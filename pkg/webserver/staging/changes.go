@@ -0,0 +1,110 @@
+package staging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/r2dtools/webmng/pkg/utils"
+)
+
+// Changes are files and symlinks changed besides the parsed configuration, e.g. TLS snippets and certificate store symlinks.
+// Deferred changes are kept until the staged configuration is tested and applied with it, otherwise they are written at once.
+type Changes struct {
+	deferred bool
+	files    map[string][]byte
+	// symlinks contains new targets of the symlinks
+	symlinks map[string]string
+}
+
+// GetChanges returns changes deferred until the staged apply if deferred is set
+func GetChanges(deferred bool) *Changes {
+	return &Changes{deferred: deferred, files: make(map[string][]byte), symlinks: make(map[string]string)}
+}
+
+// WriteFile writes the file or keeps its content until the staged apply
+func (c *Changes) WriteFile(path string, content []byte) error {
+	if c == nil || !c.deferred {
+		return os.WriteFile(path, content, 0644)
+	}
+
+	c.files[path] = content
+
+	return nil
+}
+
+// ReplaceSymlink atomically points the symlink at the target or keeps the target until the staged apply
+func (c *Changes) ReplaceSymlink(target, linkPath string) error {
+	if c == nil || !c.deferred {
+		return utils.ReplaceSymlink(target, linkPath)
+	}
+
+	c.symlinks[linkPath] = target
+
+	return nil
+}
+
+// IsEmpty checks if there are no deferred changes
+func (c *Changes) IsEmpty() bool {
+	return c == nil || len(c.files)+len(c.symlinks) == 0
+}
+
+// rewriteSymlinks points the staged files referencing the deferred symlinks at their new targets,
+// e.g. /var/lib/webmng/store/example.com/rsa/current/cert.pem at the new certificate version
+func (c *Changes) rewriteSymlinks(stage *Stage) error {
+	if c == nil || len(c.symlinks) == 0 {
+		return nil
+	}
+
+	return filepath.WalkDir(stage.Dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		newContent := content
+
+		for linkPath, target := range c.symlinks {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(linkPath), target)
+			}
+
+			newContent = bytes.ReplaceAll(newContent, []byte(linkPath+string(filepath.Separator)), []byte(target+string(filepath.Separator)))
+		}
+
+		if !bytes.Equal(newContent, content) {
+			return os.WriteFile(path, newContent, 0644)
+		}
+
+		return nil
+	})
+}
+
+// writeSymlinks switches the deferred symlinks in the live tree. Deferred files are written with the configuration files.
+func (c *Changes) writeSymlinks() error {
+	if c == nil {
+		return nil
+	}
+
+	for linkPath, target := range c.symlinks {
+		if err := utils.ReplaceSymlink(target, linkPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clear drops the deferred changes, e.g. when the configuration changes are rolled back
+func (c *Changes) Clear() {
+	if c == nil {
+		return
+	}
+
+	c.files = make(map[string][]byte)
+	c.symlinks = make(map[string]string)
+}
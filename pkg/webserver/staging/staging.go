@@ -0,0 +1,342 @@
+package staging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+// Stage is a temporary copy of the webserver configuration tree changes are tested in before they are applied to the live files
+type Stage struct {
+	// Root is a live configuration tree, e.g. /etc/nginx
+	Root string
+	// Dir is a temporary directory the tree is copied to
+	Dir string
+}
+
+// GetPath returns path of the live file inside the stage
+func (s *Stage) GetPath(livePath string) (string, error) {
+	relPath, err := filepath.Rel(s.Root, livePath)
+
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file '%s' is outside of the configuration directory '%s' and could not be staged", livePath, s.Root)
+	}
+
+	return filepath.Join(s.Dir, relPath), nil
+}
+
+// WriteFile writes content of the live file to its copy inside the stage
+func (s *Stage) WriteFile(livePath string, content []byte) error {
+	path, err := s.GetPath(livePath)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// symlinked directories point at the live tree, the live files must not be changed
+	realDir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	realStageDir, err := filepath.EvalSymlinks(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(realDir+string(filepath.Separator), realStageDir+string(filepath.Separator)) {
+		return fmt.Errorf("file '%s' is inside of symlinked directory and could not be staged", livePath)
+	}
+
+	// dangling symlinks are copied as is
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+// RewriteIncludes points absolute paths of the directives at the stage, e.g. "include /etc/nginx/sites-enabled/*;".
+// Directives are matched case-insensitively.
+func (s *Stage) RewriteIncludes(directives []string) error {
+	var names []string
+
+	for _, directive := range directives {
+		names = append(names, regexp.QuoteMeta(directive))
+	}
+
+	re, err := regexp.Compile(fmt.Sprintf(`(?im)((?:^|[\s;{])(?:%s)\s+["']?)%s(["'/;\s]|$)`, strings.Join(names, "|"), regexp.QuoteMeta(s.Root)))
+	if err != nil {
+		return err
+	}
+
+	replacement := "${1}" + strings.ReplaceAll(s.Dir, "$", "$$") + "${2}"
+
+	return filepath.WalkDir(s.Dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if newContent := re.ReplaceAll(content, []byte(replacement)); string(newContent) != string(content) {
+			return os.WriteFile(path, newContent, 0644)
+		}
+
+		return nil
+	})
+}
+
+// Remove removes the stage directory
+func (s *Stage) Remove() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// CreateStage copies the configuration tree to a temporary directory.
+// Symlinked files are copied as regular files, so the stage never changes the live files.
+// Symlinked directories are not copied, they point at their targets, e.g. logs and modules directories of RHEL apache.
+func CreateStage(root string) (*Stage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "webmng-stage-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create stage directory: %v", err)
+	}
+
+	stage := &Stage{Root: root, Dir: dir}
+
+	if err = copyTree(root, dir); err != nil {
+		stage.Remove()
+
+		return nil, fmt.Errorf("could not copy configuration to stage directory: %v", err)
+	}
+
+	return stage, nil
+}
+
+// Apply writes the changed files and the deferred changes to the stage of the configuration tree and runs the test against it.
+// The live files are atomically replaced and the deferred symlinks are switched only if the test passes.
+// The stage is removed afterwards, the deferred changes are dropped once they are applied.
+func Apply(root string, files map[string][]byte, changes *Changes, includeDirectives []string, test func(stage *Stage) error) error {
+	if len(files) == 0 && changes.IsEmpty() {
+		return nil
+	}
+
+	stage, err := CreateStage(root)
+	if err != nil {
+		return err
+	}
+
+	defer stage.Remove()
+
+	files = mergeFiles(files, changes)
+
+	for path, content := range files {
+		if err = stage.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	if err = stage.RewriteIncludes(includeDirectives); err != nil {
+		return fmt.Errorf("could not rewrite includes of staged configuration: %v", err)
+	}
+
+	if err = changes.rewriteSymlinks(stage); err != nil {
+		return fmt.Errorf("could not rewrite symlinks of staged configuration: %v", err)
+	}
+
+	if err = test(stage); err != nil {
+		return fmt.Errorf("staged configuration is invalid: %v", err)
+	}
+
+	for path, content := range files {
+		if err = ReplaceFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	if err = changes.writeSymlinks(); err != nil {
+		return err
+	}
+
+	changes.Clear()
+
+	return nil
+}
+
+// mergeFiles returns the changed configuration files together with the deferred files
+func mergeFiles(files map[string][]byte, changes *Changes) map[string][]byte {
+	merged := make(map[string][]byte)
+
+	for path, content := range files {
+		merged[path] = content
+	}
+
+	if changes != nil {
+		for path, content := range changes.files {
+			merged[path] = content
+		}
+	}
+
+	return merged
+}
+
+// ReplaceFile atomically replaces the file content keeping its mode and owner. The target of the symlink is replaced.
+func ReplaceFile(path string, content []byte) error {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		realPath = path
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(realPath), "."+filepath.Base(realPath)+".webmng-")
+	if err != nil {
+		return fmt.Errorf("could not replace file '%s': %v", path, err)
+	}
+
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(content); err == nil {
+		err = tmpFile.Sync()
+	}
+
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = copyFileMode(realPath, tmpFile.Name())
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), realPath)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not replace file '%s': %v", path, err)
+	}
+
+	return nil
+}
+
+func copyFileMode(src, dst string) error {
+	info, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		return os.Chmod(dst, 0644)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && (int(stat.Uid) != os.Getuid() || int(stat.Gid) != os.Getgid()) {
+		return os.Chown(dst, int(stat.Uid), int(stat.Gid))
+	}
+
+	return nil
+}
+
+func copyTree(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dst, srcInfo.Mode().Perm()|0700); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+		isSymlink := entry.Type()&os.ModeSymlink != 0
+		info, err := os.Stat(srcPath)
+
+		if err != nil {
+			if !isSymlink {
+				return err
+			}
+
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+
+			if err = os.Symlink(target, dstPath); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		switch {
+		case info.IsDir() && isSymlink:
+			target, err := filepath.EvalSymlinks(srcPath)
+			if err != nil {
+				return err
+			}
+
+			err = os.Symlink(target, dstPath)
+			if err != nil {
+				return err
+			}
+		case info.IsDir():
+			if err = copyTree(srcPath, dstPath); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err = copyFile(srcPath, dstPath, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+
+		return err
+	}
+
+	return dstFile.Close()
+}
@@ -0,0 +1,188 @@
+package staging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateStage(t *testing.T) {
+	root, external := createConfigTree(t)
+
+	stage, err := CreateStage(root)
+	assert.Nilf(t, err, "could not create stage: %v", err)
+	defer stage.Remove()
+
+	info, err := os.Lstat(filepath.Join(stage.Dir, "sites-enabled", "example.com"))
+	assert.Nilf(t, err, "symlinked file is not staged: %v", err)
+	assert.True(t, info.Mode().IsRegular(), "symlinked file must be copied as regular file")
+
+	target, err := os.Readlink(filepath.Join(stage.Dir, "logs"))
+	assert.Nilf(t, err, "symlinked directory must be linked: %v", err)
+	assert.Equal(t, external, target)
+
+	path, err := stage.GetPath(filepath.Join(root, "sites-enabled", "example.com"))
+	assert.Nilf(t, err, "could not get staged path: %v", err)
+	assert.Equal(t, filepath.Join(stage.Dir, "sites-enabled", "example.com"), path)
+
+	err = stage.WriteFile(filepath.Join(root, "sites-enabled", "example.com"), []byte("server {}\n"))
+	assert.Nilf(t, err, "could not write staged file: %v", err)
+	assertFileContent(t, filepath.Join(root, "sites-available", "example.com"), "server { listen 80; }\n")
+
+	_, err = stage.GetPath(filepath.Join(filepath.Dir(root), "other.conf"))
+	assert.NotNil(t, err, "file outside of the root must not be staged")
+	err = stage.WriteFile(filepath.Join(root, "logs", "error.log"), []byte("log"))
+	assert.NotNil(t, err, "file inside of symlinked directory must not be staged")
+	_, err = os.Stat(filepath.Join(external, "error.log"))
+	assert.True(t, os.IsNotExist(err), "live file must not be created via symlinked directory")
+}
+
+func TestRewriteIncludes(t *testing.T) {
+	root, _ := createConfigTree(t)
+
+	stage, err := CreateStage(root)
+	assert.Nilf(t, err, "could not create stage: %v", err)
+	defer stage.Remove()
+
+	content := strings.Join([]string{
+		"include " + root + "/sites-enabled/*;",
+		"http { include \"" + root + "/mime.types\"; }",
+		"IncludeOptional " + root + "/conf-enabled/*.conf",
+		"ServerRoot \"" + root + "\"",
+		"include " + root + "2/other.conf;",
+		"ssl_certificate " + root + "/ssl/cert.pem;",
+	}, "\n")
+	err = os.WriteFile(filepath.Join(stage.Dir, "nginx.conf"), []byte(content), 0644)
+	assert.Nilf(t, err, "could not write staged file: %v", err)
+
+	err = stage.RewriteIncludes([]string{"include", "IncludeOptional", "ServerRoot"})
+	assert.Nilf(t, err, "could not rewrite includes: %v", err)
+	assertFileContent(t, filepath.Join(stage.Dir, "nginx.conf"), strings.Join([]string{
+		"include " + stage.Dir + "/sites-enabled/*;",
+		"http { include \"" + stage.Dir + "/mime.types\"; }",
+		"IncludeOptional " + stage.Dir + "/conf-enabled/*.conf",
+		"ServerRoot \"" + stage.Dir + "\"",
+		"include " + root + "2/other.conf;",
+		"ssl_certificate " + root + "/ssl/cert.pem;",
+	}, "\n"))
+}
+
+func TestApply(t *testing.T) {
+	root, _ := createConfigTree(t)
+	hostPath := filepath.Join(root, "sites-enabled", "example.com")
+	var stageDir string
+
+	err := Apply(root, map[string][]byte{hostPath: []byte("server { listen 443 ssl; }\n")}, nil, []string{"include"}, func(stage *Stage) error {
+		stageDir = stage.Dir
+		assertFileContent(t, filepath.Join(stage.Dir, "nginx.conf"), "include "+stage.Dir+"/sites-enabled/*;\n")
+		assertFileContent(t, filepath.Join(stage.Dir, "sites-enabled", "example.com"), "server { listen 443 ssl; }\n")
+		assertFileContent(t, hostPath, "server { listen 80; }\n")
+
+		return errors.New("unknown directive")
+	})
+	assert.NotNil(t, err, "failed test must be reported")
+	assert.Contains(t, err.Error(), "unknown directive")
+	assertFileContent(t, hostPath, "server { listen 80; }\n")
+	_, err = os.Stat(stageDir)
+	assert.True(t, os.IsNotExist(err), "stage must be removed")
+
+	err = Apply(root, map[string][]byte{hostPath: []byte("server { listen 443 ssl; }\n")}, nil, []string{"include"}, func(stage *Stage) error {
+		return nil
+	})
+	assert.Nilf(t, err, "could not apply changes: %v", err)
+	assertFileContent(t, filepath.Join(root, "sites-available", "example.com"), "server { listen 443 ssl; }\n")
+	info, err := os.Lstat(hostPath)
+	assert.Nilf(t, err, "could not stat host config: %v", err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "symlink must be kept, its target must be replaced")
+}
+
+func TestApplyDeferredChanges(t *testing.T) {
+	root, _ := createConfigTree(t)
+	snippetPath := filepath.Join(root, "webmng-tls-modern.conf")
+	storeDir := t.TempDir()
+	linkPath := filepath.Join(storeDir, "current")
+	writeFile(t, filepath.Join(storeDir, "v1", "cert.pem"), "v1")
+	writeFile(t, filepath.Join(storeDir, "v2", "cert.pem"), "v2")
+	err := os.Symlink("v1", linkPath)
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+
+	changes := GetChanges(true)
+	assert.Nil(t, changes.WriteFile(snippetPath, []byte("ssl_protocols TLSv1.3;\n")))
+	assert.Nil(t, changes.ReplaceSymlink("v2", linkPath))
+	_, err = os.Stat(snippetPath)
+	assert.True(t, os.IsNotExist(err), "deferred file must not be written before apply")
+
+	hostPath := filepath.Join(root, "sites-enabled", "example.com")
+	hostConfig := []byte("server { ssl_certificate " + linkPath + "/cert.pem; }\n")
+	err = Apply(root, map[string][]byte{hostPath: hostConfig}, changes, []string{"include"}, func(stage *Stage) error {
+		assertFileContent(t, filepath.Join(stage.Dir, "webmng-tls-modern.conf"), "ssl_protocols TLSv1.3;\n")
+		assertFileContent(t, filepath.Join(stage.Dir, "sites-enabled", "example.com"), "server { ssl_certificate "+storeDir+"/v2/cert.pem; }\n")
+
+		return errors.New("unknown directive")
+	})
+	assert.NotNil(t, err, "failed test must be reported")
+	_, err = os.Stat(snippetPath)
+	assert.True(t, os.IsNotExist(err), "deferred file must not be written if the test fails")
+	assertFileContent(t, filepath.Join(linkPath, "cert.pem"), "v1")
+
+	err = Apply(root, nil, changes, []string{"include"}, func(stage *Stage) error {
+		return nil
+	})
+	assert.Nilf(t, err, "could not apply deferred changes: %v", err)
+	assertFileContent(t, snippetPath, "ssl_protocols TLSv1.3;\n")
+	assertFileContent(t, filepath.Join(linkPath, "cert.pem"), "v2")
+	assert.True(t, changes.IsEmpty(), "applied changes must be dropped")
+}
+
+func TestReplaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.conf")
+	err := os.WriteFile(path, []byte("events {}\n"), 0600)
+	assert.Nilf(t, err, "could not write file: %v", err)
+
+	err = ReplaceFile(path, []byte("events {}\nhttp {}\n"))
+	assert.Nilf(t, err, "could not replace file: %v", err)
+	assertFileContent(t, path, "events {}\nhttp {}\n")
+	info, err := os.Stat(path)
+	assert.Nilf(t, err, "could not stat file: %v", err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "file mode must be kept")
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.Nilf(t, err, "could not read directory: %v", err)
+	assert.Equal(t, 1, len(entries), "temporary file must be removed")
+}
+
+// createConfigTree creates nginx like configuration with symlinked host config and logs directory
+func createConfigTree(t *testing.T) (string, string) {
+	root := filepath.Join(t.TempDir(), "nginx")
+	external := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "nginx.conf"), "include "+root+"/sites-enabled/*;\n")
+	writeFile(t, filepath.Join(root, "sites-available", "example.com"), "server { listen 80; }\n")
+	err := os.MkdirAll(filepath.Join(root, "sites-enabled"), 0755)
+	assert.Nilf(t, err, "could not create directory: %v", err)
+	err = os.Symlink("../sites-available/example.com", filepath.Join(root, "sites-enabled", "example.com"))
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+	err = os.Symlink(external, filepath.Join(root, "logs"))
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+	err = os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "dangling"))
+	assert.Nilf(t, err, "could not create symlink: %v", err)
+
+	return root, external
+}
+
+func writeFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	assert.Nilf(t, err, "could not create directory: %v", err)
+	err = os.WriteFile(path, []byte(content), 0644)
+	assert.Nilf(t, err, "could not write file: %v", err)
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	content, err := os.ReadFile(path)
+	assert.Nilf(t, err, "could not read file: %v", err)
+	assert.Equal(t, expected, string(content))
+}
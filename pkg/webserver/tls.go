@@ -0,0 +1,87 @@
+package webserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver/headers"
+	"golang.org/x/exp/slices"
+)
+
+// CertificateFiles contains paths of a certificate and its key configured for a host
+type CertificateFiles struct {
+	CertPath,
+	KeyPath string
+}
+
+// TLSConfig contains TLS settings effective for a host
+type TLSConfig struct {
+	// WebServer is a webserver code: apache, nginx
+	WebServer string
+	Host      Host
+	// Protocols contains enabled protocols, e.g. TLSv1.2 TLSv1.3
+	Protocols []string
+	// Ciphers is a cipher list in OpenSSL format, empty if the webserver default is used
+	Ciphers             string
+	PreferServerCiphers bool
+	// HstsHeader is a Strict-Transport-Security header value, empty if the header is not sent
+	HstsHeader     string
+	OcspStapling   bool
+	SessionTickets bool
+	DHParamPath    string
+	Certificates   []CertificateFiles
+}
+
+// StaplingConfig contains OCSP stapling settings
+type StaplingConfig struct {
+	// ChainPath is a path of the issuer certificates used to verify OCSP responses.
+	// Certificates following the leaf one in the host certificate file are used if empty.
+	ChainPath string
+	// Resolvers are DNS servers used to resolve OCSP responder host name
+	Resolvers []string
+}
+
+// Client certificate verification modes
+const (
+	ClientVerifyOn           = "on"
+	ClientVerifyOptional     = "optional"
+	ClientVerifyOptionalNoCA = "optional_no_ca"
+)
+
+// ClientAuthConfig contains client certificate authentication (mTLS) settings
+type ClientAuthConfig struct {
+	// CACertPath is a path of the CA bundle used to verify client certificates
+	CACertPath string
+	// Verify is a verification mode: on, optional, optional_no_ca
+	Verify string
+	// Depth is a maximal client certificate chain depth, the webserver default is used if 0
+	Depth int
+	// Path restricts authentication to the URL path, the whole host is protected if empty
+	Path string
+	// ForwardHeader is a request header name used to pass the verified client subject DN to upstreams
+	ForwardHeader string
+}
+
+// Validate checks the config and the CA bundle
+func (c ClientAuthConfig) Validate() error {
+	if c.CACertPath == "" {
+		return errors.New("CA certificate path is not specified")
+	}
+
+	if !slices.Contains([]string{ClientVerifyOn, ClientVerifyOptional, ClientVerifyOptionalNoCA}, c.Verify) {
+		return fmt.Errorf("invalid client verification mode '%s', supported modes: on, optional, optional_no_ca", c.Verify)
+	}
+
+	if c.Depth < 0 {
+		return errors.New("verification depth could not be negative")
+	}
+
+	if c.ForwardHeader != "" {
+		if err := (headers.Header{Name: c.ForwardHeader, Value: "dn"}).Validate(); err != nil {
+			return err
+		}
+	}
+
+	return certificate.ValidateCABundle(c.CACertPath)
+}
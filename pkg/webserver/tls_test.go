@@ -0,0 +1,34 @@
+package webserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAuthConfigValidate(t *testing.T) {
+	config := ClientAuthConfig{
+		CACertPath:    certificateDir + "/example.com.issuer.crt",
+		Verify:        ClientVerifyOn,
+		Depth:         2,
+		ForwardHeader: "X-Client-DN",
+	}
+	err := config.Validate()
+	assert.Nilf(t, err, "config must be valid: %v", err)
+
+	invalidConfig := config
+	invalidConfig.Verify = "require"
+	assert.NotNil(t, invalidConfig.Validate(), "unknown verification mode must not be allowed")
+
+	invalidConfig = config
+	invalidConfig.Depth = -1
+	assert.NotNil(t, invalidConfig.Validate(), "negative depth must not be allowed")
+
+	invalidConfig = config
+	invalidConfig.ForwardHeader = "X Client"
+	assert.NotNil(t, invalidConfig.Validate(), "invalid header name must not be allowed")
+
+	invalidConfig = config
+	invalidConfig.CACertPath = certificateDir + "/example.com.crt"
+	assert.NotNil(t, invalidConfig.Validate(), "leaf certificate must not be accepted as a CA bundle")
+}
@@ -0,0 +1,445 @@
+package tlsaudit
+
+import (
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/r2dtools/webmng/pkg/certificate"
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	StatusOk      = "ok"
+	StatusFail    = "fail"
+	StatusUnknown = "unknown"
+
+	GradeNone = "none"
+)
+
+var hstsMaxAgeRegex = regexp.MustCompile(`(?i)max-age\s*=\s*"?(\d+)"?`)
+
+// cipherKeywords are OpenSSL cipher string elements that could not be expanded without OpenSSL
+var cipherKeywords = []string{"!", "+", "@", "-", "ALL", "DEFAULT", "HIGH", "MEDIUM", "LOW", "COMPLEMENTOF"}
+
+// Check is a result of a single setting check against a profile
+type Check struct {
+	Setting string `json:"setting"`
+	Status  string `json:"status"`
+	Value   string `json:"value"`
+	Message string `json:"message,omitempty"`
+	// Remediation contains directives that should be set to comply with the profile
+	Remediation []string `json:"remediation,omitempty"`
+}
+
+// ProfileResult is a result of the host TLS configuration check against a profile
+type ProfileResult struct {
+	Profile   string  `json:"profile"`
+	Compliant bool    `json:"compliant"`
+	Checks    []Check `json:"checks"`
+}
+
+// Report is a TLS audit report for a host
+type Report struct {
+	ServerName string `json:"serverName"`
+	FilePath   string `json:"filePath"`
+	// Grade is the strictest profile the host complies with
+	Grade    string          `json:"grade"`
+	Profiles []ProfileResult `json:"profiles"`
+}
+
+// GetProfileResult returns check results for the profile
+func (r Report) GetProfileResult(profile string) (ProfileResult, bool) {
+	for _, result := range r.Profiles {
+		if result.Profile == profile {
+			return result, true
+		}
+	}
+
+	return ProfileResult{}, false
+}
+
+type certificateInfo struct {
+	keyInfo   certificate.KeyInfo
+	signature x509.SignatureAlgorithm
+}
+
+type auditor struct {
+	config       webserver.TLSConfig
+	certificates []certificateInfo
+	certErrors   []string
+	dhParamSize  int
+	dhParamError error
+}
+
+// Audit checks TLS configuration of a host against Mozilla modern, intermediate and old profiles
+func Audit(config webserver.TLSConfig) Report {
+	a := auditor{config: config}
+	a.loadFiles()
+
+	report := Report{
+		ServerName: config.Host.ServerName,
+		FilePath:   config.Host.FilePath,
+		Grade:      GradeNone,
+	}
+
+	for _, profile := range tlsprofile.GetProfiles() {
+		result := a.audit(profile)
+		report.Profiles = append(report.Profiles, result)
+
+		if result.Compliant && report.Grade == GradeNone {
+			report.Grade = profile.Name
+		}
+	}
+
+	return report
+}
+
+func (a *auditor) loadFiles() {
+	for _, files := range a.config.Certificates {
+		leaf, err := certificate.LoadLeafCertificate(files.CertPath)
+		if err != nil {
+			a.certErrors = append(a.certErrors, err.Error())
+			continue
+		}
+
+		keyInfo, err := certificate.GetCertificateKeyInfo(leaf)
+		if err != nil {
+			a.certErrors = append(a.certErrors, fmt.Sprintf("certificate %s: %v", files.CertPath, err))
+			continue
+		}
+
+		if files.KeyPath != "" {
+			if _, err := certificate.LoadPrivateKey(files.KeyPath); err != nil {
+				a.certErrors = append(a.certErrors, fmt.Sprintf("could not load key %s: %v", files.KeyPath, err))
+			}
+		}
+
+		a.certificates = append(a.certificates, certificateInfo{
+			keyInfo:   keyInfo,
+			signature: leaf.SignatureAlgorithm,
+		})
+	}
+
+	if a.config.DHParamPath != "" {
+		a.dhParamSize, a.dhParamError = certificate.LoadDHParamsSize(a.config.DHParamPath)
+	}
+}
+
+func (a *auditor) audit(profile tlsprofile.Profile) ProfileResult {
+	checks := []Check{
+		a.checkProtocols(profile),
+		a.checkCiphers(profile),
+		a.checkPreferServerCiphers(profile),
+		a.checkHsts(profile),
+		a.checkOcspStapling(profile),
+		a.checkSessionTickets(profile),
+		a.checkDHParams(profile),
+		a.checkCertificateKey(profile),
+		a.checkCertificateSignature(profile),
+	}
+
+	compliant := true
+
+	for i, check := range checks {
+		if check.Status == StatusOk {
+			continue
+		}
+
+		compliant = false
+
+		for _, directive := range profile.GetSettingDirectives(a.config.WebServer, check.Setting, a.config.DHParamPath) {
+			checks[i].Remediation = append(checks[i].Remediation, directive.ToString(a.config.WebServer))
+		}
+	}
+
+	return ProfileResult{
+		Profile:   profile.Name,
+		Compliant: compliant,
+		Checks:    checks,
+	}
+}
+
+func (a *auditor) checkProtocols(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingProtocols,
+		Status:  StatusOk,
+		Value:   strings.Join(a.config.Protocols, " "),
+	}
+
+	extra := getDifference(a.config.Protocols, profile.Protocols)
+
+	if len(extra) > 0 {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("protocols are not allowed: %s", strings.Join(extra, ", "))
+	} else if len(a.config.Protocols) == 0 {
+		check.Status = StatusFail
+		check.Message = "no protocols are enabled"
+	}
+
+	return check
+}
+
+func (a *auditor) checkCiphers(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingCiphers,
+		Status:  StatusOk,
+		Value:   a.config.Ciphers,
+	}
+
+	// only TLSv1.3 is used and its ciphers are not configurable
+	if len(getDifference(a.config.Protocols, []string{tlsprofile.TLSv1_3})) == 0 {
+		return check
+	}
+
+	if len(profile.Ciphers) == 0 {
+		check.Status = StatusFail
+		check.Message = "profile does not allow ciphers of TLSv1.2 and below"
+
+		return check
+	}
+
+	if a.config.Ciphers == "" {
+		check.Status = StatusUnknown
+		check.Message = "webserver default ciphers are used"
+
+		return check
+	}
+
+	ciphers := strings.FieldsFunc(a.config.Ciphers, func(r rune) bool {
+		return r == ':' || r == ',' || r == ' '
+	})
+
+	for _, cipher := range ciphers {
+		for _, keyword := range cipherKeywords {
+			if strings.HasPrefix(strings.ToUpper(cipher), keyword) {
+				check.Status = StatusUnknown
+				check.Message = fmt.Sprintf("cipher string element '%s' could not be verified without OpenSSL", cipher)
+
+				return check
+			}
+		}
+	}
+
+	notAllowed := getDifference(ciphers, profile.Ciphers)
+
+	if len(notAllowed) > 0 {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("ciphers are not allowed: %s", strings.Join(notAllowed, ", "))
+	}
+
+	return check
+}
+
+func (a *auditor) checkPreferServerCiphers(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingPreferServerCiphers,
+		Status:  StatusOk,
+		Value:   strconv.FormatBool(a.config.PreferServerCiphers),
+	}
+
+	if a.config.PreferServerCiphers != profile.PreferServerCiphers {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("server ciphers preference should be %t", profile.PreferServerCiphers)
+	}
+
+	return check
+}
+
+func (a *auditor) checkHsts(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingHsts,
+		Status:  StatusOk,
+		Value:   a.config.HstsHeader,
+	}
+
+	if a.config.HstsHeader == "" {
+		check.Status = StatusFail
+		check.Message = "Strict-Transport-Security header is not sent"
+
+		return check
+	}
+
+	matches := hstsMaxAgeRegex.FindStringSubmatch(a.config.HstsHeader)
+	if matches == nil {
+		check.Status = StatusFail
+		check.Message = "Strict-Transport-Security header has no max-age"
+
+		return check
+	}
+
+	maxAge, _ := strconv.Atoi(matches[1])
+	if maxAge < profile.HstsMaxAge {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("Strict-Transport-Security max-age should be at least %d", profile.HstsMaxAge)
+	}
+
+	return check
+}
+
+func (a *auditor) checkOcspStapling(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingOcspStapling,
+		Status:  StatusOk,
+		Value:   strconv.FormatBool(a.config.OcspStapling),
+	}
+
+	if profile.OcspStapling && !a.config.OcspStapling {
+		check.Status = StatusFail
+		check.Message = "OCSP stapling is disabled"
+	}
+
+	return check
+}
+
+func (a *auditor) checkSessionTickets(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingSessionTickets,
+		Status:  StatusOk,
+		Value:   strconv.FormatBool(a.config.SessionTickets),
+	}
+
+	if a.config.SessionTickets != profile.SessionTickets {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("session tickets should be %s", onOff(profile.SessionTickets))
+	}
+
+	return check
+}
+
+func (a *auditor) checkDHParams(profile tlsprofile.Profile) Check {
+	check := Check{
+		Setting: tlsprofile.SettingDHParams,
+		Status:  StatusOk,
+		Value:   a.config.DHParamPath,
+	}
+
+	if a.config.DHParamPath == "" {
+		return check
+	}
+
+	if a.dhParamError != nil {
+		check.Status = StatusUnknown
+		check.Message = a.dhParamError.Error()
+
+		return check
+	}
+
+	check.Value = fmt.Sprintf("%s (%d bits)", a.config.DHParamPath, a.dhParamSize)
+
+	if profile.DHParamSize == 0 {
+		check.Status = StatusFail
+		check.Message = "profile does not use DHE ciphers, DH parameters should be removed"
+	} else if a.dhParamSize < profile.DHParamSize {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("DH parameters should be at least %d bits", profile.DHParamSize)
+	}
+
+	return check
+}
+
+func (a *auditor) checkCertificateKey(profile tlsprofile.Profile) Check {
+	check := Check{Setting: tlsprofile.SettingCertificateKey}
+
+	if status, message, ok := a.getCertificatesError(); !ok {
+		check.Status = status
+		check.Message = message
+
+		return check
+	}
+
+	var values []string
+	check.Status = StatusFail
+	check.Message = fmt.Sprintf("certificate key type should be one of: %s", strings.Join(profile.CertificateTypes, ", "))
+
+	for _, cert := range a.certificates {
+		values = append(values, cert.keyInfo.ToString())
+
+		if !slices.Contains(profile.CertificateTypes, cert.keyInfo.Type) {
+			continue
+		}
+
+		if cert.keyInfo.Type == certificate.KeyTypeRSA && cert.keyInfo.Size < profile.MinRSAKeySize {
+			check.Message = fmt.Sprintf("RSA key should be at least %d bits", profile.MinRSAKeySize)
+			continue
+		}
+
+		if cert.keyInfo.Type == certificate.KeyTypeECDSA && profile.Name == tlsprofile.Modern && cert.keyInfo.Curve != "P-256" {
+			check.Message = "ECDSA key should use P-256 curve"
+			continue
+		}
+
+		check.Status = StatusOk
+		check.Message = ""
+	}
+
+	check.Value = strings.Join(values, ", ")
+
+	return check
+}
+
+func (a *auditor) checkCertificateSignature(profile tlsprofile.Profile) Check {
+	check := Check{Setting: tlsprofile.SettingCertificateSignature}
+
+	if status, message, ok := a.getCertificatesError(); !ok {
+		check.Status = status
+		check.Message = message
+
+		return check
+	}
+
+	var values []string
+	check.Status = StatusFail
+	check.Message = fmt.Sprintf("certificate signature algorithm should be one of: %s", strings.Join(profile.CertificateSignatures, ", "))
+
+	for _, cert := range a.certificates {
+		signature := cert.signature.String()
+		values = append(values, signature)
+
+		if slices.Contains(profile.CertificateSignatures, signature) {
+			check.Status = StatusOk
+			check.Message = ""
+		}
+	}
+
+	check.Value = strings.Join(values, ", ")
+
+	return check
+}
+
+func (a *auditor) getCertificatesError() (string, string, bool) {
+	if len(a.config.Certificates) == 0 {
+		return StatusFail, "certificate is not configured", false
+	}
+
+	if len(a.certificates) == 0 {
+		return StatusUnknown, strings.Join(a.certErrors, "; "), false
+	}
+
+	return "", "", true
+}
+
+// getDifference returns elements of a that are absent in b
+func getDifference(a, b []string) []string {
+	var diff []string
+
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			diff = append(diff, item)
+		}
+	}
+
+	return diff
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+
+	return "off"
+}
@@ -0,0 +1,55 @@
+package tlsaudit
+
+import (
+	"testing"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/tlsprofile"
+	"github.com/stretchr/testify/assert"
+)
+
+const certificateDir = "../../../test/certificate"
+
+func TestAudit(t *testing.T) {
+	config := webserver.TLSConfig{
+		WebServer:      webserver.Nginx,
+		Host:           webserver.Host{ServerName: "example.com"},
+		Protocols:      []string{"TLSv1.2", "TLSv1.3"},
+		Ciphers:        "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256",
+		HstsHeader:     "max-age=63072000; includeSubDomains",
+		OcspStapling:   true,
+		SessionTickets: false,
+		Certificates: []webserver.CertificateFiles{
+			{CertPath: certificateDir + "/example.com.crt", KeyPath: certificateDir + "/example.com.key"},
+		},
+	}
+
+	report := Audit(config)
+	assert.Equal(t, tlsprofile.Intermediate, report.Grade)
+
+	result, ok := report.GetProfileResult(tlsprofile.Modern)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, false, result.Compliant)
+
+	for _, check := range result.Checks {
+		if check.Setting == tlsprofile.SettingProtocols {
+			assert.Equal(t, StatusFail, check.Status)
+			assert.Equal(t, []string{"ssl_protocols TLSv1.3;"}, check.Remediation)
+		}
+	}
+
+	config.SessionTickets = true
+	config.Protocols = []string{"TLSv1", "TLSv1.2"}
+	config.WebServer = webserver.Apache
+	report = Audit(config)
+	assert.Equal(t, GradeNone, report.Grade)
+
+	result, _ = report.GetProfileResult(tlsprofile.Intermediate)
+
+	for _, check := range result.Checks {
+		if check.Setting == tlsprofile.SettingSessionTickets {
+			assert.Equal(t, StatusFail, check.Status)
+			assert.Equal(t, []string{"SSLSessionTickets off"}, check.Remediation)
+		}
+	}
+}
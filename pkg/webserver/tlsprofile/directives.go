@@ -0,0 +1,208 @@
+package tlsprofile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	SettingProtocols            = "protocols"
+	SettingCiphers              = "ciphers"
+	SettingPreferServerCiphers  = "prefer_server_ciphers"
+	SettingCurves               = "curves"
+	SettingHsts                 = "hsts"
+	SettingOcspStapling         = "ocsp_stapling"
+	SettingSessionTickets       = "session_tickets"
+	SettingSessionTimeout       = "session_timeout"
+	SettingDHParams             = "dh_params"
+	SettingCertificateKey       = "certificate_key"
+	SettingCertificateSignature = "certificate_signature"
+)
+
+// PolicySettings are settings applied to hosts by a TLS policy
+var PolicySettings = []string{
+	SettingProtocols,
+	SettingCiphers,
+	SettingPreferServerCiphers,
+	SettingCurves,
+	SettingSessionTimeout,
+	SettingSessionTickets,
+	SettingOcspStapling,
+}
+
+// SnippetFilePrefix is a prefix of shared TLS profile snippet files
+const SnippetFilePrefix = "webmng-tls-"
+
+const (
+	apacheWebServer = "apache"
+	nginxWebServer  = "nginx"
+)
+
+// Directive is a webserver directive rendered for a profile setting
+type Directive struct {
+	Name   string
+	Values []string
+}
+
+// ToString returns directive in the webserver configuration syntax
+func (d Directive) ToString(webServer string) string {
+	line := strings.Join(append([]string{d.Name}, d.Values...), " ")
+
+	if webServer == nginxWebServer {
+		return line + ";"
+	}
+
+	return line
+}
+
+// GetSettingDirectives returns directives which configure the setting according to the profile.
+// dhParamPath is used for the dh_params setting only.
+func (p Profile) GetSettingDirectives(webServer, setting, dhParamPath string) []Directive {
+	if webServer == apacheWebServer {
+		return p.getApacheSettingDirectives(setting, dhParamPath)
+	}
+
+	return p.getNginxSettingDirectives(setting, dhParamPath)
+}
+
+// GetDirectives returns directives for all the settings supported by the webserver version
+func (p Profile) GetDirectives(webServer string, settings []string, dhParamPath string) []Directive {
+	var directives []Directive
+
+	for _, setting := range settings {
+		if slices.Contains(p.UnsupportedSettings, setting) {
+			continue
+		}
+
+		directives = append(directives, p.GetSettingDirectives(webServer, setting, dhParamPath)...)
+	}
+
+	return directives
+}
+
+// GetPolicyDirectives returns directives which any profile could write for the policy settings regardless of the webserver version.
+// They are removed from hosts before the profile is applied, so no directive of the previously applied profile is left.
+// Values are set only if they distinguish the directive, e.g. SSLOpenSSLConfCmd Curves.
+func GetPolicyDirectives(webServer string) []Directive {
+	profile := Profile{Ciphers: []string{""}}
+	var directives []Directive
+
+	for _, setting := range PolicySettings {
+		for _, directive := range profile.GetSettingDirectives(webServer, setting, "") {
+			directive.Values = nil
+
+			if directive.Name == "SSLOpenSSLConfCmd" {
+				directive.Values = []string{"Curves"}
+			}
+
+			directives = append(directives, directive)
+		}
+	}
+
+	return directives
+}
+
+// GetSnippetFileName returns name of the shared snippet file for the profile
+func (p Profile) GetSnippetFileName() string {
+	return SnippetFilePrefix + p.Name + ".conf"
+}
+
+// GetSnippetContent returns content of the shared snippet file with the directives
+func (p Profile) GetSnippetContent(webServer string, directives []Directive) string {
+	lines := []string{fmt.Sprintf("# Mozilla %s TLS profile. The file is managed by webmng, do not edit it manually.", p.Name)}
+
+	for _, directive := range directives {
+		lines = append(lines, directive.ToString(webServer))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (p Profile) getNginxSettingDirectives(setting, dhParamPath string) []Directive {
+	switch setting {
+	case SettingProtocols:
+		return []Directive{{"ssl_protocols", p.Protocols}}
+	case SettingCiphers:
+		if len(p.Ciphers) == 0 {
+			return nil
+		}
+
+		return []Directive{{"ssl_ciphers", []string{p.GetCiphersString()}}}
+	case SettingPreferServerCiphers:
+		return []Directive{{"ssl_prefer_server_ciphers", []string{onOff(p.PreferServerCiphers)}}}
+	case SettingCurves:
+		return []Directive{{"ssl_ecdh_curve", []string{strings.Join(p.Curves, ":")}}}
+	case SettingHsts:
+		return []Directive{{"add_header", []string{"Strict-Transport-Security", fmt.Sprintf(`"%s"`, p.GetHstsHeader()), "always"}}}
+	case SettingOcspStapling:
+		return []Directive{
+			{"ssl_stapling", []string{onOff(p.OcspStapling)}},
+			{"ssl_stapling_verify", []string{onOff(p.OcspStapling)}},
+		}
+	case SettingSessionTickets:
+		return []Directive{{"ssl_session_tickets", []string{onOff(p.SessionTickets)}}}
+	case SettingSessionTimeout:
+		return []Directive{
+			{"ssl_session_timeout", []string{p.SessionTimeout}},
+			{"ssl_session_cache", []string{"shared:MozSSL:10m"}},
+		}
+	case SettingDHParams:
+		if p.DHParamSize == 0 || dhParamPath == "" {
+			return nil
+		}
+
+		return []Directive{{"ssl_dhparam", []string{dhParamPath}}}
+	}
+
+	return nil
+}
+
+func (p Profile) getApacheSettingDirectives(setting, dhParamPath string) []Directive {
+	switch setting {
+	case SettingProtocols:
+		values := []string{"-all"}
+
+		for _, protocol := range p.Protocols {
+			values = append(values, "+"+protocol)
+		}
+
+		return []Directive{{"SSLProtocol", values}}
+	case SettingCiphers:
+		if len(p.Ciphers) == 0 {
+			return nil
+		}
+
+		return []Directive{{"SSLCipherSuite", []string{p.GetCiphersString()}}}
+	case SettingPreferServerCiphers:
+		return []Directive{{"SSLHonorCipherOrder", []string{onOff(p.PreferServerCiphers)}}}
+	case SettingCurves:
+		return []Directive{{"SSLOpenSSLConfCmd", []string{"Curves", strings.Join(p.Curves, ":")}}}
+	case SettingHsts:
+		return []Directive{{"Header", []string{"always", "set", "Strict-Transport-Security", fmt.Sprintf(`"%s"`, p.GetHstsHeader())}}}
+	case SettingOcspStapling:
+		return []Directive{{"SSLUseStapling", []string{onOff(p.OcspStapling)}}}
+	case SettingSessionTickets:
+		return []Directive{{"SSLSessionTickets", []string{onOff(p.SessionTickets)}}}
+	case SettingSessionTimeout:
+		return []Directive{{"SSLSessionCacheTimeout", []string{strconv.Itoa(p.SessionTimeoutSeconds)}}}
+	case SettingDHParams:
+		if p.DHParamSize == 0 || dhParamPath == "" {
+			return nil
+		}
+
+		return []Directive{{"SSLOpenSSLConfCmd", []string{"DHParameters", dhParamPath}}}
+	}
+
+	return nil
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+
+	return "off"
+}
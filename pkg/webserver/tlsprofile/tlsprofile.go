@@ -0,0 +1,165 @@
+package tlsprofile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mozilla server side TLS profiles, see https://wiki.mozilla.org/Security/Server_Side_TLS
+const (
+	Modern       = "modern"
+	Intermediate = "intermediate"
+	Old          = "old"
+)
+
+const (
+	TLSv1   = "TLSv1"
+	TLSv1_1 = "TLSv1.1"
+	TLSv1_2 = "TLSv1.2"
+	TLSv1_3 = "TLSv1.3"
+)
+
+// AllProtocols contains protocols known by apache and nginx in ascending order
+var AllProtocols = []string{"SSLv2", "SSLv3", TLSv1, TLSv1_1, TLSv1_2, TLSv1_3}
+
+var intermediateCiphers = []string{
+	"ECDHE-ECDSA-AES128-GCM-SHA256",
+	"ECDHE-RSA-AES128-GCM-SHA256",
+	"ECDHE-ECDSA-AES256-GCM-SHA384",
+	"ECDHE-RSA-AES256-GCM-SHA384",
+	"ECDHE-ECDSA-CHACHA20-POLY1305",
+	"ECDHE-RSA-CHACHA20-POLY1305",
+	"DHE-RSA-AES128-GCM-SHA256",
+	"DHE-RSA-AES256-GCM-SHA384",
+	"DHE-RSA-CHACHA20-POLY1305",
+}
+
+var oldCiphers = append(append([]string{}, intermediateCiphers...),
+	"ECDHE-ECDSA-AES128-SHA256",
+	"ECDHE-RSA-AES128-SHA256",
+	"ECDHE-ECDSA-AES128-SHA",
+	"ECDHE-RSA-AES128-SHA",
+	"ECDHE-ECDSA-AES256-SHA384",
+	"ECDHE-RSA-AES256-SHA384",
+	"ECDHE-ECDSA-AES256-SHA",
+	"ECDHE-RSA-AES256-SHA",
+	"DHE-RSA-AES128-SHA256",
+	"DHE-RSA-AES256-SHA256",
+	"AES128-GCM-SHA256",
+	"AES256-GCM-SHA384",
+	"AES128-SHA256",
+	"AES256-SHA256",
+	"AES128-SHA",
+	"AES256-SHA",
+	"DES-CBC3-SHA",
+)
+
+// Profile describes requirements of the Mozilla TLS configuration profile
+type Profile struct {
+	Name      string
+	Protocols []string
+	// Ciphers are OpenSSL cipher names for TLSv1.2 and below. TLSv1.3 ciphers are not configurable.
+	Ciphers             []string
+	Curves              []string
+	PreferServerCiphers bool
+	// CertificateTypes contains allowed certificate key types: rsa, ecdsa
+	CertificateTypes []string
+	MinRSAKeySize    int
+	// CertificateSignatures contains allowed certificate signature algorithms
+	CertificateSignatures []string
+	// DHParamSize is a minimal DH parameters size. 0 if DHE ciphers are not used.
+	DHParamSize    int
+	HstsMaxAge     int
+	SessionTimeout string
+	// SessionTimeoutSeconds is SessionTimeout in seconds for webservers which do not support time units
+	SessionTimeoutSeconds int
+	SessionTickets        bool
+	OcspStapling          bool
+	// UnsupportedSettings contains settings which are not supported by the target webserver version
+	UnsupportedSettings []string
+}
+
+var profiles = map[string]Profile{
+	Modern: {
+		Name:                  Modern,
+		Protocols:             []string{TLSv1_3},
+		Curves:                []string{"X25519", "prime256v1", "secp384r1"},
+		PreferServerCiphers:   false,
+		CertificateTypes:      []string{"ecdsa"},
+		CertificateSignatures: []string{"ECDSA-SHA256", "ECDSA-SHA384", "ECDSA-SHA512"},
+		HstsMaxAge:            63072000,
+		SessionTimeout:        "1d",
+		SessionTimeoutSeconds: 86400,
+		SessionTickets:        false,
+		OcspStapling:          true,
+	},
+	Intermediate: {
+		Name:                  Intermediate,
+		Protocols:             []string{TLSv1_2, TLSv1_3},
+		Ciphers:               intermediateCiphers,
+		Curves:                []string{"X25519", "prime256v1", "secp384r1"},
+		PreferServerCiphers:   false,
+		CertificateTypes:      []string{"ecdsa", "rsa"},
+		MinRSAKeySize:         2048,
+		CertificateSignatures: []string{"SHA256-RSA", "SHA384-RSA", "SHA512-RSA", "ECDSA-SHA256", "ECDSA-SHA384", "ECDSA-SHA512"},
+		DHParamSize:           2048,
+		HstsMaxAge:            63072000,
+		SessionTimeout:        "1d",
+		SessionTimeoutSeconds: 86400,
+		SessionTickets:        false,
+		OcspStapling:          true,
+	},
+	Old: {
+		Name:                  Old,
+		Protocols:             []string{TLSv1, TLSv1_1, TLSv1_2, TLSv1_3},
+		Ciphers:               oldCiphers,
+		Curves:                []string{"X25519", "prime256v1", "secp384r1"},
+		PreferServerCiphers:   true,
+		CertificateTypes:      []string{"rsa"},
+		MinRSAKeySize:         2048,
+		CertificateSignatures: []string{"SHA256-RSA", "SHA384-RSA", "SHA512-RSA"},
+		DHParamSize:           1024,
+		HstsMaxAge:            63072000,
+		SessionTimeout:        "1d",
+		SessionTimeoutSeconds: 86400,
+		SessionTickets:        false,
+		OcspStapling:          true,
+	},
+}
+
+// GetProfile returns Mozilla TLS profile by name
+func GetProfile(name string) (Profile, error) {
+	profile, ok := profiles[strings.ToLower(name)]
+
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown TLS profile '%s', supported profiles: %s", name, strings.Join(GetProfileNames(), ", "))
+	}
+
+	return profile, nil
+}
+
+// GetProfileNames returns profile names from the most to the least strict one
+func GetProfileNames() []string {
+	return []string{Modern, Intermediate, Old}
+}
+
+// GetProfiles returns profiles from the most to the least strict one
+func GetProfiles() []Profile {
+	var result []Profile
+
+	for _, name := range GetProfileNames() {
+		result = append(result, profiles[name])
+	}
+
+	return result
+}
+
+// GetCiphersString returns ciphers in OpenSSL format: CIPHER1:CIPHER2
+func (p Profile) GetCiphersString() string {
+	return strings.Join(p.Ciphers, ":")
+}
+
+// GetHstsHeader returns Strict-Transport-Security header value
+func (p Profile) GetHstsHeader() string {
+	return fmt.Sprintf("max-age=%d", p.HstsMaxAge)
+}
@@ -0,0 +1,108 @@
+package tlsprofile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/r2dtools/webmng/pkg/utils"
+	"golang.org/x/exp/slices"
+)
+
+type settingRequirement struct {
+	setting    string
+	minVersion string
+}
+
+var tls13MinVersions = map[string]string{
+	apacheWebServer: "2.4.36",
+	nginxWebServer:  "1.13.0",
+}
+
+// tls13MinOpenSSLVersion is the first OpenSSL version supporting TLSv1.3
+const tls13MinOpenSSLVersion = "1.1.1"
+
+// openSSLVersionRegexp matches OpenSSL version without the letter suffix, e.g. 1.1.1 of 1.1.1n
+var openSSLVersionRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+`)
+
+var settingRequirements = map[string][]settingRequirement{
+	apacheWebServer: {
+		{SettingSessionTickets, "2.4.11"},
+		{SettingCurves, "2.4.8"},
+		{SettingDHParams, "2.4.8"},
+	},
+	nginxWebServer: {
+		{SettingSessionTickets, "1.5.9"},
+		{SettingCurves, "1.11.0"},
+		{SettingOcspStapling, "1.3.7"},
+	},
+}
+
+// AdaptToVersion adapts the profile to the webserver version and OpenSSL version the webserver is built with:
+// protocols and directives unsupported by the versions are excluded. Empty OpenSSL version means it is unknown.
+// Returns warnings about the excluded settings.
+func (p Profile) AdaptToVersion(webServer, version, openSSLVersion string) (Profile, []string, error) {
+	var warnings []string
+
+	adapted := p
+	adapted.Protocols = append([]string{}, p.Protocols...)
+	adapted.UnsupportedSettings = append([]string{}, p.UnsupportedSettings...)
+
+	if minVersion, ok := tls13MinVersions[webServer]; ok && slices.Contains(adapted.Protocols, TLSv1_3) {
+		supported, err := utils.CheckMinVersion(version, minVersion)
+		if err != nil {
+			return p, nil, err
+		}
+
+		requirement := fmt.Sprintf("%s %s", webServer, minVersion)
+
+		if supported && openSSLVersion != "" {
+			if supported, err = isOpenSSLSupportingTLS13(openSSLVersion); err != nil {
+				return p, nil, err
+			}
+
+			requirement = "OpenSSL " + tls13MinOpenSSLVersion
+		}
+
+		if !supported {
+			if len(adapted.Protocols) == 1 {
+				return p, nil, fmt.Errorf("%s profile requires TLSv1.3 which is supported since %s", p.Name, requirement)
+			}
+
+			adapted.Protocols = slices.DeleteFunc(adapted.Protocols, func(protocol string) bool {
+				return protocol == TLSv1_3
+			})
+			warnings = append(warnings, fmt.Sprintf("TLSv1.3 is excluded: it requires %s or later", requirement))
+		} else if openSSLVersion == "" {
+			warnings = append(warnings, "TLSv1.3 requires the webserver to be built with OpenSSL 1.1.1 or later, OpenSSL version could not be detected")
+		}
+	}
+
+	for _, requirement := range settingRequirements[webServer] {
+		supported, err := utils.CheckMinVersion(version, requirement.minVersion)
+		if err != nil {
+			return p, nil, err
+		}
+
+		if !supported {
+			adapted.UnsupportedSettings = append(adapted.UnsupportedSettings, requirement.setting)
+			warnings = append(warnings, fmt.Sprintf("%s setting is skipped: it requires %s %s or later", requirement.setting, webServer, requirement.minVersion))
+		}
+	}
+
+	if len(adapted.Protocols) == 0 {
+		return p, nil, errors.New("none of the profile protocols is supported")
+	}
+
+	return adapted, warnings, nil
+}
+
+func isOpenSSLSupportingTLS13(openSSLVersion string) (bool, error) {
+	version := openSSLVersionRegexp.FindString(openSSLVersion)
+
+	if version == "" {
+		return false, fmt.Errorf("invalid OpenSSL version '%s'", openSSLVersion)
+	}
+
+	return utils.CheckMinVersion(version, tls13MinOpenSSLVersion)
+}
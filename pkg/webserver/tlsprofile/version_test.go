@@ -0,0 +1,71 @@
+package tlsprofile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptToVersion(t *testing.T) {
+	intermediate, err := GetProfile(Intermediate)
+	assert.Nilf(t, err, "could not get profile: %v", err)
+
+	profile, warnings, err := intermediate.AdaptToVersion(nginxWebServer, "1.10.3", "")
+	assert.Nilf(t, err, "could not adapt profile: %v", err)
+	assert.Equal(t, []string{TLSv1_2}, profile.Protocols)
+	assert.Equal(t, []string{SettingCurves}, profile.UnsupportedSettings)
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, []string{TLSv1_2, TLSv1_3}, intermediate.Protocols, "origin profile must not be changed")
+
+	for _, directive := range profile.GetDirectives(nginxWebServer, PolicySettings, "") {
+		assert.NotEqual(t, "ssl_ecdh_curve", directive.Name)
+	}
+
+	profile, warnings, err = intermediate.AdaptToVersion(apacheWebServer, "2.4.41", "3.0.2")
+	assert.Nilf(t, err, "could not adapt profile: %v", err)
+	assert.Equal(t, []string{TLSv1_2, TLSv1_3}, profile.Protocols)
+	assert.Empty(t, profile.UnsupportedSettings)
+	assert.Empty(t, warnings)
+
+	profile, warnings, err = intermediate.AdaptToVersion(nginxWebServer, "1.24.0", "1.1.0l")
+	assert.Nilf(t, err, "could not adapt profile: %v", err)
+	assert.Equal(t, []string{TLSv1_2}, profile.Protocols, "TLSv1.3 must be excluded for old OpenSSL")
+	assert.Equal(t, []string{"TLSv1.3 is excluded: it requires OpenSSL 1.1.1 or later"}, warnings)
+
+	_, warnings, err = intermediate.AdaptToVersion(nginxWebServer, "1.24.0", "")
+	assert.Nilf(t, err, "could not adapt profile: %v", err)
+	assert.Len(t, warnings, 1, "unknown OpenSSL version must be warned about")
+
+	modern, err := GetProfile(Modern)
+	assert.Nilf(t, err, "could not get profile: %v", err)
+
+	_, _, err = modern.AdaptToVersion(apacheWebServer, "2.4.29", "")
+	assert.NotNil(t, err)
+
+	_, _, err = modern.AdaptToVersion(apacheWebServer, "2.4.57", "1.0.2k")
+	assert.NotNil(t, err, "TLSv1.3 only profile must be refused for old OpenSSL")
+	_, _, err = modern.AdaptToVersion(apacheWebServer, "2.4.57", "1.1.1n")
+	assert.Nilf(t, err, "could not adapt profile: %v", err)
+}
+
+func TestGetPolicyDirectives(t *testing.T) {
+	var names []string
+
+	for _, directive := range GetPolicyDirectives(nginxWebServer) {
+		names = append(names, directive.Name)
+	}
+
+	assert.Contains(t, names, "ssl_ciphers", "ciphers of the previous profile must be removed even if the profile has no ciphers")
+	assert.Contains(t, names, "ssl_ecdh_curve", "curves must be removed even if the webserver version does not support them")
+	assert.Contains(t, GetPolicyDirectives(apacheWebServer), Directive{"SSLCipherSuite", nil})
+	assert.Contains(t, GetPolicyDirectives(apacheWebServer), Directive{"SSLOpenSSLConfCmd", []string{"Curves"}})
+}
+
+func TestGetSnippetContent(t *testing.T) {
+	profile, err := GetProfile(Modern)
+	assert.Nilf(t, err, "could not get profile: %v", err)
+
+	content := profile.GetSnippetContent(nginxWebServer, profile.GetDirectives(nginxWebServer, []string{SettingProtocols}, ""))
+	assert.Equal(t, "webmng-tls-modern.conf", profile.GetSnippetFileName())
+	assert.Contains(t, content, "\nssl_protocols TLSv1.3;\n")
+}
@@ -0,0 +1,134 @@
+package verify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+)
+
+// Probe is a local request to the webserver host
+type Probe struct {
+	ServerName string
+	// Address is a local address the host listens on, e.g. 127.0.0.1:443
+	Address string
+	Tls     bool
+}
+
+func (p Probe) String() string {
+	scheme := "http"
+
+	if p.Tls {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s via %s", scheme, p.ServerName, p.Address)
+}
+
+// Run sends HEAD request to the host. Any HTTP response is considered successful, only connection and TLS handshake errors are reported.
+func (p Probe) Run(timeout time.Duration) error {
+	scheme := "http"
+
+	if p.Tls {
+		scheme = "https"
+	}
+
+	_, port, err := net.SplitHostPort(p.Address)
+	if err != nil {
+		return fmt.Errorf("invalid probe address '%s': %v", p.Address, err)
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", p.Address)
+		},
+		// the host certificate could be self-signed or not trusted locally, only its handshake is checked
+		TLSClientConfig:   &tls.Config{ServerName: p.ServerName, InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	client := http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	request, err := http.NewRequest(http.MethodHead, fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(p.ServerName, port)), nil)
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %v", p, err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %v", p, err)
+	}
+
+	response.Body.Close()
+
+	return nil
+}
+
+// GetHostProbes returns probes of all addresses of the hosts. Addresses of ssl hosts are probed via TLS except the http port.
+func GetHostProbes(hosts []webserver.Host, httpPort string) []Probe {
+	var probes []Probe
+	probed := make(map[string]bool)
+
+	for _, host := range hosts {
+		if host.ServerName == "" {
+			continue
+		}
+
+		for _, address := range host.Addresses {
+			port := address.Port
+
+			if port == "" {
+				port = "80"
+
+				if host.Ssl {
+					port = "443"
+				}
+			}
+
+			probe := Probe{
+				ServerName: host.ServerName,
+				Address:    net.JoinHostPort(getLocalIp(address.Host, address.IsIpv6), port),
+				Tls:        host.Ssl && port != httpPort,
+			}
+
+			if !probed[probe.String()] {
+				probed[probe.String()] = true
+				probes = append(probes, probe)
+			}
+		}
+	}
+
+	return probes
+}
+
+// getLocalIp returns ip to connect to the address host, wildcard addresses are replaced with loopback
+func getLocalIp(host string, ipv6 bool) string {
+	host = strings.Trim(host, "[]")
+
+	switch host {
+	case "", "*", "0.0.0.0", "_default_":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+
+	if net.ParseIP(host) == nil {
+		if ipv6 {
+			return "::1"
+		}
+
+		return "127.0.0.1"
+	}
+
+	return host
+}
@@ -0,0 +1,146 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/procstat"
+	"github.com/shirou/gopsutil/process"
+	"golang.org/x/exp/slices"
+)
+
+const checkInterval = 200 * time.Millisecond
+
+// Snapshot is a state of the webserver processes taken before reload
+type Snapshot struct {
+	Pid     int32
+	Workers []int32
+}
+
+// TakeSnapshot returns the webserver master process and its workers. Empty snapshot is returned if the webserver is not running.
+func TakeSnapshot(controller webserver.ServiceController) (Snapshot, error) {
+	status, err := controller.Status()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("could not get webserver status: %v", err)
+	}
+
+	if !status.Running || status.Pid == 0 {
+		return Snapshot{}, nil
+	}
+
+	workers, err := procstat.GetWorkers(status.Pid)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{Pid: status.Pid, Workers: workers}, nil
+}
+
+// Verifier checks that the webserver is healthy after reload
+type Verifier struct {
+	controller webserver.ServiceController
+	timeout    time.Duration
+	probes     []Probe
+}
+
+// Verify waits until the master process is alive and its workers are regenerated, then runs the probes.
+// Processes are not checked if the webserver was not running before reload.
+func (v Verifier) Verify(before Snapshot) error {
+	if before.Pid != 0 {
+		if err := v.waitProcesses(before); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+
+	for _, probe := range v.probes {
+		if err := probe.Run(v.timeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Restore applies the restored configuration: the webserver is reloaded if it is running or started if the failed reload stopped it.
+// Nothing is done if the webserver was not running before reload.
+func (v Verifier) Restore(before Snapshot) error {
+	if before.Pid == 0 {
+		return nil
+	}
+
+	status, err := v.controller.Status()
+	if err != nil {
+		return fmt.Errorf("could not get webserver status: %v", err)
+	}
+
+	if !status.Running {
+		return v.controller.Start()
+	}
+
+	return v.controller.Reload()
+}
+
+func (v Verifier) waitProcesses(before Snapshot) error {
+	deadline := time.Now().Add(v.timeout)
+
+	for {
+		err := checkProcesses(v.controller, before)
+
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(checkInterval)
+	}
+}
+
+func checkProcesses(controller webserver.ServiceController, before Snapshot) error {
+	status, err := controller.Status()
+	if err != nil {
+		return fmt.Errorf("could not get webserver status: %v", err)
+	}
+
+	if !status.Running || status.Pid == 0 {
+		return fmt.Errorf("webserver is not running after reload: %s", status.State)
+	}
+
+	exists, err := process.PidExists(status.Pid)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("webserver master process %d is not running after reload", status.Pid)
+	}
+
+	// single process webserver has nothing to regenerate
+	if len(before.Workers) == 0 {
+		return nil
+	}
+
+	workers, err := procstat.GetWorkers(status.Pid)
+	if err != nil {
+		return err
+	}
+
+	for _, worker := range workers {
+		if !slices.Contains(before.Workers, worker) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("webserver workers of master process %d were not regenerated after reload", status.Pid)
+}
+
+// GetVerifier returns verifier of the webserver controlled by the controller. Timeout limits both waiting for processes and each probe.
+func GetVerifier(controller webserver.ServiceController, timeout time.Duration, probes []Probe) Verifier {
+	return Verifier{controller: controller, timeout: timeout, probes: probes}
+}
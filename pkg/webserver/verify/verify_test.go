@@ -0,0 +1,165 @@
+package verify
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r2dtools/webmng/pkg/webserver"
+	"github.com/r2dtools/webmng/pkg/webserver/host"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeController struct {
+	status webserver.ServiceStatus
+	calls  []string
+}
+
+func (c *fakeController) Reload() error {
+	c.calls = append(c.calls, "reload")
+
+	return nil
+}
+
+func (c *fakeController) Restart() error {
+	c.calls = append(c.calls, "restart")
+
+	return nil
+}
+
+func (c *fakeController) Start() error {
+	c.calls = append(c.calls, "start")
+
+	return nil
+}
+
+func (c *fakeController) Stop(graceful bool) error {
+	c.calls = append(c.calls, "stop")
+
+	return nil
+}
+
+func (c *fakeController) Status() (webserver.ServiceStatus, error) {
+	return c.status, nil
+}
+
+func TestVerifyProcesses(t *testing.T) {
+	// the test process plays the webserver master, its children are workers
+	controller := &fakeController{status: webserver.ServiceStatus{Running: true, Pid: int32(os.Getpid())}}
+	oldWorker := startWorker(t)
+
+	snapshot, err := TakeSnapshot(controller)
+	assert.Nilf(t, err, "could not take snapshot: %v", err)
+	assert.Equal(t, int32(os.Getpid()), snapshot.Pid)
+	assert.Contains(t, snapshot.Workers, int32(oldWorker.Process.Pid))
+
+	verifier := GetVerifier(controller, 300*time.Millisecond, nil)
+	err = verifier.Verify(snapshot)
+	assert.NotNil(t, err, "not regenerated workers must be reported")
+	assert.Contains(t, err.Error(), "were not regenerated")
+
+	startWorker(t)
+	err = verifier.Verify(snapshot)
+	assert.Nilf(t, err, "regenerated workers must pass verification: %v", err)
+
+	controller.status = webserver.ServiceStatus{State: "failed"}
+	err = verifier.Verify(snapshot)
+	assert.NotNil(t, err, "stopped webserver must be reported")
+	assert.Contains(t, err.Error(), "not running after reload")
+
+	assert.Nil(t, verifier.Verify(Snapshot{}), "processes must not be checked if the webserver was not running")
+}
+
+func TestRestore(t *testing.T) {
+	controller := &fakeController{status: webserver.ServiceStatus{Running: true, Pid: 1}}
+	verifier := GetVerifier(controller, time.Second, nil)
+
+	assert.Nil(t, verifier.Restore(Snapshot{Pid: 1}))
+	controller.status = webserver.ServiceStatus{State: "failed"}
+	assert.Nil(t, verifier.Restore(Snapshot{Pid: 1}))
+	assert.Nil(t, verifier.Restore(Snapshot{}))
+	assert.Equal(t, []string{"reload", "start"}, controller.calls, "stopped webserver must be started, not running before must be left stopped")
+}
+
+func TestProbe(t *testing.T) {
+	var serverName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	probe := Probe{ServerName: "example.com", Address: server.Listener.Addr().String(), Tls: true}
+	err := probe.Run(time.Second)
+	assert.Nilf(t, err, "probe failed: %v", err)
+	assert.Equal(t, "example.com", serverName, "server name must be sent via SNI")
+
+	httpServer := httptest.NewServer(http.NotFoundHandler())
+	defer httpServer.Close()
+	err = Probe{ServerName: "example.com", Address: httpServer.Listener.Addr().String()}.Run(time.Second)
+	assert.Nilf(t, err, "http probe failed: %v", err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nilf(t, err, "could not listen: %v", err)
+	address := listener.Addr().String()
+	listener.Close()
+	err = Probe{ServerName: "example.com", Address: address}.Run(time.Second)
+	assert.NotNil(t, err, "closed port must be reported")
+	assert.True(t, strings.HasPrefix(err.Error(), "probe http://example.com via "+address), err.Error())
+}
+
+func TestGetHostProbes(t *testing.T) {
+	hosts := []webserver.Host{
+		{
+			ServerName: "example.com",
+			Ssl:        true,
+			Addresses: map[string]host.Address{
+				"443": {Port: "443"},
+				"80":  {Host: "*", Port: "80"},
+			},
+		},
+		{
+			ServerName: "example.org",
+			Addresses: map[string]host.Address{
+				"[::]:8080":        {Host: "[::]", Port: "8080", IsIpv6: true},
+				"192.168.1.10:80":  {Host: "192.168.1.10", Port: "80"},
+				"192.168.1.10:80 ": {Host: "192.168.1.10", Port: "80"},
+			},
+		},
+		{Addresses: map[string]host.Address{"80": {Port: "80"}}},
+	}
+
+	probes := GetHostProbes(hosts, "80")
+	assert.ElementsMatch(t, []Probe{
+		{ServerName: "example.com", Address: "127.0.0.1:443", Tls: true},
+		{ServerName: "example.com", Address: "127.0.0.1:80"},
+		{ServerName: "example.org", Address: "[::1]:8080"},
+		{ServerName: "example.org", Address: "192.168.1.10:80"},
+	}, probes)
+}
+
+// startWorker starts a child process of the test process which is stopped when the test finishes
+func startWorker(t *testing.T) *exec.Cmd {
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	assert.Nilf(t, err, "could not start worker process: %v", err)
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	return cmd
+}